@@ -0,0 +1,43 @@
+// Command mockoidc is a minimal OpenID Connect provider for local development and testing of
+// the SSO login flow. It signs in every authorization request without asking for credentials,
+// as the user given by the login_hint parameter or MOCK_OIDC_EMAIL.
+//
+//	MOCK_OIDC_ADDR=:9090 go run ./cmd/mockoidc
+//
+// and configure the API with
+//
+//	OIDC_PROVIDERS=mock
+//	OIDC_MOCK_ISSUER=http://localhost:9090
+//	OIDC_MOCK_CLIENT_ID=meetia
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/meetia/backend/internal/services/auth/oidctest"
+)
+
+func main() {
+	addr := getenv("MOCK_OIDC_ADDR", ":9090")
+	p, err := oidctest.NewProvider(
+		getenv("MOCK_OIDC_ISSUER", "http://localhost"+addr),
+		getenv("MOCK_OIDC_CLIENT_ID", "meetia"),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create provider: %v", err)
+	}
+	p.DefaultEmail = getenv("MOCK_OIDC_EMAIL", p.DefaultEmail)
+	p.EmailVerified = os.Getenv("MOCK_OIDC_UNVERIFIED_EMAIL") == ""
+
+	log.Printf("Mock OIDC provider %s listening on %s\n", p.Issuer, addr)
+	log.Fatal(http.ListenAndServe(addr, p))
+}
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
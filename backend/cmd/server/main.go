@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
@@ -15,13 +16,19 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/uptrace/bun"
 
 	"github.com/meetia/backend/internal/api"
+	apimiddleware "github.com/meetia/backend/internal/api/middleware"
 	"github.com/meetia/backend/internal/config"
 	"github.com/meetia/backend/internal/db"
 	"github.com/meetia/backend/internal/repository"
 	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/mail"
 	"github.com/meetia/backend/internal/services/meeting"
+	"github.com/meetia/backend/internal/services/ratelimit"
+	"github.com/meetia/backend/internal/services/storage"
+	"github.com/meetia/backend/internal/services/user"
 	"github.com/meetia/backend/internal/services/webrtc"
 )
 
@@ -41,12 +48,15 @@ func main() {
 
 	r := chi.NewRouter()
 
+	if cfg.TrustProxyHeaders {
+		r.Use(middleware.RealIP)
+	}
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
@@ -57,16 +67,57 @@ func main() {
 	humaConfig := huma.DefaultConfig("Meetia API", "1.0.0")
 	humaConfig.Info.Description = "API for Meetia video conferencing platform"
 	humaapi := humachi.New(r, humaConfig)
+	humaapi.UseMiddleware(apimiddleware.ClientIP)
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(database)
 	meetingRepo := repository.NewMeetingRepository(database)
+	tokenRepo := repository.NewTokenRepository(database)
 
-	authService := auth.NewAuthService(userRepo, cfg.JWTSecret, 24*time.Hour)
-	meetingService := meeting.NewMeetingService(meetingRepo, userRepo)
-	sfuService := webrtc.NewSFUService()
+	keyStore, err := loadKeyStore(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+
+	limitStore, err := newRateLimitStore(cfg, database)
+	if err != nil {
+		log.Fatalf("Failed to set up rate limiting: %v", err)
+	}
+
+	mailer, err := newMailer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up mailer: %v", err)
+	}
 
-	api.SetupRoutes(humaapi, authService, sfuService, meetingService)
+	blobStore, err := newBlobStore(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up file storage: %v", err)
+	}
+
+	authService := auth.NewAuthService(userRepo, tokenRepo, keyStore, mailer, limitStore, auth.Config{
+		AccessTokenTTL:           cfg.AccessTokenTTL,
+		RefreshTokenTTL:          cfg.RefreshTokenTTL,
+		GuestTokenTTL:            cfg.GuestTokenTTL,
+		EmailVerificationTTL:     cfg.EmailVerificationTTL,
+		PasswordResetTTL:         cfg.PasswordResetTTL,
+		RequireEmailVerification: cfg.RequireEmailVerification,
+		AppBaseURL:               cfg.AppBaseURL,
+		OIDCProviders:            oidcProviderConfigs(cfg),
+		LockoutThreshold:         cfg.LockoutThreshold,
+		LockoutDuration:          cfg.LockoutDuration,
+	})
+	sfuService := webrtc.NewSFUService()
+	meetingService := meeting.NewMeetingService(meetingRepo, userRepo, limitStore, mailer, sfuService, meeting.Config{
+		EarlyJoinWindow: cfg.MeetingEarlyJoinWindow,
+		OverrunGrace:    cfg.MeetingOverrunGrace,
+		DefaultDuration: cfg.MeetingDefaultDuration,
+		AppBaseURL:      cfg.AppBaseURL,
+		MaxParticipants: cfg.MeetingMaxParticipants,
+	})
+	sfuService.SetPeerListener(meetingService)
+	userService := user.NewUserService(userRepo, authService, blobStore, cfg.AvatarMaxBytes)
+
+	api.SetupRoutes(humaapi, authService, sfuService, meetingService, userService)
 
 	// start server
 	server := &http.Server{
@@ -76,11 +127,16 @@ func main() {
 
 	serverCtx, serverStopCtx := context.WithCancel(context.Background())
 
+	authService.StartTokenCleanup(serverCtx, time.Hour)
+	ratelimit.StartCleanup(serverCtx, limitStore, 10*time.Minute, 24*time.Hour)
+	meetingService.StartScheduler(serverCtx, cfg.MeetingSchedulerInterval)
+
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	go func() {
 		<-sig
-		shutdownCtx, _ := context.WithTimeout(serverCtx, 30*time.Second)
+		shutdownCtx, cancel := context.WithTimeout(serverCtx, 30*time.Second)
+		defer cancel()
 		go func() {
 			<-shutdownCtx.Done()
 			if shutdownCtx.Err() == context.DeadlineExceeded {
@@ -98,10 +154,66 @@ func main() {
 
 	// run the server
 	log.Printf("Server is running on port %s\n", cfg.Port)
-	err := server.ListenAndServe()
+	err = server.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
 		log.Fatal(err)
 	}
 
 	<-serverCtx.Done()
 }
+
+func loadKeyStore(cfg *config.Config) (*auth.KeyStore, error) {
+	if cfg.JWTSigningKeyFile == "" {
+		slog.Warn("JWT_SIGNING_KEY_FILE not set, using an ephemeral signing key; tokens will not survive a restart")
+		return auth.NewEphemeralKeyStore()
+	}
+	return auth.LoadKeyStore(cfg.JWTSigningKeyFile, cfg.JWTVerificationKeyFiles)
+}
+
+func newMailer(cfg *config.Config) (mail.Mailer, error) {
+	switch cfg.MailDriver {
+	case "smtp":
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+	case "file":
+		return mail.NewFileMailer(cfg.MailDir, cfg.MailFrom)
+	case "log":
+		return mail.NewLogMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", cfg.MailDriver)
+	}
+}
+
+func oidcProviderConfigs(cfg *config.Config) []auth.OIDCProviderConfig {
+	providers := make([]auth.OIDCProviderConfig, len(cfg.OIDCProviders))
+	for i, p := range cfg.OIDCProviders {
+		providers[i] = auth.OIDCProviderConfig{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}
+	}
+	return providers
+}
+
+func newRateLimitStore(cfg *config.Config, database *bun.DB) (ratelimit.Store, error) {
+	switch cfg.RateLimitStore {
+	case "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "postgres":
+		return ratelimit.NewPostgresStore(database), nil
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q", cfg.RateLimitStore)
+	}
+}
+
+func newBlobStore(cfg *config.Config) (storage.BlobStore, error) {
+	switch cfg.StorageDriver {
+	case "local":
+		return storage.NewLocalStore(cfg.StorageDir)
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", cfg.StorageDriver)
+	}
+}
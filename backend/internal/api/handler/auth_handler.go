@@ -3,9 +3,15 @@ package handler
 import (
 	"context"
 	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/danielgtaylor/huma/v2"
+	"github.com/go-chi/jwtauth/v5"
 
+	"github.com/meetia/backend/internal/api/middleware"
 	"github.com/meetia/backend/internal/models"
 	"github.com/meetia/backend/internal/services/auth"
 	humagroup "github.com/meetia/backend/lib/humaGroup"
@@ -24,15 +30,76 @@ func NewAuthHandler(authService *auth.AuthService) *AuthHandler {
 
 func (h *AuthHandler) RegisterRoutes(api huma.API) {
 	authGroup := humagroup.NewHumaGroup(api, "/api/auth", []string{"Authentication"})
+	wellKnownGroup := humagroup.NewHumaGroup(api, "/.well-known", []string{"Authentication"})
 
 	humagroup.Post(authGroup, "/register", h.Register, "Register", &humagroup.HumaGroupOptions{
 		Summary:     "Register a new user",
-		Description: "Create a new user account with email, password, and display name",
+		Description: "Create a new user account with email, password, and display name. When email verification is required no tokens are returned, the user signs in once the address is verified",
 	})
 	humagroup.Post(authGroup, "/login", h.Login, "Login", &humagroup.HumaGroupOptions{
 		Summary:     "User login",
 		Description: "Authenticate a user with email and password",
 	})
+	humagroup.Post(authGroup, "/refresh", h.Refresh, "RefreshToken", &humagroup.HumaGroupOptions{
+		Summary:     "Refresh access token",
+		Description: "Exchange a refresh token for a new access token and refresh token",
+	})
+	humagroup.Post(authGroup, "/logout", h.Logout, "Logout", &humagroup.HumaGroupOptions{
+		Summary:     "User logout",
+		Description: "Revoke the current access token and its refresh token",
+	}, middleware.JWTMiddleware(h.authService))
+	humagroup.Post(authGroup, "/verify-email", h.VerifyEmail, "VerifyEmail", &humagroup.HumaGroupOptions{
+		Summary:     "Verify email address",
+		Description: "Confirm a user's email address with the token sent by email",
+	})
+	humagroup.Post(authGroup, "/verify-email/resend", h.ResendVerificationEmail, "ResendVerificationEmail", &humagroup.HumaGroupOptions{
+		Summary:     "Resend verification email",
+		Description: "Send a new email verification link to the current user",
+	}, middleware.JWTMiddleware(h.authService))
+	humagroup.Post(authGroup, "/forgot-password", h.ForgotPassword, "ForgotPassword", &humagroup.HumaGroupOptions{
+		Summary:     "Request a password reset",
+		Description: "Email a password reset link if an account exists for the address",
+	})
+	humagroup.Post(authGroup, "/reset-password", h.ResetPassword, "ResetPassword", &humagroup.HumaGroupOptions{
+		Summary:     "Reset password",
+		Description: "Set a new password using the token sent by email",
+	})
+	humagroup.Post(authGroup, "/mfa/verify", h.VerifyMFA, "VerifyMFA", &humagroup.HumaGroupOptions{
+		Summary:     "Complete two-factor login",
+		Description: "Exchange the MFA challenge returned by login and a TOTP or recovery code for tokens, a challenge can only be tried once",
+	})
+	humagroup.Post(authGroup, "/mfa/totp/enroll", h.EnrollTOTP, "EnrollTOTP", &humagroup.HumaGroupOptions{
+		Summary:     "Start TOTP enrollment",
+		Description: "Generate a TOTP secret and otpauth URI for an authenticator app",
+	}, middleware.JWTMiddleware(h.authService))
+	humagroup.Post(authGroup, "/mfa/totp/confirm", h.ConfirmTOTP, "ConfirmTOTP", &humagroup.HumaGroupOptions{
+		Summary:     "Confirm TOTP enrollment",
+		Description: "Enable two-factor authentication with a code from the authenticator app and get recovery codes",
+	}, middleware.JWTMiddleware(h.authService))
+	humagroup.Post(authGroup, "/mfa/totp/disable", h.DisableTOTP, "DisableTOTP", &humagroup.HumaGroupOptions{
+		Summary:     "Disable two-factor authentication",
+		Description: "Turn off two-factor authentication with a TOTP or recovery code",
+	}, middleware.JWTMiddleware(h.authService))
+	humagroup.Post(authGroup, "/mfa/recovery-codes", h.RegenerateRecoveryCodes, "RegenerateRecoveryCodes", &humagroup.HumaGroupOptions{
+		Summary:     "Regenerate recovery codes",
+		Description: "Replace all recovery codes, requires a TOTP code",
+	}, middleware.JWTMiddleware(h.authService))
+	humagroup.Get(authGroup, "/oidc/providers", h.ListOIDCProviders, "ListOIDCProviders", &humagroup.HumaGroupOptions{
+		Summary:     "List SSO providers",
+		Description: "Names of the identity providers users can sign in with",
+	})
+	humagroup.Get(authGroup, "/oidc/{provider}/start", h.StartOIDCLogin, "StartOIDCLogin", &humagroup.HumaGroupOptions{
+		Summary:     "Start SSO login",
+		Description: "Redirect the browser to the identity provider to sign in",
+	})
+	humagroup.Get(authGroup, "/oidc/{provider}/callback", h.OIDCCallback, "OIDCCallback", &humagroup.HumaGroupOptions{
+		Summary:     "SSO login callback",
+		Description: "Redirect target of the identity provider, signs the user in and redirects to the app with tokens, or an MFA challenge for accounts with two-factor authentication, in the URL fragment",
+	})
+	humagroup.Get(wellKnownGroup, "/jwks.json", h.JWKS, "JWKS", &humagroup.HumaGroupOptions{
+		Summary:     "JSON Web Key Set",
+		Description: "Public keys used to verify access tokens issued by this server",
+	})
 }
 
 // RegisterBody defines the request body for user registration
@@ -50,8 +117,24 @@ type LoginBody struct {
 
 // AuthResponse defines the response for authentication operations
 type AuthResponse struct {
-	User  *models.User `json:"user" doc:"User details"`
-	Token string       `json:"token" doc:"JWT authentication token"`
+	User              *models.User `json:"user" doc:"User details"`
+	Token             string       `json:"token,omitempty" doc:"JWT authentication token"`
+	RefreshToken      string       `json:"refreshToken,omitempty" doc:"Single-use token to obtain a new access token"`
+	ExpiresAt         *time.Time   `json:"expiresAt,omitempty" doc:"When the access token expires"`
+	MFARequired       bool         `json:"mfaRequired" doc:"Whether a second factor is needed, exchange mfaToken at /api/auth/mfa/verify"`
+	MFAToken          string       `json:"mfaToken,omitempty" doc:"Short-lived two-factor authentication challenge"`
+	MFATokenExpiresAt *time.Time   `json:"mfaTokenExpiresAt,omitempty" doc:"When the two-factor authentication challenge expires"`
+	// set by register when the email address has to be verified before the first login
+	VerificationRequired bool `json:"verificationRequired" doc:"Whether the account can only sign in once its email address is verified, no tokens are returned then"`
+}
+
+func newAuthResponse(user *models.User, tokens *auth.TokenPair) AuthResponse {
+	return AuthResponse{
+		User:         user,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    &tokens.ExpiresAt,
+	}
 }
 
 type RegisterRequest struct {
@@ -63,7 +146,7 @@ type RegisterResponse struct {
 }
 
 func (h *AuthHandler) Register(ctx context.Context, input *RegisterRequest) (*RegisterResponse, error) {
-	user, token, err := h.authService.Register(ctx, input.Body.Email, input.Body.Password, input.Body.DisplayName)
+	user, tokens, err := h.authService.Register(ctx, input.Body.Email, input.Body.Password, input.Body.DisplayName)
 	if err != nil {
 		switch {
 		case errors.Is(err, auth.ErrUserAlreadyExists):
@@ -73,11 +156,17 @@ func (h *AuthHandler) Register(ctx context.Context, input *RegisterRequest) (*Re
 		}
 	}
 
-	response := AuthResponse{
-		User:  user,
-		Token: token,
+	if tokens == nil {
+		return &RegisterResponse{
+			Body: AuthResponse{
+				User:                 user,
+				VerificationRequired: true,
+			},
+		}, nil
 	}
 
+	response := newAuthResponse(user, tokens)
+
 	return &RegisterResponse{
 		Body: response,
 	}, nil
@@ -92,22 +181,441 @@ type LoginResponse struct {
 }
 
 func (h *AuthHandler) Login(ctx context.Context, input *LoginRequest) (*LoginResponse, error) {
-	user, token, err := h.authService.Login(ctx, input.Body.Email, input.Body.Password)
+	result, err := h.authService.Login(ctx, middleware.GetClientIP(ctx), input.Body.Email, input.Body.Password)
 	if err != nil {
+		if limitErr := rateLimitError(err); limitErr != nil {
+			return nil, limitErr
+		}
 		switch {
 		case errors.Is(err, auth.ErrInvalidCredentials):
 			return nil, huma.Error401Unauthorized("invalid credentials", err)
+		case errors.Is(err, auth.ErrEmailNotVerified):
+			return nil, huma.Error403Forbidden("email address has not been verified", err)
 		default:
 			return nil, huma.Error500InternalServerError("an error occured", err)
 		}
 	}
 
-	response := AuthResponse{
-		User:  user,
-		Token: token,
+	if result.Tokens == nil {
+		return &LoginResponse{
+			Body: AuthResponse{
+				User:              result.User,
+				MFARequired:       true,
+				MFAToken:          result.MFAToken,
+				MFATokenExpiresAt: &result.MFATokenExpiresAt,
+			},
+		}, nil
 	}
 
+	response := newAuthResponse(result.User, result.Tokens)
+
 	return &LoginResponse{
 		Body: response,
 	}, nil
 }
+
+type RefreshRequest struct {
+	Body struct {
+		RefreshToken string `json:"refreshToken" required:"true" doc:"Refresh token returned by login, register or a previous refresh"`
+	}
+}
+
+type RefreshResponse struct {
+	Body AuthResponse
+}
+
+func (h *AuthHandler) Refresh(ctx context.Context, input *RefreshRequest) (*RefreshResponse, error) {
+	user, tokens, err := h.authService.Refresh(ctx, input.Body.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidRefreshToken), errors.Is(err, auth.ErrRefreshTokenReused):
+			return nil, huma.Error401Unauthorized("invalid refresh token", err)
+		case errors.Is(err, auth.ErrEmailNotVerified):
+			return nil, huma.Error403Forbidden("email address has not been verified", err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
+	return &RefreshResponse{
+		Body: newAuthResponse(user, tokens),
+	}, nil
+}
+
+type LogoutRequest struct {
+	AuthParam
+
+	Body struct {
+		RefreshToken string `json:"refreshToken,omitempty" doc:"Refresh token to revoke along with the access token"`
+	}
+}
+
+func (h *AuthHandler) Logout(ctx context.Context, input *LogoutRequest) (*struct{}, error) {
+	token, _, err := jwtauth.FromContext(ctx)
+	if err != nil || token == nil {
+		return nil, huma.Error401Unauthorized("invalid token", err)
+	}
+
+	if err := h.authService.Logout(ctx, token.JwtID(), token.Expiration(), input.Body.RefreshToken); err != nil {
+		return nil, huma.Error500InternalServerError("an error occured", err)
+	}
+
+	return &struct{}{}, nil
+}
+
+type VerifyEmailRequest struct {
+	Body struct {
+		Token string `json:"token" required:"true" doc:"Verification token from the email link"`
+	}
+}
+
+type VerifyEmailResponse struct {
+	Body struct {
+		User *models.User `json:"user" doc:"User details"`
+	}
+}
+
+func (h *AuthHandler) VerifyEmail(ctx context.Context, input *VerifyEmailRequest) (*VerifyEmailResponse, error) {
+	user, err := h.authService.VerifyEmail(ctx, input.Body.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidVerificationToken):
+			return nil, huma.Error400BadRequest("invalid or expired verification token", err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
+	resp := &VerifyEmailResponse{}
+	resp.Body.User = user
+	return resp, nil
+}
+
+type ResendVerificationEmailRequest struct {
+	AuthParam
+}
+
+func (h *AuthHandler) ResendVerificationEmail(ctx context.Context, input *ResendVerificationEmailRequest) (*struct{}, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.authService.ResendVerificationEmail(ctx, userID); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrEmailAlreadyVerified):
+			return nil, huma.Error409Conflict("email address is already verified", err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
+	return &struct{}{}, nil
+}
+
+type ForgotPasswordRequest struct {
+	Body struct {
+		Email string `json:"email" required:"true" doc:"Email address of the account" example:"user@example.com"`
+	}
+}
+
+func (h *AuthHandler) ForgotPassword(ctx context.Context, input *ForgotPasswordRequest) (*struct{}, error) {
+	if err := h.authService.ForgotPassword(ctx, input.Body.Email); err != nil {
+		return nil, huma.Error500InternalServerError("an error occured", err)
+	}
+
+	return &struct{}{}, nil
+}
+
+type ResetPasswordRequest struct {
+	Body struct {
+		Token    string `json:"token" required:"true" doc:"Password reset token from the email link"`
+		Password string `json:"password" required:"true" minLength:"8" doc:"New password" example:"securepassword123"`
+	}
+}
+
+func (h *AuthHandler) ResetPassword(ctx context.Context, input *ResetPasswordRequest) (*struct{}, error) {
+	if err := h.authService.ResetPassword(ctx, input.Body.Token, input.Body.Password); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidResetToken):
+			return nil, huma.Error400BadRequest("invalid or expired password reset token", err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
+	return &struct{}{}, nil
+}
+
+type VerifyMFARequest struct {
+	Body struct {
+		MFAToken string `json:"mfaToken" required:"true" doc:"Challenge returned by login"`
+		Code     string `json:"code" required:"true" doc:"TOTP code or recovery code" example:"123456"`
+	}
+}
+
+type VerifyMFAResponse struct {
+	Body AuthResponse
+}
+
+func (h *AuthHandler) VerifyMFA(ctx context.Context, input *VerifyMFARequest) (*VerifyMFAResponse, error) {
+	user, tokens, err := h.authService.VerifyMFA(ctx, input.Body.MFAToken, input.Body.Code)
+	if err != nil {
+		if limitErr := rateLimitError(err); limitErr != nil {
+			return nil, limitErr
+		}
+		switch {
+		case errors.Is(err, auth.ErrInvalidMFAChallenge):
+			return nil, huma.Error401Unauthorized("invalid or expired mfa token", err)
+		case errors.Is(err, auth.ErrInvalidMFACode):
+			return nil, huma.Error401Unauthorized("invalid code", err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
+	return &VerifyMFAResponse{
+		Body: newAuthResponse(user, tokens),
+	}, nil
+}
+
+type EnrollTOTPRequest struct {
+	AuthParam
+}
+
+type EnrollTOTPResponse struct {
+	Body struct {
+		Secret string `json:"secret" doc:"Base32 TOTP secret for manual entry"`
+		URI    string `json:"uri" doc:"otpauth URI, usually shown as a QR code"`
+	}
+}
+
+func (h *AuthHandler) EnrollTOTP(ctx context.Context, input *EnrollTOTPRequest) (*EnrollTOTPResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment, err := h.authService.EnrollTOTP(ctx, userID)
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	resp := &EnrollTOTPResponse{}
+	resp.Body.Secret = enrollment.Secret
+	resp.Body.URI = enrollment.URI
+	return resp, nil
+}
+
+type MFACodeRequest struct {
+	AuthParam
+
+	Body struct {
+		Code string `json:"code" required:"true" doc:"TOTP code, or a recovery code where accepted" example:"123456"`
+	}
+}
+
+type RecoveryCodesResponse struct {
+	Body struct {
+		RecoveryCodes []string `json:"recoveryCodes" doc:"Single-use recovery codes, store them somewhere safe"`
+	}
+}
+
+func (h *AuthHandler) ConfirmTOTP(ctx context.Context, input *MFACodeRequest) (*RecoveryCodesResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := h.authService.ConfirmTOTP(ctx, userID, input.Body.Code)
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	resp := &RecoveryCodesResponse{}
+	resp.Body.RecoveryCodes = codes
+	return resp, nil
+}
+
+func (h *AuthHandler) DisableTOTP(ctx context.Context, input *MFACodeRequest) (*struct{}, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.authService.DisableTOTP(ctx, userID, input.Body.Code); err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &struct{}{}, nil
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(ctx context.Context, input *MFACodeRequest) (*RecoveryCodesResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(ctx, userID, input.Body.Code)
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	resp := &RecoveryCodesResponse{}
+	resp.Body.RecoveryCodes = codes
+	return resp, nil
+}
+
+func mfaError(err error) error {
+	if limitErr := rateLimitError(err); limitErr != nil {
+		return limitErr
+	}
+	switch {
+	case errors.Is(err, auth.ErrMFAAlreadyEnabled):
+		return huma.Error409Conflict("two-factor authentication is already enabled", err)
+	case errors.Is(err, auth.ErrMFANotEnrolled), errors.Is(err, auth.ErrMFANotEnabled):
+		return huma.Error409Conflict("two-factor authentication is not enabled", err)
+	case errors.Is(err, auth.ErrInvalidMFACode):
+		return huma.Error400BadRequest("invalid code", err)
+	default:
+		return huma.Error500InternalServerError("an error occured", err)
+	}
+}
+
+const oidcLoginCookie = "meetia_oidc"
+
+type ListOIDCProvidersResponse struct {
+	Body struct {
+		Providers []string `json:"providers" doc:"Identity provider names"`
+	}
+}
+
+func (h *AuthHandler) ListOIDCProviders(ctx context.Context, input *struct{}) (*ListOIDCProvidersResponse, error) {
+	resp := &ListOIDCProvidersResponse{}
+	resp.Body.Providers = h.authService.OIDCProviders()
+	return resp, nil
+}
+
+type StartOIDCLoginRequest struct {
+	Provider string `path:"provider" doc:"Identity provider name" example:"google"`
+}
+
+type OIDCRedirectResponse struct {
+	Status    int
+	Location  string      `header:"Location"`
+	SetCookie http.Cookie `header:"Set-Cookie"`
+}
+
+func (h *AuthHandler) StartOIDCLogin(ctx context.Context, input *StartOIDCLoginRequest) (*OIDCRedirectResponse, error) {
+	login, err := h.authService.StartOIDCLogin(ctx, input.Provider)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrUnknownOIDCProvider):
+			return nil, huma.Error404NotFound("unknown identity provider", err)
+		case errors.Is(err, auth.ErrOIDCProviderFailure):
+			return nil, huma.Error502BadGateway("identity provider unavailable", err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
+	return &OIDCRedirectResponse{
+		Status:    http.StatusFound,
+		Location:  login.AuthURL,
+		SetCookie: h.oidcCookie(input.Provider, login.Cookie, 600),
+	}, nil
+}
+
+type OIDCCallbackRequest struct {
+	Provider         string `path:"provider" doc:"Identity provider name"`
+	Code             string `query:"code" doc:"Authorization code"`
+	State            string `query:"state" doc:"Login state"`
+	Error            string `query:"error" doc:"Error returned by the identity provider"`
+	ErrorDescription string `query:"error_description" doc:"Error description returned by the identity provider"`
+	LoginCookie      string `cookie:"meetia_oidc" doc:"Login state set by the start endpoint"`
+}
+
+func (h *AuthHandler) OIDCCallback(ctx context.Context, input *OIDCCallbackRequest) (*OIDCRedirectResponse, error) {
+	resp := &OIDCRedirectResponse{
+		Status:    http.StatusFound,
+		SetCookie: h.oidcCookie(input.Provider, "", -1),
+	}
+
+	if input.Error != "" {
+		resp.Location = h.authService.OIDCCompletionURL(url.Values{"error": {input.Error}})
+		return resp, nil
+	}
+
+	result, err := h.authService.CompleteOIDCLogin(ctx, input.Provider, input.Code, input.State, input.LoginCookie)
+	if err != nil {
+		var code string
+		switch {
+		case errors.Is(err, auth.ErrUnknownOIDCProvider):
+			return nil, huma.Error404NotFound("unknown identity provider", err)
+		case errors.Is(err, auth.ErrInvalidOIDCState):
+			code = "invalid_state"
+		case errors.Is(err, auth.ErrOIDCEmailNotVerified):
+			code = "email_not_verified"
+		case errors.Is(err, auth.ErrOIDCAccountNotVerified):
+			code = "account_not_verified"
+		case errors.Is(err, auth.ErrOIDCProviderFailure):
+			code = "provider_error"
+		default:
+			code = "server_error"
+		}
+		log.Printf("OIDC login with %s failed: %v", input.Provider, err)
+		resp.Location = h.authService.OIDCCompletionURL(url.Values{"error": {code}})
+		return resp, nil
+	}
+
+	// the frontend finishes like a password login, with VerifyMFA when a challenge comes back
+	if result.Tokens == nil {
+		resp.Location = h.authService.OIDCCompletionURL(url.Values{
+			"mfaToken":          {result.MFAToken},
+			"mfaTokenExpiresAt": {result.MFATokenExpiresAt.Format(time.RFC3339)},
+			"userId":            {result.User.ID},
+		})
+		return resp, nil
+	}
+
+	resp.Location = h.authService.OIDCCompletionURL(url.Values{
+		"token":        {result.Tokens.AccessToken},
+		"refreshToken": {result.Tokens.RefreshToken},
+		"expiresAt":    {result.Tokens.ExpiresAt.Format(time.RFC3339)},
+		"userId":       {result.User.ID},
+	})
+	return resp, nil
+}
+
+func (h *AuthHandler) oidcCookie(provider, value string, maxAge int) http.Cookie {
+	return http.Cookie{
+		Name:     oidcLoginCookie,
+		Value:    value,
+		Path:     "/api/auth/oidc/" + provider,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.authService.OIDCLoginSecure(provider),
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+type JWKSResponse struct {
+	CacheControl string `header:"Cache-Control"`
+
+	Body struct {
+		Keys []map[string]interface{} `json:"keys" doc:"Public signing keys in JWK format"`
+	}
+}
+
+func (h *AuthHandler) JWKS(ctx context.Context, input *struct{}) (*JWKSResponse, error) {
+	keys, err := h.authService.JWKS()
+	if err != nil {
+		return nil, huma.Error500InternalServerError("an error occured", err)
+	}
+
+	resp := &JWKSResponse{
+		CacheControl: "public, max-age=300",
+	}
+	resp.Body.Keys = keys
+	return resp, nil
+}
@@ -2,10 +2,17 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/go-chi/jwtauth/v5"
 
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/ratelimit"
 )
 
 type AuthParam struct {
@@ -24,3 +31,32 @@ func getUserIdFromContext(ctx context.Context) (string, error) {
 
 	return userID, nil
 }
+
+// getAuthTimeFromContext returns when the user logged in for the token in hand, zero for tokens
+// without it like personal access tokens
+func getAuthTimeFromContext(ctx context.Context) time.Time {
+	_, claims, err := jwtauth.FromContext(ctx)
+	if err != nil {
+		return time.Time{}
+	}
+	authTime, ok := claims[auth.AuthTimeClaim].(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(authTime), 0)
+}
+
+// rateLimitError turns a *ratelimit.LimitedError into a 429 with a Retry-After header,
+// it returns nil for any other error
+func rateLimitError(err error) error {
+	var limited *ratelimit.LimitedError
+	if !errors.As(err, &limited) {
+		return nil
+	}
+
+	retryAfter := strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds())))
+	return huma.ErrorWithHeaders(
+		huma.Error429TooManyRequests("too many attempts, try again later", err),
+		http.Header{"Retry-After": {retryAfter}},
+	)
+}
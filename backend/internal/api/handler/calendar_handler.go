@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/meetia/backend/internal/services/meeting"
+	"github.com/meetia/backend/internal/services/user"
+	humagroup "github.com/meetia/backend/lib/humaGroup"
+)
+
+type CalendarHandler struct {
+	userService    *user.UserService
+	meetingService *meeting.MeetingService
+}
+
+func NewCalendarHandler(userService *user.UserService, meetingService *meeting.MeetingService) *CalendarHandler {
+	return &CalendarHandler{
+		userService:    userService,
+		meetingService: meetingService,
+	}
+}
+
+func (h *CalendarHandler) RegisterRoutes(api huma.API) {
+	// calendar apps can't send credentials, the secret token in the URL authenticates the feed
+	calendarGroup := humagroup.NewHumaGroup(api, "/api/calendar", []string{"Calendar"})
+
+	humagroup.Get(calendarGroup, "/{token}.ics", h.GetCalendarFeed, "GetCalendarFeed", &humagroup.HumaGroupOptions{
+		Summary:     "Calendar feed",
+		Description: "iCalendar feed of the upcoming meetings and series a user hosts or takes part in, subscribe to it with the URL from EnableCalendarFeed",
+	})
+}
+
+type GetCalendarFeedRequest struct {
+	Token string `path:"token" doc:"secret feed token"`
+}
+
+func (h *CalendarHandler) GetCalendarFeed(ctx context.Context, input *GetCalendarFeedRequest) (*huma.StreamResponse, error) {
+	owner, err := h.userService.GetCalendarFeedOwner(ctx, input.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrCalendarFeedNotFound):
+			return nil, huma.Error404NotFound("calendar feed not found", err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
+	meetings, series, err := h.meetingService.CalendarFeed(ctx, owner.ID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list meetings", err)
+	}
+
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			hctx.SetHeader("Content-Type", meeting.CalendarContentType)
+			hctx.SetHeader("Cache-Control", "private, max-age=300")
+
+			if err := h.meetingService.WriteCalendar(hctx.BodyWriter(), "Meetia", meetings, series); err != nil {
+				log.Printf("Failed to write calendar feed: %v", err)
+			}
+		},
+	}, nil
+}
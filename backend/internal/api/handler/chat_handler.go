@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/meetia/backend/internal/api/middleware"
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/meeting"
+	humagroup "github.com/meetia/backend/lib/humaGroup"
+)
+
+type ChatHandler struct {
+	meetingService *meeting.MeetingService
+	tokenVerifier  middleware.TokenVerifier
+}
+
+func NewChatHandler(meetingService *meeting.MeetingService, tokenVerifier middleware.TokenVerifier) *ChatHandler {
+	return &ChatHandler{
+		meetingService: meetingService,
+		tokenVerifier:  tokenVerifier,
+	}
+}
+
+func (h *ChatHandler) RegisterRoutes(api huma.API) {
+	chatGroup := humagroup.NewHumaGroup(api, "/api/chat", []string{"Chat"}, middleware.JWTMiddleware(h.tokenVerifier))
+
+	humagroup.Get(chatGroup, "/search", h.SearchChat, "SearchChat", &humagroup.HumaGroupOptions{
+		Summary:     "Search chat messages",
+		Description: "Full-text search over chat messages from meetings the user participated in",
+		Scopes:      []string{auth.ScopeChatRead},
+	})
+}
+
+type SearchChatRequest struct {
+	AuthParam
+
+	Query    string `query:"q" required:"true" minLength:"1" doc:"Search query" example:"design doc link"`
+	Page     int    `query:"page" default:"1" minimum:"1" doc:"Page number"`
+	PageSize int    `query:"pageSize" default:"20" minimum:"1" maximum:"100" doc:"Number of results per page"`
+}
+
+type ChatSearchResultResponse struct {
+	ID           string    `json:"id" doc:"Message unique identifier"`
+	MeetingID    string    `json:"meetingId" doc:"ID of the meeting the message was sent in"`
+	MeetingTitle string    `json:"meetingTitle" doc:"Title of the meeting the message was sent in"`
+	UserID       string    `json:"userId" doc:"ID of the user who sent the message"`
+	DisplayName  string    `json:"displayName" doc:"Display name of the user who sent the message"`
+	Snippet      string    `json:"snippet" doc:"HTML escaped message excerpt with matches wrapped in <mark> tags"`
+	SentAt       time.Time `json:"sentAt" doc:"When the message was sent"`
+}
+
+type SearchChatResponse struct {
+	Body struct {
+		Results  []ChatSearchResultResponse `json:"results" doc:"matching messages ordered by relevance"`
+		Total    int                        `json:"total" doc:"total number of matching messages"`
+		Page     int                        `json:"page" doc:"current page"`
+		PageSize int                        `json:"pageSize" doc:"number of results per page"`
+	}
+}
+
+func (h *ChatHandler) SearchChat(ctx context.Context, input *SearchChatRequest) (*SearchChatResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results, total, err := h.meetingService.SearchChatMessages(ctx, userID, input.Query, input.Page, input.PageSize)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to search chat messages", err)
+	}
+
+	response := make([]ChatSearchResultResponse, len(results))
+	for i, r := range results {
+		response[i] = ChatSearchResultResponse{
+			ID:           r.ID,
+			MeetingID:    r.MeetingID,
+			MeetingTitle: r.MeetingTitle,
+			UserID:       r.UserID,
+			DisplayName:  r.DisplayName,
+			Snippet:      r.Snippet,
+			SentAt:       r.SentAt,
+		}
+	}
+
+	resp := &SearchChatResponse{}
+	resp.Body.Results = response
+	resp.Body.Total = total
+	resp.Body.Page = input.Page
+	resp.Body.PageSize = input.PageSize
+	return resp, nil
+}
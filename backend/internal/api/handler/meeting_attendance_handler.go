@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/meeting"
+	humagroup "github.com/meetia/backend/lib/humaGroup"
+)
+
+func (h *MeetingHandler) registerAttendanceRoutes(meetingGroup *humagroup.HumaGroup) {
+	humagroup.Get(meetingGroup, "/{id}/attendance", h.GetAttendance, "GetAttendance", &humagroup.HumaGroupOptions{
+		Summary:     "Get attendance",
+		Description: "List who connected to a meeting, how often and for how long (host only)",
+		Scopes:      []string{auth.ScopeMeetingsRead},
+	})
+	humagroup.Get(meetingGroup, "/{id}/attendance/export", h.ExportAttendance, "ExportAttendance", &humagroup.HumaGroupOptions{
+		Summary:     "Export attendance",
+		Description: "Download the attendance report of a meeting as csv (host only)",
+		Scopes:      []string{auth.ScopeMeetingsRead},
+	})
+}
+
+type AttendanceResponse struct {
+	UserID          string                 `json:"userId,omitempty" doc:"Attendee ID, empty for deleted accounts"`
+	DisplayName     string                 `json:"displayName" doc:"Attendee display name"`
+	Role            models.ParticipantRole `json:"role,omitempty" doc:"Role of the attendee in the meeting"`
+	FirstJoined     time.Time              `json:"firstJoined" doc:"When the attendee first connected"`
+	LastLeft        *time.Time             `json:"lastLeft,omitempty" doc:"When the last closed session of the attendee ended"`
+	Connected       bool                   `json:"connected" doc:"Whether the attendee is connected right now"`
+	Sessions        int                    `json:"sessions" doc:"Number of times the attendee connected"`
+	DurationSeconds int64                  `json:"durationSeconds" doc:"Total time in the meeting in seconds"`
+}
+
+type GetAttendanceRequest struct {
+	AuthParam
+
+	ID string `path:"id" doc:"meeting id"`
+}
+
+type GetAttendanceResponse struct {
+	Body struct {
+		Attendance []AttendanceResponse `json:"attendance" doc:"attendees in the order they first joined"`
+	}
+}
+
+func (h *MeetingHandler) GetAttendance(ctx context.Context, input *GetAttendanceRequest) (*GetAttendanceResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, records, err := h.meetingService.AttendanceReport(ctx, input.ID, userID)
+	if err != nil {
+		return nil, attendanceError(err)
+	}
+
+	response := make([]AttendanceResponse, len(records))
+	for i, record := range records {
+		response[i] = AttendanceResponse{
+			UserID:          record.UserID,
+			DisplayName:     record.DisplayName,
+			Role:            record.Role,
+			FirstJoined:     record.FirstJoined,
+			Connected:       record.Connected,
+			Sessions:        record.Sessions,
+			DurationSeconds: int64(record.Duration / time.Second),
+		}
+		if !record.LastLeft.IsZero() {
+			lastLeft := record.LastLeft
+			response[i].LastLeft = &lastLeft
+		}
+	}
+
+	resp := &GetAttendanceResponse{}
+	resp.Body.Attendance = response
+	return resp, nil
+}
+
+type ExportAttendanceRequest struct {
+	AuthParam
+
+	ID       string `path:"id" doc:"meeting id"`
+	Timezone string `query:"timezone" default:"UTC" doc:"IANA timezone used for timestamps" example:"Europe/London"`
+}
+
+func (h *MeetingHandler) ExportAttendance(ctx context.Context, input *ExportAttendanceRequest) (*huma.StreamResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := time.LoadLocation(input.Timezone)
+	if err != nil {
+		return nil, huma.Error400BadRequest("invalid timezone", err)
+	}
+
+	meetingRes, records, err := h.meetingService.AttendanceReport(ctx, input.ID, userID)
+	if err != nil {
+		return nil, attendanceError(err)
+	}
+
+	filename := fmt.Sprintf("attendance-%s.csv", meetingRes.MeetingCode)
+
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			hctx.SetHeader("Content-Type", meeting.ExportFormatCSV.ContentType())
+			hctx.SetHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+			if err := meeting.WriteAttendanceCSV(hctx.BodyWriter(), loc, records); err != nil {
+				log.Printf("Failed to write attendance report: %v", err)
+			}
+		},
+	}, nil
+}
+
+// attendanceError maps the errors of the attendance report
+func attendanceError(err error) error {
+	switch {
+	case errors.Is(err, meeting.ErrMeetingNotFound):
+		return huma.Error404NotFound("meeting not found", err)
+	case errors.Is(err, meeting.ErrNotAuthorized):
+		return huma.Error403Forbidden("only the host can see the attendance", err)
+	default:
+		return huma.Error500InternalServerError("an error occured", err)
+	}
+}
@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/meeting"
+	humagroup "github.com/meetia/backend/lib/humaGroup"
+)
+
+func (h *MeetingHandler) registerBreakoutRoutes(meetingGroup *humagroup.HumaGroup) {
+	humagroup.Post(meetingGroup, "/{id}/breakouts", h.CreateBreakoutRooms, "CreateBreakoutRooms", &humagroup.HumaGroupOptions{
+		Summary:     "Create breakout rooms",
+		Description: "Add named or numbered breakout rooms to a meeting (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Get(meetingGroup, "/{id}/breakouts", h.ListBreakoutRooms, "ListBreakoutRooms", &humagroup.HumaGroupOptions{
+		Summary:     "List breakout rooms",
+		Description: "List the breakout rooms of a meeting and who is assigned to them (participants only)",
+		Scopes:      []string{auth.ScopeMeetingsRead},
+	})
+	humagroup.Delete(meetingGroup, "/{id}/breakouts/{roomId}", h.DeleteBreakoutRoom, "DeleteBreakoutRoom", &humagroup.HumaGroupOptions{
+		Summary:     "Delete a breakout room",
+		Description: "Remove a breakout room and its assignments while the rooms are closed (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Put(meetingGroup, "/{id}/breakouts/assignments", h.AssignBreakoutRooms, "AssignBreakoutRooms", &humagroup.HumaGroupOptions{
+		Summary:     "Assign participants to breakout rooms",
+		Description: "Move participants into rooms or out of them, while the rooms are open their media moves right away (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Post(meetingGroup, "/{id}/breakouts/shuffle", h.ShuffleBreakoutRooms, "ShuffleBreakoutRooms", &humagroup.HumaGroupOptions{
+		Summary:     "Assign participants randomly",
+		Description: "Spread the participants evenly over the rooms at random, replacing every assignment (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Post(meetingGroup, "/{id}/breakouts/open", h.OpenBreakoutRooms, "OpenBreakoutRooms", &humagroup.HumaGroupOptions{
+		Summary:     "Open breakout rooms",
+		Description: "Send the assigned participants into their rooms, optionally for a limited time (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Post(meetingGroup, "/{id}/breakouts/close", h.CloseBreakoutRooms, "CloseBreakoutRooms", &humagroup.HumaGroupOptions{
+		Summary:     "Close breakout rooms",
+		Description: "Bring everyone back to the meeting after a countdown (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Post(meetingGroup, "/{id}/breakouts/broadcast", h.BroadcastToBreakoutRooms, "BroadcastToBreakoutRooms", &humagroup.HumaGroupOptions{
+		Summary:     "Message all breakout rooms",
+		Description: "Send a message to every participant while the rooms are open (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+}
+
+type BreakoutAssignmentResponse struct {
+	UserID     string           `json:"userId" doc:"ID of the assigned participant"`
+	AssignedAt time.Time        `json:"assignedAt" doc:"When the participant was assigned"`
+	User       *UserDisplayName `json:"user" doc:"User details"`
+}
+
+type BreakoutRoomResponse struct {
+	ID           string                       `json:"id" doc:"Breakout room unique identifier"`
+	Name         string                       `json:"name" doc:"Breakout room name"`
+	Position     int                          `json:"position" doc:"Order of the room in the meeting"`
+	Participants []BreakoutAssignmentResponse `json:"participants" doc:"Participants assigned to the room"`
+}
+
+type BreakoutRoomsResponse struct {
+	Body struct {
+		Rooms []BreakoutRoomResponse `json:"rooms" doc:"breakout rooms of the meeting"`
+	}
+}
+
+type CreateBreakoutRoomsRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		Names []string `json:"names,omitempty" maxItems:"50" doc:"Names of the rooms to create" example:"[\"Design\",\"Backend\"]"`
+		Count int      `json:"count,omitempty" minimum:"0" maximum:"50" doc:"Number of rooms to create with numbered names, ignored when names are given" example:"4"`
+	}
+}
+
+func (h *MeetingHandler) CreateBreakoutRooms(ctx context.Context, input *CreateBreakoutRoomsRequest) (*BreakoutRoomsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range input.Body.Names {
+		if name == "" || len(name) > 100 {
+			return nil, huma.Error400BadRequest("room names must be 1 to 100 characters")
+		}
+	}
+
+	rooms, err := h.meetingService.CreateBreakoutRooms(ctx, input.ID, userID, input.Body.Count, input.Body.Names)
+	if err != nil {
+		return nil, breakoutError(err)
+	}
+	return breakoutRoomsToResponse(rooms), nil
+}
+
+type ListBreakoutRoomsRequest struct {
+	AuthParam
+
+	ID string `path:"id" doc:"meeting id"`
+}
+
+type ListBreakoutRoomsResponse struct {
+	Body struct {
+		Meeting MeetingResponse        `json:"meeting"`
+		Rooms   []BreakoutRoomResponse `json:"rooms" doc:"breakout rooms of the meeting"`
+	}
+}
+
+func (h *MeetingHandler) ListBreakoutRooms(ctx context.Context, input *ListBreakoutRoomsRequest) (*ListBreakoutRoomsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meetingRes, rooms, err := h.meetingService.ListBreakoutRooms(ctx, input.ID, userID)
+	if errors.Is(err, meeting.ErrNotAuthorized) {
+		return nil, huma.Error403Forbidden("only participants can see the breakout rooms", err)
+	}
+	if err != nil {
+		return nil, breakoutError(err)
+	}
+
+	resp := &ListBreakoutRoomsResponse{}
+	resp.Body.Meeting = meetingToResponse(meetingRes)
+	resp.Body.Rooms = breakoutRoomsToResponse(rooms).Body.Rooms
+	return resp, nil
+}
+
+type DeleteBreakoutRoomRequest struct {
+	AuthParam
+
+	ID     string `path:"id" doc:"meeting id"`
+	RoomID string `path:"roomId" doc:"breakout room id"`
+}
+
+func (h *MeetingHandler) DeleteBreakoutRoom(ctx context.Context, input *DeleteBreakoutRoomRequest) (*struct{}, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.meetingService.DeleteBreakoutRoom(ctx, input.ID, userID, input.RoomID); err != nil {
+		return nil, breakoutError(err)
+	}
+	return &struct{}{}, nil
+}
+
+type BreakoutAssignmentInput struct {
+	UserID string `json:"userId" required:"true" doc:"Participant to assign"`
+	RoomID string `json:"roomId,omitempty" doc:"Room to assign them to, empty takes them out of their room"`
+}
+
+type AssignBreakoutRoomsRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		Assignments []BreakoutAssignmentInput `json:"assignments" required:"true" minItems:"1" maxItems:"500" doc:"Participants and their rooms"`
+	}
+}
+
+func (h *MeetingHandler) AssignBreakoutRooms(ctx context.Context, input *AssignBreakoutRoomsRequest) (*BreakoutRoomsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := make(map[string]string, len(input.Body.Assignments))
+	for _, a := range input.Body.Assignments {
+		assignments[a.UserID] = a.RoomID
+	}
+
+	rooms, err := h.meetingService.AssignBreakouts(ctx, input.ID, userID, assignments)
+	if err != nil {
+		return nil, breakoutError(err)
+	}
+	return breakoutRoomsToResponse(rooms), nil
+}
+
+type ShuffleBreakoutRoomsRequest struct {
+	AuthParam
+
+	ID string `path:"id" doc:"meeting id"`
+}
+
+func (h *MeetingHandler) ShuffleBreakoutRooms(ctx context.Context, input *ShuffleBreakoutRoomsRequest) (*BreakoutRoomsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rooms, err := h.meetingService.ShuffleBreakouts(ctx, input.ID, userID)
+	if err != nil {
+		return nil, breakoutError(err)
+	}
+	return breakoutRoomsToResponse(rooms), nil
+}
+
+type OpenBreakoutRoomsRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		DurationMinutes int `json:"durationMinutes,omitempty" minimum:"0" maximum:"1440" doc:"Close the rooms on their own after this many minutes, 0 keeps them open until closed" example:"15"`
+	}
+}
+
+type BreakoutStateResponse struct {
+	Body struct {
+		Meeting MeetingResponse `json:"meeting"`
+	}
+}
+
+func (h *MeetingHandler) OpenBreakoutRooms(ctx context.Context, input *OpenBreakoutRoomsRequest) (*BreakoutStateResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := time.Duration(input.Body.DurationMinutes) * time.Minute
+	meetingRes, err := h.meetingService.OpenBreakouts(ctx, input.ID, userID, duration)
+	if err != nil {
+		return nil, breakoutError(err)
+	}
+
+	resp := &BreakoutStateResponse{}
+	resp.Body.Meeting = meetingToResponse(meetingRes)
+	return resp, nil
+}
+
+type CloseBreakoutRoomsRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		CountdownSeconds int `json:"countdownSeconds" minimum:"0" maximum:"600" default:"60" doc:"Seconds participants get to wrap up, 0 brings them back right away" example:"60"`
+	}
+}
+
+func (h *MeetingHandler) CloseBreakoutRooms(ctx context.Context, input *CloseBreakoutRoomsRequest) (*BreakoutStateResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	countdown := time.Duration(input.Body.CountdownSeconds) * time.Second
+	meetingRes, err := h.meetingService.CloseBreakouts(ctx, input.ID, userID, countdown)
+	if err != nil {
+		return nil, breakoutError(err)
+	}
+
+	resp := &BreakoutStateResponse{}
+	resp.Body.Meeting = meetingToResponse(meetingRes)
+	return resp, nil
+}
+
+type BroadcastToBreakoutRoomsRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		Message string `json:"message" required:"true" minLength:"1" maxLength:"1000" doc:"Message to show in every room" example:"Five minutes left"`
+	}
+}
+
+func (h *MeetingHandler) BroadcastToBreakoutRooms(ctx context.Context, input *BroadcastToBreakoutRoomsRequest) (*struct{}, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.meetingService.BroadcastToBreakouts(ctx, input.ID, userID, input.Body.Message); err != nil {
+		return nil, breakoutError(err)
+	}
+	return &struct{}{}, nil
+}
+
+func breakoutRoomsToResponse(rooms []*models.BreakoutRoom) *BreakoutRoomsResponse {
+	response := make([]BreakoutRoomResponse, len(rooms))
+	for i, room := range rooms {
+		participants := make([]BreakoutAssignmentResponse, len(room.Assignments))
+		for j, a := range room.Assignments {
+			participants[j] = BreakoutAssignmentResponse{
+				UserID:     a.UserID,
+				AssignedAt: a.AssignedAt,
+				User:       newUserDisplayName(a.User),
+			}
+		}
+		response[i] = BreakoutRoomResponse{
+			ID:           room.ID,
+			Name:         room.Name,
+			Position:     room.Position,
+			Participants: participants,
+		}
+	}
+
+	resp := &BreakoutRoomsResponse{}
+	resp.Body.Rooms = response
+	return resp
+}
+
+// breakoutError maps the errors of managing breakout rooms
+func breakoutError(err error) error {
+	switch {
+	case errors.Is(err, meeting.ErrMeetingNotFound):
+		return huma.Error404NotFound("meeting not found", err)
+	case errors.Is(err, meeting.ErrBreakoutRoomNotFound):
+		return huma.Error404NotFound("breakout room not found", err)
+	case errors.Is(err, meeting.ErrNotAuthorized):
+		return huma.Error403Forbidden("only hosts and co-hosts can manage breakout rooms", err)
+	case errors.Is(err, meeting.ErrMeetingEnded):
+		return huma.Error410Gone("meeting has ended", err)
+	case errors.Is(err, meeting.ErrBreakoutsOpen), errors.Is(err, meeting.ErrBreakoutsClosed):
+		return huma.Error409Conflict(err.Error(), err)
+	case errors.Is(err, meeting.ErrNoBreakoutRooms), errors.Is(err, meeting.ErrInvalidBreakout):
+		return huma.Error400BadRequest(err.Error(), err)
+	default:
+		return huma.Error500InternalServerError("an error occured", err)
+	}
+}
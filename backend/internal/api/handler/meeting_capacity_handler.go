@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/meeting"
+	humagroup "github.com/meetia/backend/lib/humaGroup"
+)
+
+func (h *MeetingHandler) registerCapacityRoutes(meetingGroup *humagroup.HumaGroup) {
+	humagroup.Put(meetingGroup, "/{id}/lock", h.SetMeetingLocked, "SetMeetingLocked", &humagroup.HumaGroupOptions{
+		Summary:     "Lock or unlock a meeting",
+		Description: "Turn away new joiners, participants who already joined can still reconnect (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Put(meetingGroup, "/{id}/capacity", h.SetMeetingCapacity, "SetMeetingCapacity", &humagroup.HumaGroupOptions{
+		Summary:     "Limit the participants of a meeting",
+		Description: "Cap how many users may be connected at once, up to the server limit. 0 goes back to the server limit (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+}
+
+type SetMeetingLockedRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		Locked bool `json:"locked" doc:"Whether new joiners are turned away" example:"true"`
+	}
+}
+
+type MeetingSettingsResponse struct {
+	Body struct {
+		Meeting MeetingResponse `json:"meeting"`
+	}
+}
+
+func (h *MeetingHandler) SetMeetingLocked(ctx context.Context, input *SetMeetingLockedRequest) (*MeetingSettingsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meetingRes, err := h.meetingService.SetLocked(ctx, input.ID, userID, input.Body.Locked)
+	if err != nil {
+		return nil, capacityError(err)
+	}
+
+	resp := &MeetingSettingsResponse{}
+	resp.Body.Meeting = meetingToResponse(meetingRes)
+	return resp, nil
+}
+
+type SetMeetingCapacityRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		MaxParticipants int `json:"maxParticipants" minimum:"0" doc:"Users connected at once, 0 for the server limit" example:"25"`
+	}
+}
+
+func (h *MeetingHandler) SetMeetingCapacity(ctx context.Context, input *SetMeetingCapacityRequest) (*MeetingSettingsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meetingRes, err := h.meetingService.SetMaxParticipants(ctx, input.ID, userID, input.Body.MaxParticipants)
+	if err != nil {
+		return nil, capacityError(err)
+	}
+
+	resp := &MeetingSettingsResponse{}
+	resp.Body.Meeting = meetingToResponse(meetingRes)
+	return resp, nil
+}
+
+// capacityError maps the errors of locking and limiting a meeting
+func capacityError(err error) error {
+	switch {
+	case errors.Is(err, meeting.ErrMeetingNotFound):
+		return huma.Error404NotFound("meeting not found", err)
+	case errors.Is(err, meeting.ErrNotAuthorized):
+		return huma.Error403Forbidden("not allowed to change who can join this meeting", err)
+	case errors.Is(err, meeting.ErrInvalidCapacity):
+		return huma.Error400BadRequest(err.Error(), err)
+	default:
+		return huma.Error500InternalServerError("an error occured", err)
+	}
+}
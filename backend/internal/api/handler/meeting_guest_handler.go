@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/meetia/backend/internal/api/middleware"
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/meeting"
+	humagroup "github.com/meetia/backend/lib/humaGroup"
+)
+
+// GuestTokenIssuer signs the meeting scoped tokens guests connect with
+type GuestTokenIssuer interface {
+	GenerateGuestToken(guestID, meetingID, displayName string) (string, time.Time, error)
+}
+
+func (h *MeetingHandler) registerGuestRoutes(api huma.API, meetingGroup *humagroup.HumaGroup) {
+	// joining as a guest is how guests get a token, it can't require one
+	guestGroup := humagroup.NewHumaGroup(api, "/api/meetings", []string{"Meetings"})
+
+	humagroup.Put(meetingGroup, "/{id}/guests", h.SetMeetingAllowGuests, "SetMeetingAllowGuests", &humagroup.HumaGroupOptions{
+		Summary:     "Allow or disallow guests",
+		Description: "Let people without an account join with a display name, guests who already joined stay (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+
+	humagroup.Post(guestGroup, "/join-guest", h.JoinMeetingAsGuest, "JoinMeetingAsGuest", &humagroup.HumaGroupOptions{
+		Summary:     "Join a meeting as a guest",
+		Description: "Join a meeting that allows guests with a display name instead of an account. The guest token is only accepted by the signaling and chat endpoints of that meeting.",
+	})
+}
+
+type SetMeetingAllowGuestsRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		Allowed bool `json:"allowed" doc:"Whether people without an account can join" example:"true"`
+	}
+}
+
+func (h *MeetingHandler) SetMeetingAllowGuests(ctx context.Context, input *SetMeetingAllowGuestsRequest) (*MeetingSettingsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meetingRes, err := h.meetingService.SetAllowGuests(ctx, input.ID, userID, input.Body.Allowed)
+	if err != nil {
+		return nil, hostOnlyError(err)
+	}
+
+	resp := &MeetingSettingsResponse{}
+	resp.Body.Meeting = meetingToResponse(meetingRes)
+	return resp, nil
+}
+
+type JoinMeetingAsGuestRequest struct {
+	Body struct {
+		MeetingCode string `json:"meetingCode" required:"true" doc:"Code of the meeting to join" example:"ABC123XYZ0"`
+		DisplayName string `json:"displayName" required:"true" minLength:"1" maxLength:"100" doc:"Name shown to the other participants" example:"Jamie"`
+		Password    string `json:"password,omitempty" doc:"Password if the meeting is private" example:"securepass123"`
+	}
+}
+
+type JoinMeetingAsGuestResponse struct {
+	Body struct {
+		Meeting           MeetingResponse          `json:"meeting"`
+		ParticipantStatus models.ParticipantStatus `json:"participantStatus" enum:"waiting,admitted" doc:"waiting until a host admits the guest, signaling refuses a peer connection until then"`
+		GuestID           string                   `json:"guestId" doc:"ID the guest appears with on signaling and in the chat"`
+		Token             string                   `json:"token" doc:"Guest token for the signaling and chat endpoints of the meeting"`
+		ExpiresAt         time.Time                `json:"expiresAt" doc:"When the guest token expires, it can't be refreshed"`
+	}
+}
+
+func (h *MeetingHandler) JoinMeetingAsGuest(ctx context.Context, input *JoinMeetingAsGuestRequest) (*JoinMeetingAsGuestResponse, error) {
+	joinedMeeting, participant, err := h.meetingService.JoinAsGuest(ctx, middleware.GetClientIP(ctx), input.Body.MeetingCode, input.Body.DisplayName, input.Body.Password)
+	if err != nil {
+		if limitErr := rateLimitError(err); limitErr != nil {
+			return nil, limitErr
+		}
+		switch {
+		case errors.Is(err, meeting.ErrMeetingNotFound):
+			return nil, huma.Error404NotFound("meeting not found", err)
+		case errors.Is(err, meeting.ErrInvalidPassword):
+			return nil, huma.Error401Unauthorized("Invalid password", err)
+		case errors.Is(err, meeting.ErrMeetingEnded):
+			return nil, huma.Error410Gone("meeting has ended", err)
+		case errors.Is(err, meeting.ErrInvalidGuestName):
+			return nil, huma.Error400BadRequest(err.Error(), err)
+		case errors.Is(err, meeting.ErrGuestsNotAllowed),
+			errors.Is(err, meeting.ErrNotInvited),
+			errors.Is(err, meeting.ErrMeetingNotStarted),
+			errors.Is(err, meeting.ErrMeetingLocked),
+			errors.Is(err, meeting.ErrMeetingFull):
+			return nil, huma.Error403Forbidden(err.Error(), err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
+	token, expiresAt, err := h.guestTokens.GenerateGuestToken(participant.GuestID, joinedMeeting.ID, participant.DisplayName())
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to issue guest token", err)
+	}
+
+	resp := &JoinMeetingAsGuestResponse{}
+	resp.Body.Meeting = meetingToResponse(joinedMeeting)
+	resp.Body.ParticipantStatus = participant.Status
+	resp.Body.GuestID = participant.GuestID
+	resp.Body.Token = token
+	resp.Body.ExpiresAt = expiresAt
+	return resp, nil
+}
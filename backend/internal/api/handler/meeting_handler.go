@@ -3,63 +3,109 @@ package handler
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/danielgtaylor/huma/v2"
-	"github.com/go-chi/jwtauth/v5"
 
 	"github.com/meetia/backend/internal/api/middleware"
 	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/auth"
 	"github.com/meetia/backend/internal/services/meeting"
 	humagroup "github.com/meetia/backend/lib/humaGroup"
 )
 
 type MeetingHandler struct {
 	meetingService *meeting.MeetingService
-	tokenAuth      *jwtauth.JWTAuth
+	tokenVerifier  middleware.TokenVerifier
+	guestTokens    GuestTokenIssuer
 }
 
-func NewMeetinghandler(meetingService *meeting.MeetingService, tokenAuth *jwtauth.JWTAuth) *MeetingHandler {
+func NewMeetinghandler(meetingService *meeting.MeetingService, tokenVerifier middleware.TokenVerifier, guestTokens GuestTokenIssuer) *MeetingHandler {
 	return &MeetingHandler{
 		meetingService: meetingService,
-		tokenAuth:      tokenAuth,
+		tokenVerifier:  tokenVerifier,
+		guestTokens:    guestTokens,
 	}
 }
 
 func (h *MeetingHandler) RegisterRoutes(api huma.API) {
-	meetingGroup := humagroup.NewHumaGroup(api, "/api/meetings", []string{"Meetings"}, middleware.JWTMiddleware(h.tokenAuth))
+	meetingGroup := humagroup.NewHumaGroup(api, "/api/meetings", []string{"Meetings"}, middleware.JWTMiddleware(h.tokenVerifier))
 
 	humagroup.Post(meetingGroup, "", h.CreateMeeting, "CreateMeeting", &humagroup.HumaGroupOptions{
 		Summary:     "Create a new meeting",
 		Description: "Creates a new meeting with the current user as host",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
 	})
 	humagroup.Post(meetingGroup, "/join", h.JoinMeeting, "JoinMeeting", &humagroup.HumaGroupOptions{
 		Summary:     "Join an existing meeting",
 		Description: "Join a meeting using its meeting code and password (if required)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
 	})
 	humagroup.Get(meetingGroup, "", h.ListMeetings, "ListMeetings", &humagroup.HumaGroupOptions{
 		Summary:     "List user's meetings",
-		Description: "Get a list of active meetings where the user is a host or participant",
+		Description: "List meetings where the user is a host or participant, filtered by upcoming, live or past",
+		Scopes:      []string{auth.ScopeMeetingsRead},
 	})
+	h.registerSeriesRoutes(meetingGroup)
+	h.registerInvitationRoutes(api, meetingGroup)
+	h.registerLobbyRoutes(meetingGroup)
+	h.registerRoleRoutes(meetingGroup)
+	h.registerBreakoutRoutes(meetingGroup)
+	h.registerPollRoutes(meetingGroup)
+	h.registerQuestionRoutes(meetingGroup)
+	h.registerAttendanceRoutes(meetingGroup)
+	h.registerCapacityRoutes(meetingGroup)
+	h.registerGuestRoutes(api, meetingGroup)
+	h.registerPersonalRoutes(meetingGroup)
 	humagroup.Get(meetingGroup, "/{id}", h.GetMeeting, "GetMeeting", &humagroup.HumaGroupOptions{
 		Summary:     "Get meeting details",
 		Description: "Get details about a specific meeting",
+		Scopes:      []string{auth.ScopeMeetingsRead},
+	})
+	humagroup.Get(meetingGroup, "/{id}/invite.ics", h.GetMeetingInvite, "GetMeetingInvite", &humagroup.HumaGroupOptions{
+		Summary:     "Download calendar invite",
+		Description: "Download the meeting as an iCalendar event with the host as organizer and the join link (host, participants and invitees only)",
+		Scopes:      []string{auth.ScopeMeetingsRead},
 	})
 	humagroup.Post(meetingGroup, "/{id}/end", h.EndMeeting, "EndMeeting", &humagroup.HumaGroupOptions{
 		Summary:     "End a meeting",
-		Description: "End a meeting (host only)",
+		Description: "End a meeting, personal meetings are reset for their next session instead (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Put(meetingGroup, "/{id}/password", h.ChangeMeetingPassword, "ChangeMeetingPassword", &humagroup.HumaGroupOptions{
+		Summary:     "Change meeting password",
+		Description: "Set a new password and make the meeting private (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Post(meetingGroup, "/{id}/code", h.RegenerateMeetingCode, "RegenerateMeetingCode", &humagroup.HumaGroupOptions{
+		Summary:     "Regenerate meeting code",
+		Description: "Replace the meeting code so the old one can no longer be used to join (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
 	})
 	humagroup.Get(meetingGroup, "/{id}/participants", h.GetParticipants, "GetParticipants", &humagroup.HumaGroupOptions{
 		Summary:     "Get meeting participants",
 		Description: "Get a list of participants in a meeting",
+		Scopes:      []string{auth.ScopeMeetingsRead},
 	})
 	humagroup.Post(meetingGroup, "/{id}/chat", h.SendChatMessage, "SendChatMessage", &humagroup.HumaGroupOptions{
 		Summary:     "Send a chat message",
 		Description: "Send a chat message in a meeting",
+		Scopes:      []string{auth.ScopeChatWrite},
+		GuestParam:  "id",
 	})
 	humagroup.Get(meetingGroup, "/{id}/chat", h.GetChatMessages, "GetChatMessages", &humagroup.HumaGroupOptions{
 		Summary:     "Get chat messages",
 		Description: "Get chat message history for a meeting",
+		Scopes:      []string{auth.ScopeChatRead},
+		GuestParam:  "id",
+	})
+	humagroup.Get(meetingGroup, "/{id}/chat/export", h.ExportChat, "ExportChat", &humagroup.HumaGroupOptions{
+		Summary:     "Export chat transcript",
+		Description: "Download the chat transcript of a meeting as txt, json, csv or html (participants only)",
+		Scopes:      []string{auth.ScopeChatRead},
+		GuestParam:  "id",
 	})
 }
 
@@ -67,9 +113,15 @@ type CreateMeetingRequest struct {
 	AuthParam
 
 	Body struct {
-		Title     string `json:"title" doc:"Meeting title" example:"Team Weekly Sync"`
-		IsPrivate bool   `json:"isPrivate" doc:"Whether the meeting requires a password" example:"false"`
-		Password  string `json:"password,omitempty" doc:"Password if the meeting is private" example:"securepass123"`
+		Title       string `json:"title" doc:"Meeting title" example:"Team Weekly Sync"`
+		IsPrivate   bool   `json:"isPrivate" doc:"Whether the meeting requires a password" example:"false"`
+		Password    string `json:"password,omitempty" maxLength:"72" doc:"Password if the meeting is private" example:"securepass123"`
+		InviteOnly  bool   `json:"inviteOnly,omitempty" doc:"Whether only the host and invited users can join" example:"false"`
+		WaitingRoom bool   `json:"waitingRoom,omitempty" doc:"Whether joiners wait in a lobby until a host admits them" example:"false"`
+
+		ScheduledAt     *time.Time `json:"scheduledAt,omitempty" doc:"Planned start, omit for an instant meeting" example:"2026-11-02T15:00:00+01:00"`
+		DurationMinutes int        `json:"durationMinutes,omitempty" minimum:"0" maximum:"1440" doc:"Planned length in minutes, defaults to the server setting" example:"45"`
+		Timezone        string     `json:"timezone,omitempty" doc:"IANA timezone the meeting was planned in" example:"Europe/Berlin"`
 	}
 }
 
@@ -79,13 +131,27 @@ type UserInfoSmall struct {
 }
 
 type MeetingResponse struct {
-	ID          string         `json:"id" doc:"Meeting unique identifier"`
-	Title       string         `json:"title" doc:"Meeting title"`
-	HostID      string         `json:"hostId" doc:"ID of the meeting host"`
-	MeetingCode string         `json:"meetingCode" doc:"Unique code to join the meeting"`
-	IsPrivate   bool           `json:"isPrivate" doc:"Whether the meeting requires a password"`
-	CreatedAt   time.Time      `json:"createdAt" doc:"When the meeting was created"`
-	Host        *UserInfoSmall `json:"host,omitempty" doc:"Host details"`
+	ID               string               `json:"id" doc:"Meeting unique identifier"`
+	Title            string               `json:"title" doc:"Meeting title"`
+	HostID           string               `json:"hostId" doc:"ID of the meeting host"`
+	MeetingCode      string               `json:"meetingCode" doc:"Unique code to join the meeting"`
+	IsPrivate        bool                 `json:"isPrivate" doc:"Whether the meeting requires a password"`
+	InviteOnly       bool                 `json:"inviteOnly" doc:"Whether only the host and invited users can join"`
+	AllowGuests      bool                 `json:"allowGuests" doc:"Whether people without an account can join with a display name"`
+	Personal         bool                 `json:"personal" doc:"Whether this is the permanent room of its host, it never ends"`
+	WaitingRoom      bool                 `json:"waitingRoom" doc:"Whether joiners wait in a lobby until a host admits them"`
+	Locked           bool                 `json:"locked" doc:"Whether new joiners are turned away"`
+	MaxParticipants  int                  `json:"maxParticipants,omitempty" doc:"Users connected at once the host allows, missing for the server default"`
+	Status           models.MeetingStatus `json:"status" enum:"upcoming,live,past" doc:"Whether the meeting is still to come, can be joined, or is over"`
+	ScheduledAt      *time.Time           `json:"scheduledAt,omitempty" doc:"Planned start of a scheduled meeting"`
+	DurationMinutes  int                  `json:"durationMinutes,omitempty" doc:"Planned length of a scheduled meeting in minutes"`
+	Timezone         string               `json:"timezone,omitempty" doc:"IANA timezone the meeting was planned in"`
+	EndedAt          *time.Time           `json:"endedAt,omitempty" doc:"When the meeting ended"`
+	SeriesID         string               `json:"seriesId,omitempty" doc:"Series the meeting is an occurrence of"`
+	BreakoutsOpen    bool                 `json:"breakoutsOpen" doc:"Whether participants are in breakout rooms"`
+	BreakoutsCloseAt *time.Time           `json:"breakoutsCloseAt,omitempty" doc:"When the open breakout rooms close"`
+	CreatedAt        time.Time            `json:"createdAt" doc:"When the meeting was created"`
+	Host             *UserInfoSmall       `json:"host,omitempty" doc:"Host details"`
 }
 
 type CreateMeetingResponse struct {
@@ -101,13 +167,28 @@ func (h *MeetingHandler) CreateMeeting(ctx context.Context, input *CreateMeeting
 		return nil, err
 	}
 
-	meeting, err := h.meetingService.CreateMeeting(ctx, input.Body.Title, userID, input.Body.IsPrivate, input.Body.Password)
+	schedule := meeting.Schedule{
+		Duration: time.Duration(input.Body.DurationMinutes) * time.Minute,
+		Timezone: input.Body.Timezone,
+	}
+	if input.Body.ScheduledAt != nil {
+		schedule.StartAt = *input.Body.ScheduledAt
+	}
+
+	meetingRes, err := h.meetingService.CreateMeeting(ctx, input.Body.Title, userID, input.Body.IsPrivate, input.Body.Password, input.Body.InviteOnly, input.Body.WaitingRoom, schedule)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("an error occured while creating meeting", err)
+		switch {
+		case errors.Is(err, meeting.ErrPasswordRequired):
+			return nil, huma.Error400BadRequest("private meetings need a password", err)
+		case errors.Is(err, meeting.ErrInvalidSchedule):
+			return nil, huma.Error400BadRequest(err.Error(), err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured while creating meeting", err)
+		}
 	}
 
 	resp := &CreateMeetingResponse{}
-	meetingResp := meetingToResponse(meeting)
+	meetingResp := meetingToResponse(meetingRes)
 	resp.Body.Meeting = meetingResp
 	return resp, nil
 }
@@ -123,7 +204,8 @@ type JoinMeetingRequest struct {
 
 type JoinMeetingResponse struct {
 	Body struct {
-		Meeting MeetingResponse `json:"meeting"`
+		Meeting           MeetingResponse          `json:"meeting"`
+		ParticipantStatus models.ParticipantStatus `json:"participantStatus" enum:"waiting,admitted" doc:"waiting until a host admits the user, signaling refuses a peer connection until then"`
 	}
 }
 
@@ -133,13 +215,26 @@ func (h *MeetingHandler) JoinMeeting(ctx context.Context, input *JoinMeetingRequ
 		return nil, err
 	}
 
-	joinedmeeting, err := h.meetingService.JoinMeeting(ctx, input.Body.MeetingCode, userID, input.Body.Password)
+	joinedmeeting, participant, err := h.meetingService.JoinMeeting(ctx, middleware.GetClientIP(ctx), input.Body.MeetingCode, userID, input.Body.Password)
 	if err != nil {
+		if limitErr := rateLimitError(err); limitErr != nil {
+			return nil, limitErr
+		}
 		switch {
 		case errors.Is(err, meeting.ErrMeetingNotFound):
 			return nil, huma.Error404NotFound("meeting not found", err)
 		case errors.Is(err, meeting.ErrInvalidPassword):
 			return nil, huma.Error401Unauthorized("Invalid password", err)
+		case errors.Is(err, meeting.ErrMeetingEnded):
+			return nil, huma.Error410Gone("meeting has ended", err)
+		case errors.Is(err, meeting.ErrMeetingNotStarted):
+			return nil, huma.Error403Forbidden(err.Error(), err)
+		case errors.Is(err, meeting.ErrNotInvited):
+			return nil, huma.Error403Forbidden("this meeting is invite only", err)
+		case errors.Is(err, meeting.ErrAdmissionDenied):
+			return nil, huma.Error403Forbidden(err.Error(), err)
+		case errors.Is(err, meeting.ErrMeetingLocked), errors.Is(err, meeting.ErrMeetingFull):
+			return nil, huma.Error403Forbidden(err.Error(), err)
 		default:
 			return nil, huma.Error500InternalServerError("an error occured", err)
 		}
@@ -147,16 +242,26 @@ func (h *MeetingHandler) JoinMeeting(ctx context.Context, input *JoinMeetingRequ
 
 	resp := &JoinMeetingResponse{}
 	resp.Body.Meeting = meetingToResponse(joinedmeeting)
+	resp.Body.ParticipantStatus = participant.Status
 	return resp, nil
 }
 
 type ListMeetingsRequest struct {
 	AuthParam
+
+	Status   string    `query:"status" default:"active" enum:"active,upcoming,live,past,all" doc:"active is upcoming and live meetings"`
+	From     time.Time `query:"from" doc:"Only meetings starting at or after this time, instant meetings start when created"`
+	To       time.Time `query:"to" doc:"Only meetings starting before this time"`
+	Page     int       `query:"page" default:"1" minimum:"1" doc:"Page number"`
+	PageSize int       `query:"pageSize" default:"50" minimum:"1" maximum:"100" doc:"Number of meetings per page"`
 }
 
 type ListMeetingsResponse struct {
 	Body struct {
-		Meetings []MeetingResponse `json:"meetings" doc:"list of active meetings for user"`
+		Meetings []MeetingResponse `json:"meetings" doc:"meetings the user hosts or joined"`
+		Total    int               `json:"total" doc:"total number of matching meetings"`
+		Page     int               `json:"page" doc:"current page"`
+		PageSize int               `json:"pageSize" doc:"number of meetings per page"`
 	}
 }
 
@@ -166,7 +271,7 @@ func (h *MeetingHandler) ListMeetings(ctx context.Context, input *ListMeetingsRe
 		return nil, err
 	}
 
-	meetings, err := h.meetingService.GetActiveUserMeetings(ctx, userID)
+	meetings, total, err := h.meetingService.ListUserMeetings(ctx, userID, input.Status, input.From, input.To, input.Page, input.PageSize)
 	if err != nil {
 		return nil, huma.Error500InternalServerError("failed to list meetings", err)
 	}
@@ -178,6 +283,9 @@ func (h *MeetingHandler) ListMeetings(ctx context.Context, input *ListMeetingsRe
 
 	resp := &ListMeetingsResponse{}
 	resp.Body.Meetings = response
+	resp.Body.Total = total
+	resp.Body.Page = input.Page
+	resp.Body.PageSize = input.PageSize
 	return resp, nil
 }
 
@@ -211,6 +319,47 @@ func (h *MeetingHandler) GetMeeting(ctx context.Context, input *GetMeetingReques
 	return resp, nil
 }
 
+type GetMeetingInviteRequest struct {
+	AuthParam
+
+	ID string `path:"id" doc:"meeting id"`
+}
+
+func (h *MeetingHandler) GetMeetingInvite(ctx context.Context, input *GetMeetingInviteRequest) (*huma.StreamResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meetingRes, err := h.meetingService.GetMeetingInvite(ctx, input.ID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, meeting.ErrMeetingNotFound):
+			return nil, huma.Error404NotFound("meeting not found", err)
+		case errors.Is(err, meeting.ErrNotAuthorized):
+			return nil, huma.Error403Forbidden("only the host, participants and invitees can download the invite", err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
+	return h.calendarResponse("meeting-"+meetingRes.MeetingCode+".ics", []*models.Meeting{meetingRes}, nil), nil
+}
+
+// calendarResponse streams the meetings and series as an iCalendar attachment
+func (h *MeetingHandler) calendarResponse(filename string, meetings []*models.Meeting, series []*models.MeetingSeries) *huma.StreamResponse {
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			hctx.SetHeader("Content-Type", meeting.CalendarContentType)
+			hctx.SetHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+			if err := h.meetingService.WriteCalendar(hctx.BodyWriter(), "", meetings, series); err != nil {
+				log.Printf("Failed to write calendar: %v", err)
+			}
+		},
+	}
+}
+
 type EndMeetingRequest struct {
 	AuthParam
 
@@ -239,15 +388,98 @@ func (h *MeetingHandler) EndMeeting(ctx context.Context, input *EndMeetingReques
 	return &struct{}{}, nil
 }
 
+type ChangeMeetingPasswordRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		Password string `json:"password" required:"true" minLength:"4" maxLength:"72" doc:"New meeting password" example:"securepass123"`
+	}
+}
+
+type ChangeMeetingPasswordResponse struct {
+	Body struct {
+		Meeting MeetingResponse `json:"meeting"`
+	}
+}
+
+func (h *MeetingHandler) ChangeMeetingPassword(ctx context.Context, input *ChangeMeetingPasswordRequest) (*ChangeMeetingPasswordResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meetingRes, err := h.meetingService.ChangePassword(ctx, input.ID, userID, input.Body.Password)
+	if err != nil {
+		return nil, hostOnlyError(err)
+	}
+
+	resp := &ChangeMeetingPasswordResponse{}
+	resp.Body.Meeting = meetingToResponse(meetingRes)
+	return resp, nil
+}
+
+type RegenerateMeetingCodeRequest struct {
+	AuthParam
+
+	ID string `path:"id" doc:"meeting id"`
+}
+
+type RegenerateMeetingCodeResponse struct {
+	Body struct {
+		Meeting MeetingResponse `json:"meeting"`
+	}
+}
+
+func (h *MeetingHandler) RegenerateMeetingCode(ctx context.Context, input *RegenerateMeetingCodeRequest) (*RegenerateMeetingCodeResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meetingRes, err := h.meetingService.RegenerateCode(ctx, input.ID, userID)
+	if err != nil {
+		return nil, hostOnlyError(err)
+	}
+
+	resp := &RegenerateMeetingCodeResponse{}
+	resp.Body.Meeting = meetingToResponse(meetingRes)
+	return resp, nil
+}
+
+// hostOnlyError maps the errors of meeting settings only the host may change
+func hostOnlyError(err error) error {
+	switch {
+	case errors.Is(err, meeting.ErrMeetingNotFound):
+		return huma.Error404NotFound("meeting not found", err)
+	case errors.Is(err, meeting.ErrNotAuthorized):
+		return huma.Error403Forbidden("only the host can change meeting settings", err)
+	case errors.Is(err, meeting.ErrPasswordRequired):
+		return huma.Error400BadRequest("private meetings need a password", err)
+	default:
+		return huma.Error500InternalServerError("an error occured", err)
+	}
+}
+
 type UserDisplayName struct {
 	DisplayName string `json:"displayName" doc:"User display name"`
 }
 
+// newUserDisplayName handles the missing user of records left behind by deleted accounts
+func newUserDisplayName(user *models.User) *UserDisplayName {
+	if user == nil {
+		return &UserDisplayName{DisplayName: models.DeletedUserDisplayName}
+	}
+	return &UserDisplayName{DisplayName: user.DisplayName}
+}
+
 type ParticipantResponse struct {
-	ID     string           `json:"id" doc:"Participant record ID"`
-	UserID string           `json:"userId" doc:"User ID"`
-	Role   string           `json:"role" doc:"Participant role (host, co-host, participant)"`
-	User   *UserDisplayName `json:"user" doc:"User details"`
+	ID      string           `json:"id" doc:"Participant record ID"`
+	UserID  string           `json:"userId,omitempty" doc:"User ID, missing for guests"`
+	GuestID string           `json:"guestId,omitempty" doc:"Guest ID of participants without an account"`
+	Role    string           `json:"role" enum:"Host,Co-Host,Participant" doc:"Participant role"`
+	Status  string           `json:"status" enum:"waiting,admitted,denied" doc:"Whether the participant waits in the lobby, was admitted or denied"`
+	User    *UserDisplayName `json:"user" doc:"User details"`
 }
 
 type GetParticipantsRequest struct {
@@ -273,12 +505,12 @@ func (h *MeetingHandler) GetParticipants(ctx context.Context, input *GetParticip
 	response := make([]ParticipantResponse, len(participants))
 	for i, p := range participants {
 		response[i] = ParticipantResponse{
-			ID:     p.ID,
-			UserID: p.UserID,
-			Role:   string(p.Role),
-			User: &UserDisplayName{
-				DisplayName: p.User.DisplayName,
-			},
+			ID:      p.ID,
+			UserID:  p.UserID,
+			GuestID: p.GuestID,
+			Role:    string(p.Role),
+			Status:  string(p.Status),
+			User:    &UserDisplayName{DisplayName: p.DisplayName()},
 		}
 	}
 
@@ -306,6 +538,9 @@ func (h *MeetingHandler) SendChatMessage(ctx context.Context, input *SendChatMes
 
 	err = h.meetingService.SaveChatMessage(ctx, meetingID, userID, message)
 	if err != nil {
+		if errors.Is(err, meeting.ErrNotAuthorized) {
+			return nil, huma.Error403Forbidden("guests can only chat once admitted", err)
+		}
 		return nil, huma.Error500InternalServerError("failed to send message", err)
 	}
 
@@ -316,6 +551,7 @@ type ChatMessageResponse struct {
 	ID        string           `json:"id" doc:"Message unique identifier"`
 	MeetingID string           `json:"meetingId" doc:"ID of the meeting this message belongs to"`
 	UserID    string           `json:"userId" doc:"ID of the user who sent the message"`
+	GuestID   string           `json:"guestId,omitempty" doc:"ID of the guest who sent the message"`
 	Message   string           `json:"message" doc:"Message content"`
 	SentAt    time.Time        `json:"sentAt" doc:"When the message was sent"`
 	User      *UserDisplayName `json:"user" doc:"User details"`
@@ -334,10 +570,17 @@ type GetChatMessagesResponse struct {
 }
 
 func (h *MeetingHandler) GetChatMessages(ctx context.Context, input *GetChatMessagesRequest) (*GetChatMessagesResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
 	meetingID := input.ID
 
-	messages, err := h.meetingService.GetChatHistory(ctx, meetingID)
+	messages, err := h.meetingService.GetChatHistory(ctx, meetingID, userID)
 	if err != nil {
+		if errors.Is(err, meeting.ErrNotAuthorized) {
+			return nil, huma.Error403Forbidden("guests can only chat once admitted", err)
+		}
 		return nil, huma.Error500InternalServerError("failed to get chat messages", err)
 	}
 
@@ -347,26 +590,97 @@ func (h *MeetingHandler) GetChatMessages(ctx context.Context, input *GetChatMess
 			ID:        msg.ID,
 			MeetingID: msg.MeetingID,
 			UserID:    msg.UserID,
+			GuestID:   msg.GuestID,
 			Message:   msg.Message,
 			SentAt:    msg.SentAt,
-			User: &UserDisplayName{
-				DisplayName: msg.User.DisplayName,
-			},
+			User:      &UserDisplayName{DisplayName: msg.DisplayName()},
 		}
 	}
 
 	resp := &GetChatMessagesResponse{}
+	resp.Body.ChatMessages = response
 	return resp, nil
 }
 
+type ExportChatRequest struct {
+	AuthParam
+
+	ID       string `path:"id" doc:"meeting id"`
+	Format   string `query:"format" default:"txt" enum:"txt,json,csv,html" doc:"Transcript format"`
+	Timezone string `query:"timezone" default:"UTC" doc:"IANA timezone used for message timestamps" example:"Europe/London"`
+}
+
+func (h *MeetingHandler) ExportChat(ctx context.Context, input *ExportChatRequest) (*huma.StreamResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := time.LoadLocation(input.Timezone)
+	if err != nil {
+		return nil, huma.Error400BadRequest("invalid timezone", err)
+	}
+
+	meetingRes, chats, err := h.meetingService.GetChatTranscript(ctx, input.ID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, meeting.ErrMeetingNotFound):
+			return nil, huma.Error404NotFound("meeting not found", err)
+		case errors.Is(err, meeting.ErrNotAuthorized):
+			return nil, huma.Error403Forbidden("only participants can export the chat", err)
+		default:
+			return nil, huma.Error500InternalServerError("failed to get chat messages", err)
+		}
+	}
+
+	format := meeting.ExportFormat(input.Format)
+	filename := fmt.Sprintf("chat-%s.%s", meetingRes.MeetingCode, format)
+
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			hctx.SetHeader("Content-Type", format.ContentType())
+			hctx.SetHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+			if err := meeting.WriteChatTranscript(hctx.BodyWriter(), format, loc, meetingRes, chats); err != nil {
+				log.Printf("Failed to write chat transcript: %v", err)
+			}
+		},
+	}, nil
+}
+
 func meetingToResponse(meeting *models.Meeting) MeetingResponse {
 	response := MeetingResponse{
-		ID:          meeting.ID,
-		Title:       meeting.Title,
-		HostID:      meeting.HostID,
-		MeetingCode: meeting.MeetingCode,
-		IsPrivate:   meeting.IsPrivate,
-		CreatedAt:   meeting.CreatedAt,
+		ID:              meeting.ID,
+		Title:           meeting.Title,
+		HostID:          meeting.HostID,
+		MeetingCode:     meeting.MeetingCode,
+		IsPrivate:       meeting.IsPrivate,
+		InviteOnly:      meeting.InviteOnly,
+		WaitingRoom:     meeting.WaitingRoom,
+		Locked:          meeting.Locked,
+		AllowGuests:     meeting.AllowGuests,
+		Personal:        meeting.Personal,
+		MaxParticipants: meeting.MaxParticipants,
+		Status:          meeting.Status(time.Now()),
+		SeriesID:        meeting.SeriesID,
+		BreakoutsOpen:   meeting.BreakoutsOpen(),
+		CreatedAt:       meeting.CreatedAt,
+	}
+
+	if meeting.IsScheduled() {
+		scheduledAt := meeting.ScheduledAt
+		response.ScheduledAt = &scheduledAt
+		response.DurationMinutes = meeting.DurationMinutes
+		response.Timezone = meeting.Timezone
+	}
+	if !meeting.EndedAt.IsZero() {
+		endedAt := meeting.EndedAt
+		response.EndedAt = &endedAt
+	}
+
+	if meeting.BreakoutsOpen() && !meeting.BreakoutsCloseAt.IsZero() {
+		closeAt := meeting.BreakoutsCloseAt
+		response.BreakoutsCloseAt = &closeAt
 	}
 
 	if meeting.Host != nil {
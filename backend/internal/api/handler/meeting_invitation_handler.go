@@ -0,0 +1,306 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/meetia/backend/internal/api/middleware"
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/meeting"
+	humagroup "github.com/meetia/backend/lib/humaGroup"
+)
+
+func (h *MeetingHandler) registerInvitationRoutes(api huma.API, meetingGroup *humagroup.HumaGroup) {
+	invitationGroup := humagroup.NewHumaGroup(api, "/api/invitations", []string{"Invitations"}, middleware.JWTMiddleware(h.tokenVerifier))
+
+	humagroup.Post(meetingGroup, "/{id}/invitations", h.CreateMeetingInvitations, "CreateMeetingInvitations", &humagroup.HumaGroupOptions{
+		Summary:     "Invite people to a meeting",
+		Description: "Invite registered users or email addresses and email them the join link (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Get(meetingGroup, "/{id}/invitations", h.ListMeetingInvitations, "ListMeetingInvitations", &humagroup.HumaGroupOptions{
+		Summary:     "List meeting invitations",
+		Description: "List who was invited and whether they answered (host only)",
+		Scopes:      []string{auth.ScopeMeetingsRead},
+	})
+	humagroup.Delete(meetingGroup, "/{id}/invitations/{invitationId}", h.RevokeMeetingInvitation, "RevokeMeetingInvitation", &humagroup.HumaGroupOptions{
+		Summary:     "Revoke an invitation",
+		Description: "Withdraw an invitation, the invitee can no longer join an invite only meeting (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Put(meetingGroup, "/{id}/invite-only", h.SetMeetingInviteOnly, "SetMeetingInviteOnly", &humagroup.HumaGroupOptions{
+		Summary:     "Make a meeting invite only",
+		Description: "Restrict joining to the host and invited users, or lift the restriction (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+
+	humagroup.Get(invitationGroup, "", h.ListMyInvitations, "ListMyInvitations", &humagroup.HumaGroupOptions{
+		Summary:     "List my invitations",
+		Description: "List the meeting invitations sent to the current user's account or email address",
+		Scopes:      []string{auth.ScopeMeetingsRead},
+	})
+	humagroup.Post(invitationGroup, "/{id}/accept", h.AcceptInvitation, "AcceptInvitation", &humagroup.HumaGroupOptions{
+		Summary:     "Accept an invitation",
+		Description: "Accept a meeting invitation, joining an invite only meeting accepts it as well",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Post(invitationGroup, "/{id}/decline", h.DeclineInvitation, "DeclineInvitation", &humagroup.HumaGroupOptions{
+		Summary:     "Decline an invitation",
+		Description: "Decline a meeting invitation, it can still be accepted later",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+}
+
+type InvitationResponse struct {
+	ID          string                  `json:"id" doc:"Invitation unique identifier"`
+	MeetingID   string                  `json:"meetingId" doc:"ID of the meeting"`
+	UserID      string                  `json:"userId,omitempty" doc:"Invited user, missing for addresses without an account"`
+	Email       string                  `json:"email,omitempty" doc:"Invited address, missing when a registered user was invited"`
+	Status      models.InvitationStatus `json:"status" enum:"pending,accepted,declined" doc:"Whether the invitee answered"`
+	CreatedAt   time.Time               `json:"createdAt" doc:"When the invitation was sent"`
+	RespondedAt *time.Time              `json:"respondedAt,omitempty" doc:"When the invitee answered"`
+	User        *UserDisplayName        `json:"user,omitempty" doc:"Invited user details"`
+	Inviter     *UserDisplayName        `json:"inviter,omitempty" doc:"Who sent the invitation"`
+	Meeting     *MeetingResponse        `json:"meeting,omitempty" doc:"The meeting, in the invitee's list"`
+}
+
+type CreateMeetingInvitationsRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		UserIDs []string `json:"userIds,omitempty" maxItems:"50" doc:"Registered users to invite"`
+		Emails  []string `json:"emails,omitempty" maxItems:"50" doc:"Email addresses to invite, they don't need an account" example:"[\"ada@example.com\"]"`
+	}
+}
+
+type MeetingInvitationsResponse struct {
+	Body struct {
+		Invitations []InvitationResponse `json:"invitations" doc:"every invitation of the meeting"`
+	}
+}
+
+func (h *MeetingHandler) CreateMeetingInvitations(ctx context.Context, input *CreateMeetingInvitationsRequest) (*MeetingInvitationsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	invitees := meeting.Invitees{
+		UserIDs: input.Body.UserIDs,
+		Emails:  input.Body.Emails,
+	}
+	invitations, err := h.meetingService.InviteToMeeting(ctx, input.ID, userID, invitees)
+	if err != nil {
+		return nil, invitationError(err)
+	}
+
+	return newMeetingInvitationsResponse(invitations), nil
+}
+
+type ListMeetingInvitationsRequest struct {
+	AuthParam
+
+	ID string `path:"id" doc:"meeting id"`
+}
+
+func (h *MeetingHandler) ListMeetingInvitations(ctx context.Context, input *ListMeetingInvitationsRequest) (*MeetingInvitationsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := h.meetingService.ListMeetingInvitations(ctx, input.ID, userID)
+	if err != nil {
+		return nil, invitationError(err)
+	}
+
+	return newMeetingInvitationsResponse(invitations), nil
+}
+
+type RevokeMeetingInvitationRequest struct {
+	AuthParam
+
+	ID           string `path:"id" doc:"meeting id"`
+	InvitationID string `path:"invitationId" doc:"invitation id"`
+}
+
+func (h *MeetingHandler) RevokeMeetingInvitation(ctx context.Context, input *RevokeMeetingInvitationRequest) (*struct{}, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.meetingService.RevokeInvitation(ctx, input.ID, userID, input.InvitationID); err != nil {
+		return nil, invitationError(err)
+	}
+	return &struct{}{}, nil
+}
+
+type SetMeetingInviteOnlyRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		InviteOnly bool `json:"inviteOnly" doc:"Whether only the host and invited users can join" example:"true"`
+	}
+}
+
+type SetMeetingInviteOnlyResponse struct {
+	Body struct {
+		Meeting MeetingResponse `json:"meeting"`
+	}
+}
+
+func (h *MeetingHandler) SetMeetingInviteOnly(ctx context.Context, input *SetMeetingInviteOnlyRequest) (*SetMeetingInviteOnlyResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meetingRes, err := h.meetingService.SetInviteOnly(ctx, input.ID, userID, input.Body.InviteOnly)
+	if err != nil {
+		return nil, hostOnlyError(err)
+	}
+
+	resp := &SetMeetingInviteOnlyResponse{}
+	resp.Body.Meeting = meetingToResponse(meetingRes)
+	return resp, nil
+}
+
+type ListMyInvitationsRequest struct {
+	AuthParam
+
+	Status string `query:"status" default:"pending" enum:"pending,accepted,declined,all" doc:"Only invitations with this answer"`
+}
+
+type ListMyInvitationsResponse struct {
+	Body struct {
+		Invitations []InvitationResponse `json:"invitations" doc:"invitations sent to the user, newest first"`
+	}
+}
+
+func (h *MeetingHandler) ListMyInvitations(ctx context.Context, input *ListMyInvitationsRequest) (*ListMyInvitationsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := models.InvitationStatus(input.Status)
+	if input.Status == "all" {
+		status = ""
+	}
+	invitations, err := h.meetingService.ListUserInvitations(ctx, userID, status)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list invitations", err)
+	}
+
+	response := make([]InvitationResponse, len(invitations))
+	for i, invitation := range invitations {
+		response[i] = invitationToResponse(invitation)
+	}
+
+	resp := &ListMyInvitationsResponse{}
+	resp.Body.Invitations = response
+	return resp, nil
+}
+
+type RespondToInvitationRequest struct {
+	AuthParam
+
+	ID string `path:"id" doc:"invitation id"`
+}
+
+type RespondToInvitationResponse struct {
+	Body struct {
+		Invitation InvitationResponse `json:"invitation"`
+	}
+}
+
+func (h *MeetingHandler) AcceptInvitation(ctx context.Context, input *RespondToInvitationRequest) (*RespondToInvitationResponse, error) {
+	return h.respondToInvitation(ctx, input.ID, true)
+}
+
+func (h *MeetingHandler) DeclineInvitation(ctx context.Context, input *RespondToInvitationRequest) (*RespondToInvitationResponse, error) {
+	return h.respondToInvitation(ctx, input.ID, false)
+}
+
+func (h *MeetingHandler) respondToInvitation(ctx context.Context, invitationID string, accept bool) (*RespondToInvitationResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := h.meetingService.RespondToInvitation(ctx, invitationID, userID, accept)
+	if err != nil {
+		return nil, invitationError(err)
+	}
+
+	resp := &RespondToInvitationResponse{}
+	resp.Body.Invitation = invitationToResponse(invitation)
+	return resp, nil
+}
+
+// invitationError maps the errors of sending and answering invitations
+func invitationError(err error) error {
+	switch {
+	case errors.Is(err, meeting.ErrMeetingNotFound):
+		return huma.Error404NotFound("meeting not found", err)
+	case errors.Is(err, meeting.ErrInvitationNotFound):
+		return huma.Error404NotFound("invitation not found", err)
+	case errors.Is(err, meeting.ErrNotAuthorized):
+		return huma.Error403Forbidden("only the host can manage invitations", err)
+	case errors.Is(err, meeting.ErrMeetingEnded):
+		return huma.Error410Gone("meeting has ended", err)
+	case errors.Is(err, meeting.ErrInvalidInvitee), errors.Is(err, meeting.ErrNoInvitees):
+		return huma.Error400BadRequest(err.Error(), err)
+	default:
+		return huma.Error500InternalServerError("an error occured", err)
+	}
+}
+
+func newMeetingInvitationsResponse(invitations []*models.MeetingInvitation) *MeetingInvitationsResponse {
+	response := make([]InvitationResponse, len(invitations))
+	for i, invitation := range invitations {
+		response[i] = invitationToResponse(invitation)
+	}
+
+	resp := &MeetingInvitationsResponse{}
+	resp.Body.Invitations = response
+	return resp
+}
+
+func invitationToResponse(invitation *models.MeetingInvitation) InvitationResponse {
+	response := InvitationResponse{
+		ID:        invitation.ID,
+		MeetingID: invitation.MeetingID,
+		UserID:    invitation.UserID,
+		Status:    invitation.Status,
+		CreatedAt: invitation.CreatedAt,
+	}
+
+	// the address of a registered user is theirs to share
+	if invitation.UserID == "" {
+		response.Email = invitation.Email
+	}
+	if !invitation.RespondedAt.IsZero() {
+		respondedAt := invitation.RespondedAt
+		response.RespondedAt = &respondedAt
+	}
+	if invitation.User != nil {
+		response.User = newUserDisplayName(invitation.User)
+	}
+	if invitation.Inviter != nil {
+		response.Inviter = newUserDisplayName(invitation.Inviter)
+	}
+	if invitation.Meeting != nil {
+		meetingResp := meetingToResponse(invitation.Meeting)
+		response.Meeting = &meetingResp
+	}
+
+	return response
+}
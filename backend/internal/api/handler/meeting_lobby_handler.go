@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/meeting"
+	humagroup "github.com/meetia/backend/lib/humaGroup"
+)
+
+func (h *MeetingHandler) registerLobbyRoutes(meetingGroup *humagroup.HumaGroup) {
+	humagroup.Put(meetingGroup, "/{id}/waiting-room", h.SetMeetingWaitingRoom, "SetMeetingWaitingRoom", &humagroup.HumaGroupOptions{
+		Summary:     "Turn the waiting room on or off",
+		Description: "Make joiners wait in a lobby until admitted, turning it off admits everyone waiting (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Get(meetingGroup, "/{id}/lobby", h.GetMeetingLobby, "GetMeetingLobby", &humagroup.HumaGroupOptions{
+		Summary:     "List the waiting room",
+		Description: "List the participants waiting to be admitted, longest waiting first (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsRead},
+	})
+	humagroup.Post(meetingGroup, "/{id}/lobby/admit", h.AdmitParticipants, "AdmitParticipants", &humagroup.HumaGroupOptions{
+		Summary:     "Admit waiting participants",
+		Description: "Let some or all waiting participants into the meeting (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Post(meetingGroup, "/{id}/lobby/deny", h.DenyParticipants, "DenyParticipants", &humagroup.HumaGroupOptions{
+		Summary:     "Deny waiting participants",
+		Description: "Turn some or all waiting participants away, they can't join the meeting again (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+}
+
+type SetMeetingWaitingRoomRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		Enabled bool `json:"enabled" doc:"Whether joiners wait until a host admits them" example:"true"`
+	}
+}
+
+type SetMeetingWaitingRoomResponse struct {
+	Body struct {
+		Meeting MeetingResponse `json:"meeting"`
+	}
+}
+
+func (h *MeetingHandler) SetMeetingWaitingRoom(ctx context.Context, input *SetMeetingWaitingRoomRequest) (*SetMeetingWaitingRoomResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meetingRes, err := h.meetingService.SetWaitingRoom(ctx, input.ID, userID, input.Body.Enabled)
+	if err != nil {
+		return nil, hostOnlyError(err)
+	}
+
+	resp := &SetMeetingWaitingRoomResponse{}
+	resp.Body.Meeting = meetingToResponse(meetingRes)
+	return resp, nil
+}
+
+type LobbyEntryResponse struct {
+	UserID       string           `json:"userId" doc:"ID of the waiting user, or guest ID of a guest"`
+	Guest        bool             `json:"guest,omitempty" doc:"Whether the waiting participant is a guest"`
+	WaitingSince time.Time        `json:"waitingSince" doc:"When the user started waiting"`
+	User         *UserDisplayName `json:"user" doc:"User details"`
+}
+
+type GetMeetingLobbyRequest struct {
+	AuthParam
+
+	ID string `path:"id" doc:"meeting id"`
+}
+
+type GetMeetingLobbyResponse struct {
+	Body struct {
+		Waiting []LobbyEntryResponse `json:"waiting" doc:"participants waiting to be admitted"`
+	}
+}
+
+func (h *MeetingHandler) GetMeetingLobby(ctx context.Context, input *GetMeetingLobbyRequest) (*GetMeetingLobbyResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	participants, err := h.meetingService.ListLobby(ctx, input.ID, userID)
+	if err != nil {
+		return nil, lobbyError(err)
+	}
+
+	response := make([]LobbyEntryResponse, len(participants))
+	for i, p := range participants {
+		response[i] = LobbyEntryResponse{
+			UserID:       p.MemberID(),
+			Guest:        p.GuestID != "",
+			WaitingSince: p.JoinedAt,
+			User:         &UserDisplayName{DisplayName: p.DisplayName()},
+		}
+	}
+
+	resp := &GetMeetingLobbyResponse{}
+	resp.Body.Waiting = response
+	return resp, nil
+}
+
+type LobbyDecisionRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		UserIDs []string `json:"userIds,omitempty" maxItems:"100" doc:"Waiting users to decide on"`
+		All     bool     `json:"all,omitempty" doc:"Decide on everyone waiting instead of userIds" example:"false"`
+	}
+}
+
+type LobbyDecisionResponse struct {
+	Body struct {
+		UserIDs []string `json:"userIds" doc:"users that left the lobby, users that weren't waiting are skipped"`
+	}
+}
+
+func (h *MeetingHandler) AdmitParticipants(ctx context.Context, input *LobbyDecisionRequest) (*LobbyDecisionResponse, error) {
+	return h.decideAdmission(ctx, input, h.meetingService.AdmitParticipants)
+}
+
+func (h *MeetingHandler) DenyParticipants(ctx context.Context, input *LobbyDecisionRequest) (*LobbyDecisionResponse, error) {
+	return h.decideAdmission(ctx, input, h.meetingService.DenyParticipants)
+}
+
+func (h *MeetingHandler) decideAdmission(
+	ctx context.Context,
+	input *LobbyDecisionRequest,
+	decide func(ctx context.Context, meetingID string, moderatorID string, userIDs []string) ([]string, error),
+) (*LobbyDecisionResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := input.Body.UserIDs
+	if input.Body.All {
+		userIDs = nil
+	} else if len(userIDs) == 0 {
+		return nil, huma.Error400BadRequest("userIds or all is required")
+	}
+
+	changed, err := decide(ctx, input.ID, userID, userIDs)
+	if err != nil {
+		return nil, lobbyError(err)
+	}
+
+	resp := &LobbyDecisionResponse{}
+	resp.Body.UserIDs = changed
+	if resp.Body.UserIDs == nil {
+		resp.Body.UserIDs = []string{}
+	}
+	return resp, nil
+}
+
+// lobbyError maps the errors of managing the waiting room
+func lobbyError(err error) error {
+	switch {
+	case errors.Is(err, meeting.ErrMeetingNotFound):
+		return huma.Error404NotFound("meeting not found", err)
+	case errors.Is(err, meeting.ErrNotAuthorized):
+		return huma.Error403Forbidden("only hosts and co-hosts can manage the waiting room", err)
+	default:
+		return huma.Error500InternalServerError("an error occured", err)
+	}
+}
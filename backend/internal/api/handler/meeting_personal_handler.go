@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/meeting"
+	humagroup "github.com/meetia/backend/lib/humaGroup"
+)
+
+func (h *MeetingHandler) registerPersonalRoutes(meetingGroup *humagroup.HumaGroup) {
+	humagroup.Get(meetingGroup, "/personal", h.GetPersonalMeeting, "GetPersonalMeeting", &humagroup.HumaGroupOptions{
+		Summary:     "Get the personal meeting",
+		Description: "Get the permanent room of the current user, 404 until it is created",
+		Scopes:      []string{auth.ScopeMeetingsRead},
+	})
+	humagroup.Post(meetingGroup, "/personal", h.CreatePersonalMeeting, "CreatePersonalMeeting", &humagroup.HumaGroupOptions{
+		Summary:     "Create the personal meeting",
+		Description: "Create the permanent room of the current user, it keeps its code. Returns the existing room if there is one",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Put(meetingGroup, "/{id}/code", h.SetMeetingVanityCode, "SetMeetingVanityCode", &humagroup.HumaGroupOptions{
+		Summary:     "Choose a personal meeting code",
+		Description: "Give a personal meeting a code of your choice, codes are case-insensitive and the old one can no longer be used to join (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+}
+
+type GetPersonalMeetingRequest struct {
+	AuthParam
+}
+
+func (h *MeetingHandler) GetPersonalMeeting(ctx context.Context, input *GetPersonalMeetingRequest) (*MeetingSettingsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meetingRes, err := h.meetingService.GetPersonalMeeting(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, meeting.ErrMeetingNotFound):
+			return nil, huma.Error404NotFound("no personal meeting yet", err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
+	resp := &MeetingSettingsResponse{}
+	resp.Body.Meeting = meetingToResponse(meetingRes)
+	return resp, nil
+}
+
+type CreatePersonalMeetingRequest struct {
+	AuthParam
+}
+
+func (h *MeetingHandler) CreatePersonalMeeting(ctx context.Context, input *CreatePersonalMeetingRequest) (*MeetingSettingsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meetingRes, err := h.meetingService.CreatePersonalMeeting(ctx, userID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("an error occured", err)
+	}
+
+	resp := &MeetingSettingsResponse{}
+	resp.Body.Meeting = meetingToResponse(meetingRes)
+	return resp, nil
+}
+
+type SetMeetingVanityCodeRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		Code string `json:"code" required:"true" minLength:"4" maxLength:"20" doc:"Letters, digits and dashes, matched case-insensitively" example:"jamie-room"`
+	}
+}
+
+func (h *MeetingHandler) SetMeetingVanityCode(ctx context.Context, input *SetMeetingVanityCodeRequest) (*MeetingSettingsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meetingRes, err := h.meetingService.SetVanityCode(ctx, input.ID, userID, input.Body.Code)
+	if err != nil {
+		return nil, vanityCodeError(err)
+	}
+
+	resp := &MeetingSettingsResponse{}
+	resp.Body.Meeting = meetingToResponse(meetingRes)
+	return resp, nil
+}
+
+// vanityCodeError maps the errors of choosing a personal meeting code
+func vanityCodeError(err error) error {
+	switch {
+	case errors.Is(err, meeting.ErrInvalidVanityCode), errors.Is(err, meeting.ErrNotPersonal):
+		return huma.Error400BadRequest(err.Error(), err)
+	case errors.Is(err, meeting.ErrCodeTaken):
+		return huma.Error409Conflict(err.Error(), err)
+	default:
+		return hostOnlyError(err)
+	}
+}
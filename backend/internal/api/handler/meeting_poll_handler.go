@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/meeting"
+	humagroup "github.com/meetia/backend/lib/humaGroup"
+)
+
+func (h *MeetingHandler) registerPollRoutes(meetingGroup *humagroup.HumaGroup) {
+	humagroup.Post(meetingGroup, "/{id}/polls", h.CreatePoll, "CreatePoll", &humagroup.HumaGroupOptions{
+		Summary:     "Create a poll",
+		Description: "Draft a single or multiple choice poll, participants see it once it opens (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Get(meetingGroup, "/{id}/polls", h.ListPolls, "ListPolls", &humagroup.HumaGroupOptions{
+		Summary:     "List polls",
+		Description: "List the polls of a meeting with their results, also after the meeting ended (participants only)",
+		Scopes:      []string{auth.ScopeMeetingsRead},
+	})
+	humagroup.Post(meetingGroup, "/{id}/polls/{pollId}/open", h.OpenPoll, "OpenPoll", &humagroup.HumaGroupOptions{
+		Summary:     "Open a poll",
+		Description: "Start the voting on a drafted poll (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Post(meetingGroup, "/{id}/polls/{pollId}/close", h.ClosePoll, "ClosePoll", &humagroup.HumaGroupOptions{
+		Summary:     "Close a poll",
+		Description: "End the voting on an open poll, its results stay available (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Put(meetingGroup, "/{id}/polls/{pollId}/vote", h.VotePoll, "VotePoll", &humagroup.HumaGroupOptions{
+		Summary:     "Vote on a poll",
+		Description: "Pick options on an open poll, voting again replaces the earlier vote (participants only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+}
+
+type PollOptionResponse struct {
+	ID     string            `json:"id" doc:"Option unique identifier"`
+	Label  string            `json:"label" doc:"Option text"`
+	Votes  int               `json:"votes" doc:"Number of votes for the option"`
+	Voters []PollVoterResult `json:"voters,omitempty" doc:"Who voted for the option, missing on anonymous polls"`
+}
+
+type PollVoterResult struct {
+	UserID      string `json:"userId" doc:"ID of the voter"`
+	DisplayName string `json:"displayName" doc:"Voter display name"`
+}
+
+type PollResponse struct {
+	ID             string               `json:"id" doc:"Poll unique identifier"`
+	Question       string               `json:"question" doc:"The question asked"`
+	MultipleChoice bool                 `json:"multipleChoice" doc:"Whether voters may pick several options"`
+	Anonymous      bool                 `json:"anonymous" doc:"Whether the results hide who voted for what"`
+	Status         models.PollStatus    `json:"status" enum:"draft,open,closed" doc:"Whether the poll is drafted, open for voting or closed"`
+	Voters         int                  `json:"voters" doc:"Number of participants who voted"`
+	Options        []PollOptionResponse `json:"options" doc:"Options in order with their votes"`
+	MyVotes        []string             `json:"myVotes" doc:"Options the current user picked"`
+	CreatedAt      time.Time            `json:"createdAt" doc:"When the poll was drafted"`
+	OpenedAt       *time.Time           `json:"openedAt,omitempty" doc:"When the voting started"`
+	ClosedAt       *time.Time           `json:"closedAt,omitempty" doc:"When the voting ended"`
+}
+
+type PollResponseBody struct {
+	Body struct {
+		Poll PollResponse `json:"poll"`
+	}
+}
+
+type CreatePollRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		Question       string   `json:"question" required:"true" minLength:"1" maxLength:"500" doc:"The question to ask" example:"Which topic next?"`
+		Options        []string `json:"options" required:"true" minItems:"2" maxItems:"10" doc:"Answers to pick from, in order" example:"[\"Scaling\",\"Security\"]"`
+		MultipleChoice bool     `json:"multipleChoice,omitempty" doc:"Let voters pick several options" example:"false"`
+		Anonymous      bool     `json:"anonymous,omitempty" doc:"Hide who voted for what in the results" example:"false"`
+	}
+}
+
+func (h *MeetingHandler) CreatePoll(ctx context.Context, input *CreatePollRequest) (*PollResponseBody, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, option := range input.Body.Options {
+		if option == "" || len(option) > 200 {
+			return nil, huma.Error400BadRequest("options must be 1 to 200 characters")
+		}
+	}
+
+	poll, err := h.meetingService.CreatePoll(ctx, input.ID, userID, input.Body.Question, input.Body.Options, input.Body.MultipleChoice, input.Body.Anonymous)
+	if err != nil {
+		return nil, pollError(err)
+	}
+
+	resp := &PollResponseBody{}
+	resp.Body.Poll = pollToResponse(poll, userID)
+	return resp, nil
+}
+
+type ListPollsRequest struct {
+	AuthParam
+
+	ID string `path:"id" doc:"meeting id"`
+}
+
+type ListPollsResponse struct {
+	Body struct {
+		Polls []PollResponse `json:"polls" doc:"polls of the meeting, oldest first"`
+	}
+}
+
+func (h *MeetingHandler) ListPolls(ctx context.Context, input *ListPollsRequest) (*ListPollsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	polls, err := h.meetingService.ListPolls(ctx, input.ID, userID)
+	if errors.Is(err, meeting.ErrNotAuthorized) {
+		return nil, huma.Error403Forbidden("only participants can see the polls", err)
+	}
+	if err != nil {
+		return nil, pollError(err)
+	}
+
+	response := make([]PollResponse, len(polls))
+	for i, poll := range polls {
+		response[i] = pollToResponse(poll, userID)
+	}
+
+	resp := &ListPollsResponse{}
+	resp.Body.Polls = response
+	return resp, nil
+}
+
+type PollActionRequest struct {
+	AuthParam
+
+	ID     string `path:"id" doc:"meeting id"`
+	PollID string `path:"pollId" doc:"poll id"`
+}
+
+func (h *MeetingHandler) OpenPoll(ctx context.Context, input *PollActionRequest) (*PollResponseBody, error) {
+	return h.changePoll(ctx, input, h.meetingService.OpenPoll)
+}
+
+func (h *MeetingHandler) ClosePoll(ctx context.Context, input *PollActionRequest) (*PollResponseBody, error) {
+	return h.changePoll(ctx, input, h.meetingService.ClosePoll)
+}
+
+func (h *MeetingHandler) changePoll(
+	ctx context.Context,
+	input *PollActionRequest,
+	change func(ctx context.Context, meetingID string, userID string, pollID string) (*models.MeetingPoll, error),
+) (*PollResponseBody, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	poll, err := change(ctx, input.ID, userID, input.PollID)
+	if err != nil {
+		return nil, pollError(err)
+	}
+
+	resp := &PollResponseBody{}
+	resp.Body.Poll = pollToResponse(poll, userID)
+	return resp, nil
+}
+
+type VotePollRequest struct {
+	AuthParam
+
+	ID     string `path:"id" doc:"meeting id"`
+	PollID string `path:"pollId" doc:"poll id"`
+	Body   struct {
+		OptionIDs []string `json:"optionIds" required:"true" minItems:"1" maxItems:"10" doc:"Options to vote for, exactly one on single choice polls"`
+	}
+}
+
+func (h *MeetingHandler) VotePoll(ctx context.Context, input *VotePollRequest) (*PollResponseBody, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	poll, err := h.meetingService.Vote(ctx, input.ID, userID, input.PollID, input.Body.OptionIDs)
+	if errors.Is(err, meeting.ErrNotAuthorized) {
+		return nil, huma.Error403Forbidden("only participants can vote", err)
+	}
+	if err != nil {
+		return nil, pollError(err)
+	}
+
+	resp := &PollResponseBody{}
+	resp.Body.Poll = pollToResponse(poll, userID)
+	return resp, nil
+}
+
+// pollToResponse tallies a poll for the user, who also sees their own votes on anonymous polls
+func pollToResponse(poll *models.MeetingPoll, userID string) PollResponse {
+	results := meeting.Tally(poll)
+	response := PollResponse{
+		ID:             results.ID,
+		Question:       results.Question,
+		MultipleChoice: results.MultipleChoice,
+		Anonymous:      results.Anonymous,
+		Status:         results.Status,
+		Voters:         results.Voters,
+		Options:        make([]PollOptionResponse, len(results.Options)),
+		MyVotes:        []string{},
+		CreatedAt:      poll.CreatedAt,
+	}
+
+	for i, option := range results.Options {
+		response.Options[i] = PollOptionResponse{
+			ID:    option.ID,
+			Label: option.Label,
+			Votes: option.Votes,
+		}
+		for _, voter := range option.Voters {
+			response.Options[i].Voters = append(response.Options[i].Voters, PollVoterResult(voter))
+		}
+	}
+	for _, vote := range poll.Votes {
+		if vote.UserID == userID {
+			response.MyVotes = append(response.MyVotes, vote.OptionID)
+		}
+	}
+
+	if !poll.OpenedAt.IsZero() {
+		openedAt := poll.OpenedAt
+		response.OpenedAt = &openedAt
+	}
+	if !poll.ClosedAt.IsZero() {
+		closedAt := poll.ClosedAt
+		response.ClosedAt = &closedAt
+	}
+	return response
+}
+
+// pollError maps the errors of running polls
+func pollError(err error) error {
+	switch {
+	case errors.Is(err, meeting.ErrMeetingNotFound):
+		return huma.Error404NotFound("meeting not found", err)
+	case errors.Is(err, meeting.ErrPollNotFound):
+		return huma.Error404NotFound("poll not found", err)
+	case errors.Is(err, meeting.ErrNotAuthorized):
+		return huma.Error403Forbidden("only hosts and co-hosts can manage polls", err)
+	case errors.Is(err, meeting.ErrMeetingEnded):
+		return huma.Error410Gone("meeting has ended", err)
+	case errors.Is(err, meeting.ErrPollNotOpen), errors.Is(err, meeting.ErrPollStatus):
+		return huma.Error409Conflict(err.Error(), err)
+	case errors.Is(err, meeting.ErrInvalidPoll), errors.Is(err, meeting.ErrInvalidVote):
+		return huma.Error400BadRequest(err.Error(), err)
+	default:
+		return huma.Error500InternalServerError("an error occured", err)
+	}
+}
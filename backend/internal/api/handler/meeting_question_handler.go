@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/meeting"
+	humagroup "github.com/meetia/backend/lib/humaGroup"
+)
+
+func (h *MeetingHandler) registerQuestionRoutes(meetingGroup *humagroup.HumaGroup) {
+	humagroup.Post(meetingGroup, "/{id}/questions", h.AskQuestion, "AskQuestion", &humagroup.HumaGroupOptions{
+		Summary:     "Ask a question",
+		Description: "Put a question on the Q&A board of a meeting, optionally without showing who asked (participants only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Get(meetingGroup, "/{id}/questions", h.ListQuestions, "ListQuestions", &humagroup.HumaGroupOptions{
+		Summary:     "List questions",
+		Description: "List the Q&A board, most upvoted first. Dismissed questions are only listed for hosts and co-hosts (participants only)",
+		Scopes:      []string{auth.ScopeMeetingsRead},
+	})
+	humagroup.Put(meetingGroup, "/{id}/questions/{questionId}/upvote", h.UpvoteQuestion, "UpvoteQuestion", &humagroup.HumaGroupOptions{
+		Summary:     "Upvote a question",
+		Description: "Upvote an open question, once per participant",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Delete(meetingGroup, "/{id}/questions/{questionId}/upvote", h.RemoveQuestionUpvote, "RemoveQuestionUpvote", &humagroup.HumaGroupOptions{
+		Summary:     "Take back an upvote",
+		Description: "Remove the current user's upvote from an open question",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Put(meetingGroup, "/{id}/questions/{questionId}/status", h.SetQuestionStatus, "SetQuestionStatus", &humagroup.HumaGroupOptions{
+		Summary:     "Moderate a question",
+		Description: "Mark a question answered or dismissed, or open it again (hosts and co-hosts only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+}
+
+type QuestionResponse struct {
+	ID         string                `json:"id" doc:"Question unique identifier"`
+	Text       string                `json:"text" doc:"The question"`
+	Anonymous  bool                  `json:"anonymous" doc:"Whether the author is hidden"`
+	Status     models.QuestionStatus `json:"status" enum:"open,answered,dismissed" doc:"Whether the question is open, answered or dismissed"`
+	Upvotes    int                   `json:"upvotes" doc:"Number of upvotes"`
+	Upvoted    bool                  `json:"upvoted" doc:"Whether the current user upvoted the question"`
+	Mine       bool                  `json:"mine" doc:"Whether the current user asked the question"`
+	CreatedAt  time.Time             `json:"createdAt" doc:"When the question was asked"`
+	ResolvedAt *time.Time            `json:"resolvedAt,omitempty" doc:"When the question was answered or dismissed"`
+	User       *UserDisplayName      `json:"user,omitempty" doc:"Author details, missing on anonymous questions"`
+}
+
+type QuestionResponseBody struct {
+	Body struct {
+		Question QuestionResponse `json:"question"`
+	}
+}
+
+type AskQuestionRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		Text      string `json:"text" required:"true" minLength:"1" maxLength:"1000" doc:"The question" example:"Will the slides be shared?"`
+		Anonymous bool   `json:"anonymous,omitempty" doc:"Hide who asked from everyone, hosts included" example:"false"`
+	}
+}
+
+func (h *MeetingHandler) AskQuestion(ctx context.Context, input *AskQuestionRequest) (*QuestionResponseBody, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	question, err := h.meetingService.AskQuestion(ctx, input.ID, userID, input.Body.Text, input.Body.Anonymous)
+	if err != nil {
+		return nil, questionError(err)
+	}
+
+	resp := &QuestionResponseBody{}
+	resp.Body.Question = questionToResponse(question, userID, false)
+	return resp, nil
+}
+
+type ListQuestionsRequest struct {
+	AuthParam
+
+	ID string `path:"id" doc:"meeting id"`
+}
+
+type ListQuestionsResponse struct {
+	Body struct {
+		Questions []QuestionResponse `json:"questions" doc:"questions of the meeting, most upvoted first"`
+	}
+}
+
+func (h *MeetingHandler) ListQuestions(ctx context.Context, input *ListQuestionsRequest) (*ListQuestionsResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	questions, upvoted, err := h.meetingService.ListQuestions(ctx, input.ID, userID)
+	if err != nil {
+		return nil, questionError(err)
+	}
+
+	response := make([]QuestionResponse, len(questions))
+	for i, question := range questions {
+		response[i] = questionToResponse(question, userID, slices.Contains(upvoted, question.ID))
+	}
+
+	resp := &ListQuestionsResponse{}
+	resp.Body.Questions = response
+	return resp, nil
+}
+
+type QuestionUpvoteRequest struct {
+	AuthParam
+
+	ID         string `path:"id" doc:"meeting id"`
+	QuestionID string `path:"questionId" doc:"question id"`
+}
+
+func (h *MeetingHandler) UpvoteQuestion(ctx context.Context, input *QuestionUpvoteRequest) (*QuestionResponseBody, error) {
+	return h.upvoteQuestion(ctx, input, true)
+}
+
+func (h *MeetingHandler) RemoveQuestionUpvote(ctx context.Context, input *QuestionUpvoteRequest) (*QuestionResponseBody, error) {
+	return h.upvoteQuestion(ctx, input, false)
+}
+
+func (h *MeetingHandler) upvoteQuestion(ctx context.Context, input *QuestionUpvoteRequest, upvote bool) (*QuestionResponseBody, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	question, err := h.meetingService.UpvoteQuestion(ctx, input.ID, userID, input.QuestionID, upvote)
+	if err != nil {
+		return nil, questionError(err)
+	}
+
+	resp := &QuestionResponseBody{}
+	resp.Body.Question = questionToResponse(question, userID, upvote)
+	return resp, nil
+}
+
+type SetQuestionStatusRequest struct {
+	AuthParam
+
+	ID         string `path:"id" doc:"meeting id"`
+	QuestionID string `path:"questionId" doc:"question id"`
+	Body       struct {
+		Status models.QuestionStatus `json:"status" required:"true" enum:"open,answered,dismissed" doc:"New status of the question" example:"answered"`
+	}
+}
+
+func (h *MeetingHandler) SetQuestionStatus(ctx context.Context, input *SetQuestionStatusRequest) (*struct{}, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.meetingService.SetQuestionStatus(ctx, input.ID, userID, input.QuestionID, input.Body.Status); err != nil {
+		return nil, questionError(err)
+	}
+	return &struct{}{}, nil
+}
+
+func questionToResponse(question *models.MeetingQuestion, userID string, upvoted bool) QuestionResponse {
+	response := QuestionResponse{
+		ID:        question.ID,
+		Text:      question.Text,
+		Anonymous: question.Anonymous,
+		Status:    question.Status,
+		Upvotes:   question.Upvotes,
+		Upvoted:   upvoted,
+		Mine:      question.UserID != "" && question.UserID == userID,
+		CreatedAt: question.CreatedAt,
+	}
+	if !question.ResolvedAt.IsZero() {
+		resolvedAt := question.ResolvedAt
+		response.ResolvedAt = &resolvedAt
+	}
+	if !question.Anonymous {
+		response.User = newUserDisplayName(question.User)
+	}
+	return response
+}
+
+// questionError maps the errors of the Q&A board
+func questionError(err error) error {
+	switch {
+	case errors.Is(err, meeting.ErrMeetingNotFound):
+		return huma.Error404NotFound("meeting not found", err)
+	case errors.Is(err, meeting.ErrQuestionNotFound):
+		return huma.Error404NotFound("question not found", err)
+	case errors.Is(err, meeting.ErrNotAuthorized):
+		return huma.Error403Forbidden("not allowed on the Q&A board of this meeting", err)
+	case errors.Is(err, meeting.ErrMeetingEnded):
+		return huma.Error410Gone("meeting has ended", err)
+	case errors.Is(err, meeting.ErrQuestionClosed):
+		return huma.Error409Conflict(err.Error(), err)
+	case errors.Is(err, meeting.ErrInvalidQuestion):
+		return huma.Error400BadRequest(err.Error(), err)
+	default:
+		return huma.Error500InternalServerError("an error occured", err)
+	}
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/meeting"
+	humagroup "github.com/meetia/backend/lib/humaGroup"
+)
+
+func (h *MeetingHandler) registerRoleRoutes(meetingGroup *humagroup.HumaGroup) {
+	humagroup.Put(meetingGroup, "/{id}/participants/{userId}/role", h.SetParticipantRole, "SetParticipantRole", &humagroup.HumaGroupOptions{
+		Summary:     "Change a participant's role",
+		Description: "Promote an admitted participant to co-host or demote them back (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Post(meetingGroup, "/{id}/host", h.TransferMeetingHost, "TransferMeetingHost", &humagroup.HumaGroupOptions{
+		Summary:     "Transfer host",
+		Description: "Make an admitted participant the host, the current host stays on as co-host (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+}
+
+type SetParticipantRoleRequest struct {
+	AuthParam
+
+	ID     string `path:"id" doc:"meeting id"`
+	UserID string `path:"userId" doc:"user id of the participant"`
+	Body   struct {
+		Role models.ParticipantRole `json:"role" required:"true" enum:"Co-Host,Participant" doc:"New role, the host role changes hands by transferring it" example:"Co-Host"`
+	}
+}
+
+func (h *MeetingHandler) SetParticipantRole(ctx context.Context, input *SetParticipantRoleRequest) (*struct{}, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.meetingService.SetParticipantRole(ctx, input.ID, userID, input.UserID, input.Body.Role); err != nil {
+		return nil, roleError(err)
+	}
+	return &struct{}{}, nil
+}
+
+type TransferMeetingHostRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"meeting id"`
+	Body struct {
+		UserID string `json:"userId" required:"true" doc:"Participant to make the host"`
+	}
+}
+
+type TransferMeetingHostResponse struct {
+	Body struct {
+		Meeting MeetingResponse `json:"meeting"`
+	}
+}
+
+func (h *MeetingHandler) TransferMeetingHost(ctx context.Context, input *TransferMeetingHostRequest) (*TransferMeetingHostResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meetingRes, err := h.meetingService.TransferHost(ctx, input.ID, userID, input.Body.UserID)
+	if err != nil {
+		return nil, roleError(err)
+	}
+
+	resp := &TransferMeetingHostResponse{}
+	resp.Body.Meeting = meetingToResponse(meetingRes)
+	return resp, nil
+}
+
+// roleError maps the errors of changing roles
+func roleError(err error) error {
+	switch {
+	case errors.Is(err, meeting.ErrMeetingNotFound):
+		return huma.Error404NotFound("meeting not found", err)
+	case errors.Is(err, meeting.ErrParticipantNotFound):
+		return huma.Error404NotFound("no admitted participant with this id", err)
+	case errors.Is(err, meeting.ErrNotAuthorized):
+		return huma.Error403Forbidden("only the host can change roles", err)
+	case errors.Is(err, meeting.ErrInvalidRole):
+		return huma.Error400BadRequest("the host's role can only change by transferring it", err)
+	default:
+		return huma.Error500InternalServerError("an error occured", err)
+	}
+}
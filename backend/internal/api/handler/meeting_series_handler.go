@@ -0,0 +1,452 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/meeting"
+	humagroup "github.com/meetia/backend/lib/humaGroup"
+)
+
+func (h *MeetingHandler) registerSeriesRoutes(meetingGroup *humagroup.HumaGroup) {
+	humagroup.Post(meetingGroup, "/series", h.CreateMeetingSeries, "CreateMeetingSeries", &humagroup.HumaGroupOptions{
+		Summary:     "Create a recurring meeting",
+		Description: "Creates a meeting series repeating by an RFC 5545 RRULE, every occurrence is joined with the series code",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Get(meetingGroup, "/series", h.ListMeetingSeries, "ListMeetingSeries", &humagroup.HumaGroupOptions{
+		Summary:     "List user's meeting series",
+		Description: "List series the user hosts or joined an occurrence of",
+		Scopes:      []string{auth.ScopeMeetingsRead},
+	})
+	humagroup.Get(meetingGroup, "/series/{id}", h.GetMeetingSeries, "GetMeetingSeries", &humagroup.HumaGroupOptions{
+		Summary:     "Get meeting series details",
+		Description: "Get a series with its next occurrence",
+		Scopes:      []string{auth.ScopeMeetingsRead},
+	})
+	humagroup.Get(meetingGroup, "/series/{id}/invite.ics", h.GetSeriesInvite, "GetSeriesInvite", &humagroup.HumaGroupOptions{
+		Summary:     "Download series calendar invite",
+		Description: "Download the series as a recurring iCalendar event with its exceptions",
+		Scopes:      []string{auth.ScopeMeetingsRead},
+	})
+	humagroup.Get(meetingGroup, "/series/{id}/occurrences", h.ListSeriesOccurrences, "ListSeriesOccurrences", &humagroup.HumaGroupOptions{
+		Summary:     "List occurrences of a series",
+		Description: "Expand the series over a time range, occurrences someone joined link to their meeting with its own participants and chat",
+		Scopes:      []string{auth.ScopeMeetingsRead},
+	})
+	humagroup.Post(meetingGroup, "/series/{id}/exceptions", h.CancelSeriesOccurrence, "CancelSeriesOccurrence", &humagroup.HumaGroupOptions{
+		Summary:     "Cancel an occurrence",
+		Description: "Add an exception so a single occurrence won't take place (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Put(meetingGroup, "/series/{id}/password", h.ChangeSeriesPassword, "ChangeSeriesPassword", &humagroup.HumaGroupOptions{
+		Summary:     "Change series password",
+		Description: "Set a new password for every upcoming occurrence and make the series private (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Post(meetingGroup, "/series/{id}/code", h.RegenerateSeriesCode, "RegenerateSeriesCode", &humagroup.HumaGroupOptions{
+		Summary:     "Regenerate series code",
+		Description: "Replace the code shared by the occurrences so the old one can no longer be used to join (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+	humagroup.Delete(meetingGroup, "/series/{id}", h.EndMeetingSeries, "EndMeetingSeries", &humagroup.HumaGroupOptions{
+		Summary:     "End a meeting series",
+		Description: "Stop the series and end its open occurrences, their history is kept (host only)",
+		Scopes:      []string{auth.ScopeMeetingsWrite},
+	})
+}
+
+type MeetingSeriesResponse struct {
+	ID              string         `json:"id" doc:"Series unique identifier"`
+	Title           string         `json:"title" doc:"Title of every occurrence"`
+	HostID          string         `json:"hostId" doc:"ID of the series host"`
+	MeetingCode     string         `json:"meetingCode" doc:"Code joining the current occurrence"`
+	IsPrivate       bool           `json:"isPrivate" doc:"Whether joining requires a password"`
+	RRule           string         `json:"rrule" doc:"RFC 5545 recurrence rule"`
+	StartsAt        time.Time      `json:"startsAt" doc:"Start of the series, occurrences begin at its time of day"`
+	DurationMinutes int            `json:"durationMinutes" doc:"Length of every occurrence in minutes"`
+	Timezone        string         `json:"timezone" doc:"IANA timezone the rule repeats in"`
+	ExDates         []time.Time    `json:"exdates" doc:"Starts of cancelled occurrences"`
+	NextOccurrence  *time.Time     `json:"nextOccurrence,omitempty" doc:"Start of the next occurrence, missing once the series is over"`
+	EndedAt         *time.Time     `json:"endedAt,omitempty" doc:"When the series was ended"`
+	CreatedAt       time.Time      `json:"createdAt" doc:"When the series was created"`
+	Host            *UserInfoSmall `json:"host,omitempty" doc:"Host details"`
+}
+
+type OccurrenceResponse struct {
+	StartAt   time.Time            `json:"startAt" doc:"Start of the occurrence"`
+	EndAt     time.Time            `json:"endAt" doc:"Planned end of the occurrence"`
+	Status    models.MeetingStatus `json:"status" enum:"upcoming,live,past" doc:"Whether the occurrence is still to come, can be joined, or is over"`
+	MeetingID string               `json:"meetingId,omitempty" doc:"Meeting holding the participants and chat, set once someone joined"`
+}
+
+type CreateMeetingSeriesRequest struct {
+	AuthParam
+
+	Body struct {
+		Title     string `json:"title" doc:"Meeting title" example:"Team Weekly Sync"`
+		IsPrivate bool   `json:"isPrivate" doc:"Whether the occurrences require a password" example:"false"`
+		Password  string `json:"password,omitempty" maxLength:"72" doc:"Password if the series is private" example:"securepass123"`
+
+		StartsAt        time.Time   `json:"startsAt" doc:"Start of the first occurrence" example:"2026-11-02T15:00:00+01:00"`
+		DurationMinutes int         `json:"durationMinutes,omitempty" minimum:"0" maximum:"1440" doc:"Length of every occurrence in minutes, defaults to the server setting" example:"45"`
+		Timezone        string      `json:"timezone,omitempty" doc:"IANA timezone the rule repeats in, occurrences keep their local time across daylight saving changes" example:"Europe/Berlin"`
+		RRule           string      `json:"rrule" minLength:"1" doc:"RFC 5545 RRULE with a DAILY, WEEKLY or MONTHLY frequency, without DTSTART" example:"FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20270101T000000Z"`
+		ExDates         []time.Time `json:"exdates,omitempty" doc:"Starts of occurrences that won't take place"`
+	}
+}
+
+type CreateMeetingSeriesResponse struct {
+	Body struct {
+		Series MeetingSeriesResponse `json:"series"`
+	}
+}
+
+func (h *MeetingHandler) CreateMeetingSeries(ctx context.Context, input *CreateMeetingSeriesRequest) (*CreateMeetingSeriesResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := meeting.Schedule{
+		StartAt:  input.Body.StartsAt,
+		Duration: time.Duration(input.Body.DurationMinutes) * time.Minute,
+		Timezone: input.Body.Timezone,
+	}
+	recurrence := meeting.Recurrence{
+		RRule:   input.Body.RRule,
+		ExDates: input.Body.ExDates,
+	}
+
+	series, err := h.meetingService.CreateSeries(ctx, input.Body.Title, userID, input.Body.IsPrivate, input.Body.Password, schedule, recurrence)
+	if err != nil {
+		switch {
+		case errors.Is(err, meeting.ErrPasswordRequired):
+			return nil, huma.Error400BadRequest("private meetings need a password", err)
+		case errors.Is(err, meeting.ErrInvalidSchedule), errors.Is(err, meeting.ErrInvalidRecurrence):
+			return nil, huma.Error400BadRequest(err.Error(), err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured while creating meeting series", err)
+		}
+	}
+
+	resp := &CreateMeetingSeriesResponse{}
+	resp.Body.Series = h.seriesToResponse(series)
+	return resp, nil
+}
+
+type ListMeetingSeriesRequest struct {
+	AuthParam
+
+	Status string `query:"status" default:"active" enum:"active,all" doc:"active leaves out ended series"`
+}
+
+type ListMeetingSeriesResponse struct {
+	Body struct {
+		Series []MeetingSeriesResponse `json:"series" doc:"series the user hosts or joined"`
+	}
+}
+
+func (h *MeetingHandler) ListMeetingSeries(ctx context.Context, input *ListMeetingSeriesRequest) (*ListMeetingSeriesResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := h.meetingService.ListUserSeries(ctx, userID, input.Status == "active")
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list meeting series", err)
+	}
+
+	response := make([]MeetingSeriesResponse, len(series))
+	for i, s := range series {
+		response[i] = h.seriesToResponse(s)
+	}
+
+	resp := &ListMeetingSeriesResponse{}
+	resp.Body.Series = response
+	return resp, nil
+}
+
+type GetMeetingSeriesRequest struct {
+	AuthParam
+
+	ID string `path:"id" doc:"series id"`
+}
+
+type GetMeetingSeriesResponse struct {
+	Body struct {
+		Series MeetingSeriesResponse `json:"series"`
+	}
+}
+
+func (h *MeetingHandler) GetMeetingSeries(ctx context.Context, input *GetMeetingSeriesRequest) (*GetMeetingSeriesResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := h.meetingService.GetSeries(ctx, input.ID, userID)
+	if err != nil {
+		return nil, seriesAccessError(err)
+	}
+
+	resp := &GetMeetingSeriesResponse{}
+	resp.Body.Series = h.seriesToResponse(series)
+	return resp, nil
+}
+
+type GetSeriesInviteRequest struct {
+	AuthParam
+
+	ID string `path:"id" doc:"series id"`
+}
+
+func (h *MeetingHandler) GetSeriesInvite(ctx context.Context, input *GetSeriesInviteRequest) (*huma.StreamResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := h.meetingService.GetSeries(ctx, input.ID, userID)
+	if err != nil {
+		return nil, seriesAccessError(err)
+	}
+
+	return h.calendarResponse("meeting-"+series.MeetingCode+".ics", nil, []*models.MeetingSeries{series}), nil
+}
+
+type ListSeriesOccurrencesRequest struct {
+	AuthParam
+
+	ID   string    `path:"id" doc:"series id"`
+	From time.Time `query:"from" doc:"Start of the range, defaults to now"`
+	To   time.Time `query:"to" doc:"End of the range, defaults to 90 days after from, at most a year after it"`
+}
+
+type ListSeriesOccurrencesResponse struct {
+	Body struct {
+		Series      MeetingSeriesResponse `json:"series"`
+		Occurrences []OccurrenceResponse  `json:"occurrences" doc:"occurrences overlapping the range, at most 500"`
+	}
+}
+
+func (h *MeetingHandler) ListSeriesOccurrences(ctx context.Context, input *ListSeriesOccurrencesRequest) (*ListSeriesOccurrencesResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := input.From, input.To
+	if from.IsZero() {
+		from = time.Now()
+	}
+	if to.IsZero() {
+		to = from.AddDate(0, 0, 90)
+	}
+
+	series, occurrences, err := h.meetingService.ListOccurrences(ctx, input.ID, userID, from, to)
+	if err != nil {
+		if errors.Is(err, meeting.ErrInvalidRange) {
+			return nil, huma.Error400BadRequest(err.Error(), err)
+		}
+		return nil, seriesAccessError(err)
+	}
+
+	now := time.Now()
+	response := make([]OccurrenceResponse, len(occurrences))
+	for i, o := range occurrences {
+		response[i] = OccurrenceResponse{
+			StartAt: o.StartAt,
+			EndAt:   o.EndAt,
+			Status:  o.Status(now),
+		}
+		if o.Meeting != nil {
+			response[i].MeetingID = o.Meeting.ID
+		}
+	}
+
+	resp := &ListSeriesOccurrencesResponse{}
+	resp.Body.Series = h.seriesToResponse(series)
+	resp.Body.Occurrences = response
+	return resp, nil
+}
+
+type CancelSeriesOccurrenceRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"series id"`
+	Body struct {
+		StartAt time.Time `json:"startAt" doc:"Start of the occurrence to cancel" example:"2026-11-09T15:00:00+01:00"`
+	}
+}
+
+type CancelSeriesOccurrenceResponse struct {
+	Body struct {
+		Series MeetingSeriesResponse `json:"series"`
+	}
+}
+
+func (h *MeetingHandler) CancelSeriesOccurrence(ctx context.Context, input *CancelSeriesOccurrenceRequest) (*CancelSeriesOccurrenceResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := h.meetingService.CancelOccurrence(ctx, input.ID, userID, input.Body.StartAt)
+	if err != nil {
+		if errors.Is(err, meeting.ErrOccurrenceNotFound) {
+			return nil, huma.Error404NotFound("the series has no occurrence at that time", err)
+		}
+		return nil, seriesHostOnlyError(err)
+	}
+
+	resp := &CancelSeriesOccurrenceResponse{}
+	resp.Body.Series = h.seriesToResponse(series)
+	return resp, nil
+}
+
+type ChangeSeriesPasswordRequest struct {
+	AuthParam
+
+	ID   string `path:"id" doc:"series id"`
+	Body struct {
+		Password string `json:"password" required:"true" minLength:"4" maxLength:"72" doc:"New password of the occurrences" example:"securepass123"`
+	}
+}
+
+type ChangeSeriesPasswordResponse struct {
+	Body struct {
+		Series MeetingSeriesResponse `json:"series"`
+	}
+}
+
+func (h *MeetingHandler) ChangeSeriesPassword(ctx context.Context, input *ChangeSeriesPasswordRequest) (*ChangeSeriesPasswordResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := h.meetingService.ChangeSeriesPassword(ctx, input.ID, userID, input.Body.Password)
+	if err != nil {
+		return nil, seriesHostOnlyError(err)
+	}
+
+	resp := &ChangeSeriesPasswordResponse{}
+	resp.Body.Series = h.seriesToResponse(series)
+	return resp, nil
+}
+
+type RegenerateSeriesCodeRequest struct {
+	AuthParam
+
+	ID string `path:"id" doc:"series id"`
+}
+
+type RegenerateSeriesCodeResponse struct {
+	Body struct {
+		Series MeetingSeriesResponse `json:"series"`
+	}
+}
+
+func (h *MeetingHandler) RegenerateSeriesCode(ctx context.Context, input *RegenerateSeriesCodeRequest) (*RegenerateSeriesCodeResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := h.meetingService.RegenerateSeriesCode(ctx, input.ID, userID)
+	if err != nil {
+		return nil, seriesHostOnlyError(err)
+	}
+
+	resp := &RegenerateSeriesCodeResponse{}
+	resp.Body.Series = h.seriesToResponse(series)
+	return resp, nil
+}
+
+type EndMeetingSeriesRequest struct {
+	AuthParam
+
+	ID string `path:"id" doc:"series id"`
+}
+
+func (h *MeetingHandler) EndMeetingSeries(ctx context.Context, input *EndMeetingSeriesRequest) (*struct{}, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.meetingService.EndSeries(ctx, input.ID, userID); err != nil {
+		return nil, seriesHostOnlyError(err)
+	}
+	return &struct{}{}, nil
+}
+
+// seriesAccessError maps the errors of reading a series, which hosts and past participants may do
+func seriesAccessError(err error) error {
+	switch {
+	case errors.Is(err, meeting.ErrSeriesNotFound):
+		return huma.Error404NotFound("meeting series not found", err)
+	case errors.Is(err, meeting.ErrNotAuthorized):
+		return huma.Error403Forbidden("only the host and participants can see the series", err)
+	default:
+		return huma.Error500InternalServerError("an error occured", err)
+	}
+}
+
+// seriesHostOnlyError maps the errors of series settings only the host may change
+func seriesHostOnlyError(err error) error {
+	switch {
+	case errors.Is(err, meeting.ErrSeriesNotFound):
+		return huma.Error404NotFound("meeting series not found", err)
+	case errors.Is(err, meeting.ErrNotAuthorized):
+		return huma.Error403Forbidden("only the host can change the series", err)
+	case errors.Is(err, meeting.ErrPasswordRequired):
+		return huma.Error400BadRequest("private meetings need a password", err)
+	default:
+		return huma.Error500InternalServerError("an error occured", err)
+	}
+}
+
+func (h *MeetingHandler) seriesToResponse(series *models.MeetingSeries) MeetingSeriesResponse {
+	response := MeetingSeriesResponse{
+		ID:              series.ID,
+		Title:           series.Title,
+		HostID:          series.HostID,
+		MeetingCode:     series.MeetingCode,
+		IsPrivate:       series.IsPrivate,
+		RRule:           series.RRule,
+		StartsAt:        series.StartsAt,
+		DurationMinutes: series.DurationMinutes,
+		Timezone:        series.Timezone,
+		ExDates:         series.ExDates,
+		CreatedAt:       series.CreatedAt,
+	}
+	if response.ExDates == nil {
+		response.ExDates = []time.Time{}
+	}
+
+	if next := h.meetingService.NextOccurrence(series, time.Now()); !next.IsZero() {
+		next = next.UTC()
+		response.NextOccurrence = &next
+	}
+	if !series.EndedAt.IsZero() {
+		endedAt := series.EndedAt
+		response.EndedAt = &endedAt
+	}
+
+	if series.Host != nil {
+		response.Host = &UserInfoSmall{
+			ID:          series.Host.ID,
+			DisplayName: series.Host.DisplayName,
+		}
+	}
+
+	return response
+}
@@ -0,0 +1,452 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/go-chi/jwtauth/v5"
+
+	"github.com/meetia/backend/internal/api/middleware"
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/user"
+	humagroup "github.com/meetia/backend/lib/humaGroup"
+)
+
+type UserHandler struct {
+	userService *user.UserService
+	authService *auth.AuthService
+}
+
+func NewUserHandler(userService *user.UserService, authService *auth.AuthService) *UserHandler {
+	return &UserHandler{
+		userService: userService,
+		authService: authService,
+	}
+}
+
+func (h *UserHandler) RegisterRoutes(api huma.API) {
+	meGroup := humagroup.NewHumaGroup(api, "/api/users/me", []string{"Users"}, middleware.JWTMiddleware(h.authService))
+	usersGroup := humagroup.NewHumaGroup(api, "/api/users", []string{"Users"})
+
+	humagroup.Get(meGroup, "", h.GetProfile, "GetProfile", &humagroup.HumaGroupOptions{
+		Summary:     "Get current user",
+		Description: "Get the profile of the signed in user",
+		Scopes:      []string{auth.ScopeProfileRead},
+	})
+	humagroup.Patch(meGroup, "", h.UpdateProfile, "UpdateProfile", &humagroup.HumaGroupOptions{
+		Summary:     "Update current user",
+		Description: "Change the display name or email address, a new email address has to be verified again",
+	})
+	humagroup.Delete(meGroup, "", h.DeleteAccount, "DeleteAccount", &humagroup.HumaGroupOptions{
+		Summary:     "Delete account",
+		Description: "Permanently delete the signed in user, their messages stay in meetings without an author",
+	})
+	humagroup.Post(meGroup, "/password", h.ChangePassword, "ChangePassword", &humagroup.HumaGroupOptions{
+		Summary:     "Change password",
+		Description: "Set a new password, signs out every other session",
+	})
+	humagroup.Put(meGroup, "/avatar", h.UploadAvatar, "UploadAvatar", &humagroup.HumaGroupOptions{
+		Summary:      "Upload avatar",
+		Description:  "Replace the profile picture with a PNG, JPEG, GIF or WebP image",
+		MaxBodyBytes: h.userService.MaxAvatarBytes() + 64*1024, // room for the multipart envelope
+	})
+	humagroup.Delete(meGroup, "/avatar", h.RemoveAvatar, "RemoveAvatar", &humagroup.HumaGroupOptions{
+		Summary:     "Remove avatar",
+		Description: "Remove the profile picture",
+	})
+	humagroup.Get(meGroup, "/tokens", h.ListPersonalTokens, "ListPersonalTokens", &humagroup.HumaGroupOptions{
+		Summary:     "List personal access tokens",
+		Description: "List the personal access tokens of the signed in user, without the secret",
+	})
+	humagroup.Post(meGroup, "/tokens", h.CreatePersonalToken, "CreatePersonalToken", &humagroup.HumaGroupOptions{
+		Summary:     "Create personal access token",
+		Description: "Create a scoped token for scripts and bots, the token is only shown once. Send it as a Bearer token.",
+	})
+	humagroup.Delete(meGroup, "/tokens/{id}", h.RevokePersonalToken, "RevokePersonalToken", &humagroup.HumaGroupOptions{
+		Summary:     "Revoke personal access token",
+		Description: "Delete a personal access token, it stops working immediately",
+	})
+	humagroup.Post(meGroup, "/calendar-feed", h.EnableCalendarFeed, "EnableCalendarFeed", &humagroup.HumaGroupOptions{
+		Summary:     "Enable calendar feed",
+		Description: "Create a secret iCalendar feed URL listing upcoming meetings, replacing the previous URL. The URL is only shown once.",
+	})
+	humagroup.Delete(meGroup, "/calendar-feed", h.DisableCalendarFeed, "DisableCalendarFeed", &humagroup.HumaGroupOptions{
+		Summary:     "Disable calendar feed",
+		Description: "Turn the calendar feed off, its URL stops working",
+	})
+	humagroup.Get(usersGroup, "/avatars/{name}", h.GetAvatar, "GetAvatar", &humagroup.HumaGroupOptions{
+		Summary:     "Get avatar",
+		Description: "Download a profile picture, the URL is the avatarUrl of a user",
+	})
+}
+
+type ProfileResponse struct {
+	Body struct {
+		User *models.User `json:"user" doc:"User details"`
+	}
+}
+
+func newProfileResponse(u *models.User) *ProfileResponse {
+	resp := &ProfileResponse{}
+	resp.Body.User = u
+	return resp
+}
+
+type GetProfileRequest struct {
+	AuthParam
+}
+
+func (h *UserHandler) GetProfile(ctx context.Context, input *GetProfileRequest) (*ProfileResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := h.userService.GetProfile(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrUserNotFound):
+			return nil, huma.Error404NotFound("user not found", err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
+	return newProfileResponse(u), nil
+}
+
+type UpdateProfileRequest struct {
+	AuthParam
+
+	Body struct {
+		DisplayName     *string `json:"displayName,omitempty" maxLength:"255" doc:"New display name" example:"John Doe"`
+		Email           *string `json:"email,omitempty" format:"email" maxLength:"255" doc:"New email address" example:"user@example.com"`
+		CurrentPassword string  `json:"currentPassword,omitempty" doc:"Current password, required to change the email address. Accounts created through single sign-on sign in again instead"`
+	}
+}
+
+func (h *UserHandler) UpdateProfile(ctx context.Context, input *UpdateProfileRequest) (*ProfileResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := h.userService.UpdateProfile(ctx, userID, user.ProfileUpdate{
+		DisplayName: input.Body.DisplayName,
+		Email:       input.Body.Email,
+		Confirmation: auth.Confirmation{
+			Password:        input.Body.CurrentPassword,
+			AuthenticatedAt: getAuthTimeFromContext(ctx),
+		},
+	})
+	if err != nil {
+		return nil, accountError(err)
+	}
+
+	return newProfileResponse(u), nil
+}
+
+type ChangePasswordRequest struct {
+	AuthParam
+
+	Body struct {
+		CurrentPassword string `json:"currentPassword" required:"true" doc:"Current password"`
+		NewPassword     string `json:"newPassword" required:"true" minLength:"8" doc:"New password" example:"securepassword123"`
+	}
+}
+
+type ChangePasswordResponse struct {
+	Body AuthResponse
+}
+
+func (h *UserHandler) ChangePassword(ctx context.Context, input *ChangePasswordRequest) (*ChangePasswordResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u, tokens, err := h.authService.ChangePassword(ctx, userID, input.Body.CurrentPassword, input.Body.NewPassword)
+	if err != nil {
+		return nil, accountError(err)
+	}
+
+	return &ChangePasswordResponse{
+		Body: newAuthResponse(u, tokens),
+	}, nil
+}
+
+type DeleteAccountRequest struct {
+	AuthParam
+
+	Body struct {
+		CurrentPassword string `json:"currentPassword,omitempty" doc:"Current password, confirms the deletion. Accounts created through single sign-on sign in again instead"`
+	}
+}
+
+func (h *UserHandler) DeleteAccount(ctx context.Context, input *DeleteAccountRequest) (*struct{}, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmation := auth.Confirmation{
+		Password:        input.Body.CurrentPassword,
+		AuthenticatedAt: getAuthTimeFromContext(ctx),
+	}
+	if err := h.userService.DeleteAccount(ctx, userID, confirmation); err != nil {
+		return nil, accountError(err)
+	}
+
+	// refresh tokens went with the user, the access token in hand would stay valid until it expires
+	if token, _, err := jwtauth.FromContext(ctx); err == nil && token != nil {
+		if err := h.authService.Logout(ctx, token.JwtID(), token.Expiration(), ""); err != nil {
+			log.Printf("Failed to revoke access token of deleted user: %v", err)
+		}
+	}
+
+	return &struct{}{}, nil
+}
+
+type AvatarForm struct {
+	Avatar huma.FormFile `form:"avatar" contentType:"image/png,image/jpeg,image/gif,image/webp" required:"true" doc:"Avatar image"`
+}
+
+type UploadAvatarRequest struct {
+	AuthParam
+
+	RawBody huma.MultipartFormFiles[AvatarForm]
+}
+
+func (h *UserHandler) UploadAvatar(ctx context.Context, input *UploadAvatarRequest) (*ProfileResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	avatar := input.RawBody.Data().Avatar
+	defer avatar.Close()
+
+	u, err := h.userService.SetAvatar(ctx, userID, avatar)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrAvatarTooLarge):
+			return nil, huma.NewError(http.StatusRequestEntityTooLarge, "avatar image is too large", err)
+		case errors.Is(err, user.ErrUnsupportedAvatar):
+			return nil, huma.Error415UnsupportedMediaType("avatar must be a PNG, JPEG, GIF or WebP image", err)
+		default:
+			return nil, accountError(err)
+		}
+	}
+
+	return newProfileResponse(u), nil
+}
+
+type RemoveAvatarRequest struct {
+	AuthParam
+}
+
+func (h *UserHandler) RemoveAvatar(ctx context.Context, input *RemoveAvatarRequest) (*ProfileResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := h.userService.RemoveAvatar(ctx, userID)
+	if err != nil {
+		return nil, accountError(err)
+	}
+
+	return newProfileResponse(u), nil
+}
+
+type GetAvatarRequest struct {
+	Name string `path:"name" doc:"avatar file name"`
+}
+
+func (h *UserHandler) GetAvatar(ctx context.Context, input *GetAvatarRequest) (*huma.StreamResponse, error) {
+	blob, contentType, err := h.userService.GetAvatar(ctx, input.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrAvatarNotFound):
+			return nil, huma.Error404NotFound("avatar not found", err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			defer blob.Close()
+
+			hctx.SetHeader("Content-Type", contentType)
+			hctx.SetHeader("X-Content-Type-Options", "nosniff")
+			// avatar names change with every upload
+			hctx.SetHeader("Cache-Control", "public, max-age=31536000, immutable")
+
+			if _, err := io.Copy(hctx.BodyWriter(), blob); err != nil {
+				log.Printf("Failed to write avatar: %v", err)
+			}
+		},
+	}, nil
+}
+
+type ListPersonalTokensRequest struct {
+	AuthParam
+}
+
+type ListPersonalTokensResponse struct {
+	Body struct {
+		Tokens []*models.PersonalAccessToken `json:"tokens" doc:"personal access tokens, newest first"`
+	}
+}
+
+func (h *UserHandler) ListPersonalTokens(ctx context.Context, input *ListPersonalTokensRequest) (*ListPersonalTokensResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := h.authService.ListPersonalTokens(ctx, userID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("an error occured", err)
+	}
+
+	resp := &ListPersonalTokensResponse{}
+	resp.Body.Tokens = tokens
+	return resp, nil
+}
+
+type CreatePersonalTokenRequest struct {
+	AuthParam
+
+	Body struct {
+		Name          string   `json:"name" required:"true" minLength:"1" maxLength:"100" doc:"Name to recognise the token by" example:"CI meeting scheduler"`
+		Scopes        []string `json:"scopes" required:"true" minItems:"1" enum:"meetings:read,meetings:write,chat:read,chat:write,profile:read" doc:"What the token may do"`
+		ExpiresInDays int      `json:"expiresInDays,omitempty" minimum:"0" maximum:"365" doc:"Days until the token expires, 0 never expires"`
+	}
+}
+
+type CreatePersonalTokenResponse struct {
+	Body struct {
+		Token  string                      `json:"token" doc:"The secret token, store it now, it can't be shown again"`
+		Detail *models.PersonalAccessToken `json:"detail" doc:"Token details"`
+	}
+}
+
+func (h *UserHandler) CreatePersonalToken(ctx context.Context, input *CreatePersonalTokenRequest) (*CreatePersonalTokenResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := time.Duration(input.Body.ExpiresInDays) * 24 * time.Hour
+	token, raw, err := h.authService.CreatePersonalToken(ctx, userID, input.Body.Name, input.Body.Scopes, ttl)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrUnknownScope), errors.Is(err, auth.ErrNoScopes):
+			return nil, huma.Error400BadRequest(err.Error(), err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
+	resp := &CreatePersonalTokenResponse{}
+	resp.Body.Token = raw
+	resp.Body.Detail = token
+	return resp, nil
+}
+
+type RevokePersonalTokenRequest struct {
+	AuthParam
+
+	ID string `path:"id" format:"uuid" doc:"personal access token id"`
+}
+
+func (h *UserHandler) RevokePersonalToken(ctx context.Context, input *RevokePersonalTokenRequest) (*struct{}, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.authService.RevokePersonalToken(ctx, userID, input.ID); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPersonalTokenNotFound):
+			return nil, huma.Error404NotFound("personal access token not found", err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
+	return &struct{}{}, nil
+}
+
+type EnableCalendarFeedRequest struct {
+	AuthParam
+}
+
+type EnableCalendarFeedResponse struct {
+	Body struct {
+		URL string `json:"url" doc:"Path of the feed on this server, keep it secret. It is only shown once." example:"/api/calendar/3q2-7wQ8qXpM0uYk.ics"`
+	}
+}
+
+func (h *UserHandler) EnableCalendarFeed(ctx context.Context, input *EnableCalendarFeedRequest) (*EnableCalendarFeedResponse, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	feedURL, err := h.userService.EnableCalendarFeed(ctx, userID)
+	if err != nil {
+		return nil, accountError(err)
+	}
+
+	resp := &EnableCalendarFeedResponse{}
+	resp.Body.URL = feedURL
+	return resp, nil
+}
+
+type DisableCalendarFeedRequest struct {
+	AuthParam
+}
+
+func (h *UserHandler) DisableCalendarFeed(ctx context.Context, input *DisableCalendarFeedRequest) (*struct{}, error) {
+	userID, err := getUserIdFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.userService.DisableCalendarFeed(ctx, userID); err != nil {
+		return nil, accountError(err)
+	}
+	return &struct{}{}, nil
+}
+
+// accountError maps the errors of profile and credential changes to responses
+func accountError(err error) error {
+	if limitErr := rateLimitError(err); limitErr != nil {
+		return limitErr
+	}
+	switch {
+	case errors.Is(err, user.ErrUserNotFound), errors.Is(err, auth.ErrUserNotFound):
+		return huma.Error404NotFound("user not found", err)
+	case errors.Is(err, auth.ErrIncorrectPassword):
+		return huma.Error403Forbidden("current password is incorrect", err)
+	case errors.Is(err, auth.ErrPasswordRequired):
+		return huma.Error400BadRequest("current password is required", err)
+	case errors.Is(err, auth.ErrReauthenticationRequired):
+		return huma.Error403Forbidden("sign in again to confirm this change", err)
+	case errors.Is(err, user.ErrInvalidDisplayName):
+		return huma.Error400BadRequest("display name cannot be empty", err)
+	case errors.Is(err, auth.ErrUserAlreadyExists):
+		return huma.Error409Conflict("user already exists", err)
+	default:
+		return huma.Error500InternalServerError("an error occured", err)
+	}
+}
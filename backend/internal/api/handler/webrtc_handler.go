@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -9,23 +10,26 @@ import (
 	"github.com/coder/websocket"
 	"github.com/coder/websocket/wsjson"
 	"github.com/danielgtaylor/huma/v2"
-	"github.com/go-chi/jwtauth/v5"
 	pion "github.com/pion/webrtc/v3"
 
 	"github.com/meetia/backend/internal/api/middleware"
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/meeting"
 	"github.com/meetia/backend/internal/services/webrtc"
 	humagroup "github.com/meetia/backend/lib/humaGroup"
 )
 
 type WebRTCHandler struct {
-	sfuService *webrtc.SFUService
-	tokenAuth  *jwtauth.JWTAuth
+	sfuService     *webrtc.SFUService
+	meetingService *meeting.MeetingService
+	tokenVerifier  middleware.TokenVerifier
 }
 
-func NewWebRTCHandler(sfuService *webrtc.SFUService, tokenAuth *jwtauth.JWTAuth) *WebRTCHandler {
+func NewWebRTCHandler(sfuService *webrtc.SFUService, meetingService *meeting.MeetingService, tokenVerifier middleware.TokenVerifier) *WebRTCHandler {
 	return &WebRTCHandler{
-		sfuService: sfuService,
-		tokenAuth:  tokenAuth,
+		sfuService:     sfuService,
+		meetingService: meetingService,
+		tokenVerifier:  tokenVerifier,
 	}
 }
 
@@ -34,7 +38,7 @@ func (h *WebRTCHandler) RegisterRoutes(api huma.API) {
 		api,
 		"/api/rtc",
 		[]string{"WebRTC"},
-		middleware.JWTMiddleware(h.tokenAuth),
+		middleware.JWTMiddleware(h.tokenVerifier),
 	)
 
 	humagroup.Get(
@@ -44,7 +48,8 @@ func (h *WebRTCHandler) RegisterRoutes(api huma.API) {
 		"wsSignal",
 		&humagroup.HumaGroupOptions{
 			Summary:     "WebRTC Signaling",
-			Description: "WebSocket endpoint for WebRTC signaling",
+			Description: "WebSocket endpoint for WebRTC signaling, raised hands and reactions, participants waiting in the lobby get no peer connection until admitted",
+			GuestParam:  "meetingID",
 		},
 	)
 }
@@ -63,6 +68,23 @@ func (h *WebRTCHandler) HandleWebSocket(ctx context.Context, input *handleWebSoc
 		return nil, err
 	}
 
+	// only participants get a connection, refuse the others before upgrading
+	status, err := h.meetingService.SignalingStatus(ctx, meetingID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, meeting.ErrMeetingNotFound):
+			return nil, huma.Error404NotFound("meeting not found", err)
+		case errors.Is(err, meeting.ErrMeetingEnded):
+			return nil, huma.Error410Gone("meeting has ended", err)
+		case errors.Is(err, meeting.ErrNotParticipant):
+			return nil, huma.Error403Forbidden("join the meeting before connecting", err)
+		case errors.Is(err, meeting.ErrAdmissionDenied):
+			return nil, huma.Error403Forbidden(err.Error(), err)
+		default:
+			return nil, huma.Error500InternalServerError("an error occured", err)
+		}
+	}
+
 	r, w, ok := middleware.GetHttpContext(ctx)
 	if !ok {
 		return nil, fmt.Errorf("http context not available")
@@ -79,32 +101,89 @@ func (h *WebRTCHandler) HandleWebSocket(ctx context.Context, input *handleWebSoc
 	}
 	defer c.Close(websocket.StatusInternalError, "Connection closed")
 
-	// create peer connection
-	peer, err := h.sfuService.CreatePeerConnection(meetingID, userID)
+	// signal channel to coordinate websocket communication
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// a single reader for the lifetime of the connection, it also notices when the client goes away
+	incoming := make(chan webrtc.SignalMessage)
+	go func() {
+		defer cancel() // cancel context when this goroutine exits
+
+		for {
+			var msg webrtc.SignalMessage
+			if err := wsjson.Read(ctx, c, &msg); err != nil {
+				log.Printf("WebSocket read error: %v", err)
+				return
+			}
+			select {
+			case incoming <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	if status == models.ParticipantWaiting {
+		admitted, err := h.waitForAdmission(ctx, c, meetingID, userID, incoming)
+		if err != nil {
+			log.Printf("Waiting room error: %v", err)
+			return &struct{}{}, nil
+		}
+		if !admitted {
+			c.Close(websocket.StatusPolicyViolation, meeting.ErrAdmissionDenied.Error())
+			return &struct{}{}, nil
+		}
+	}
+
+	// create peer connection, the SFU refuses it once the meeting is full
+	limit, err := h.meetingService.PeerLimit(ctx, meetingID, userID)
+	if err != nil {
+		log.Printf("Failed to look up participant limit: %v", err)
+		c.Close(websocket.StatusInternalError, "Failed to create peer connection")
+		return nil, fmt.Errorf("failed to look up participant limit: %v", err)
+	}
+	peer, err := h.sfuService.CreatePeerConnection(meetingID, userID, limit)
+	if errors.Is(err, webrtc.ErrRoomFull) {
+		c.Close(websocket.StatusPolicyViolation, meeting.ErrMeetingFull.Error())
+		return &struct{}{}, nil
+	}
 	if err != nil {
 		log.Printf("Failed to create peer connection: %v", err)
 		c.Close(websocket.StatusInternalError, "Failed to create peer connection")
 		return nil, fmt.Errorf("failed to create peer connection: %v", err)
 	}
 
-	// signal channel to coordinate websocket communication
-	ctx, cancel := context.WithCancel(r.Context())
-	defer cancel()
+	// users reconnecting while the breakout rooms are open go straight to their room
+	breakoutID, err := h.meetingService.BreakoutRoomOf(ctx, meetingID, userID)
+	if err != nil {
+		log.Printf("Failed to look up breakout room: %v", err)
+	} else if breakoutID != "" {
+		h.sfuService.MoveToBreakout(meetingID, breakoutID, userID)
+	}
+
+	// the speaking queue outlives connections, catch the client up with it
+	peer.SignalChannel <- &webrtc.SignalMessage{
+		Type:      webrtc.EventHands,
+		UserID:    "server",
+		MeetingID: meetingID,
+		Data:      h.sfuService.Hands(meetingID),
+	}
 
 	// wait group to ensure all goroutines finish before closing
 	var wg sync.WaitGroup
 	wg.Add(2)
 
-	// read messages from websocket
+	// handle messages from websocket
 	go func() {
 		defer wg.Done()
 		defer cancel() // cancel context when this goroutine exits
 
 		for {
 			var msg webrtc.SignalMessage
-			err := wsjson.Read(ctx, c, &msg)
-			if err != nil {
-				log.Printf("WebSocket read error: %v", err)
+			select {
+			case msg = <-incoming:
+			case <-ctx.Done():
 				return
 			}
 
@@ -154,6 +233,9 @@ func (h *WebRTCHandler) HandleWebSocket(ctx context.Context, input *handleWebSoc
 						log.Printf("Handle candidate error: %v", err)
 					}
 				}
+
+			case "raise-hand", "lower-hand", "lower-all-hands", "next-hand", "reaction":
+				h.handleHandMessage(ctx, meetingID, userID, msg)
 			}
 		}
 	}()
@@ -177,7 +259,104 @@ func (h *WebRTCHandler) HandleWebSocket(ctx context.Context, input *handleWebSoc
 
 	// Wait for both goroutines to finish
 	wg.Wait()
+	// the media goes with the signaling socket, the user left the meeting now
+	h.sfuService.ClosePeer(peer)
 	c.Close(websocket.StatusNormalClosure, "Connection closed")
 
 	return &struct{}{}, nil
 }
+
+// waitForAdmission keeps a waiting participant's socket open without a peer connection until a
+// host decides. Signaling messages sent meanwhile are dropped. It reports whether they were admitted.
+func (h *WebRTCHandler) waitForAdmission(ctx context.Context, c *websocket.Conn, meetingID string, userID string, incoming <-chan webrtc.SignalMessage) (bool, error) {
+	events, leave := h.sfuService.WaitInLobby(meetingID, userID)
+	defer leave()
+
+	// a host may have decided before the lobby registration
+	status, err := h.meetingService.SignalingStatus(ctx, meetingID, userID)
+	if errors.Is(err, meeting.ErrAdmissionDenied) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if status == models.ParticipantAdmitted {
+		return true, nil
+	}
+
+	waiting := webrtc.SignalMessage{
+		Type:      "waiting",
+		UserID:    userID,
+		MeetingID: meetingID,
+	}
+	if err := wsjson.Write(ctx, c, waiting); err != nil {
+		return false, err
+	}
+
+	for {
+		select {
+		case msg := <-events:
+			switch msg.Type {
+			case meeting.EventAdmitted:
+				return true, wsjson.Write(ctx, c, msg)
+			case meeting.EventDenied:
+				return false, wsjson.Write(ctx, c, msg)
+			}
+		case msg := <-incoming:
+			log.Printf("Dropped %s message from user %s waiting for admission", msg.Type, userID)
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+// maxReactionLength bounds the bytes of a reaction, enough for any emoji sequence
+const maxReactionLength = 32
+
+// handleHandMessage runs the speaking queue and reactions. Anyone raises and lowers their own
+// hand, lowering other hands and calling on the next speaker needs PermManageHands.
+func (h *WebRTCHandler) handleHandMessage(ctx context.Context, meetingID string, userID string, msg webrtc.SignalMessage) {
+	switch msg.Type {
+	case "raise-hand":
+		h.sfuService.RaiseHand(meetingID, userID)
+
+	case "lower-hand":
+		if msg.Target == "" || msg.Target == userID {
+			h.sfuService.LowerHands(meetingID, []string{userID})
+			return
+		}
+		if h.canManageHands(ctx, meetingID, userID) {
+			h.sfuService.LowerHands(meetingID, []string{msg.Target})
+		}
+
+	case "lower-all-hands":
+		if h.canManageHands(ctx, meetingID, userID) {
+			h.sfuService.LowerHands(meetingID, nil)
+		}
+
+	case "next-hand":
+		if h.canManageHands(ctx, meetingID, userID) {
+			h.sfuService.NextHand(meetingID)
+		}
+
+	case "reaction":
+		emoji, ok := msg.Data.(string)
+		if !ok || emoji == "" || len(emoji) > maxReactionLength {
+			log.Printf("Dropped invalid reaction from user %s", userID)
+			return
+		}
+		h.sfuService.React(meetingID, userID, emoji)
+	}
+}
+
+func (h *WebRTCHandler) canManageHands(ctx context.Context, meetingID string, userID string) bool {
+	allowed, err := h.meetingService.HasPermission(ctx, meetingID, userID, meeting.PermManageHands)
+	if err != nil {
+		log.Printf("Failed to check hand permissions: %v", err)
+		return false
+	}
+	if !allowed {
+		log.Printf("User %s may not manage the raised hands of meeting %s", userID, meetingID)
+	}
+	return allowed
+}
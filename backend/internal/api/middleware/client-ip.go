@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"context"
+	"net"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type clientIPKey struct{}
+
+// ClientIP stores the address of the caller in the request context, see GetClientIP.
+// Run chi's RealIP middleware in front of it when the server sits behind a trusted proxy.
+func ClientIP(ctx huma.Context, next func(huma.Context)) {
+	ip := ctx.RemoteAddr()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	next(huma.WithValue(ctx, clientIPKey{}, ip))
+}
+
+func GetClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
@@ -1,35 +1,86 @@
 package middleware
 
 import (
+	"context"
+	"log/slog"
 	"net/http"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humachi"
 	"github.com/go-chi/jwtauth/v5"
+	"github.com/lestrrat-go/jwx/v2/jwt"
 )
 
-func JWTMiddleware(tokenAuth *jwtauth.JWTAuth) func(huma.Context, func(huma.Context)) {
+// TokenVerifier provides what JWTMiddleware needs to accept an access token. VerifyToken also
+// accepts personal access tokens, their claims carry the scopes humagroup checks per operation.
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, tokenString string) (jwt.Token, error)
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+func JWTMiddleware(verifier TokenVerifier) func(huma.Context, func(huma.Context)) {
 	return func(ctx huma.Context, next func(huma.Context)) {
 		// Unwrap only once at the beginning.
 		r, w := humachi.Unwrap(ctx)
 
-		// Get the standard Chi JWT middlewares
-		jwtVerifier := Verifier(tokenAuth)
-		jwtAuthenticator := jwtauth.Authenticator(tokenAuth)
-
 		finalHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			newCtx := huma.WithContext(ctx, r.Context())
 			next(newCtx)
 		})
 
-		chainedHandler := jwtVerifier(jwtAuthenticator(WithHttpContextChi(finalHandler)))
+		chainedHandler := Verifier(verifier)(Authenticator(verifier)(WithHttpContextChi(finalHandler)))
 		chainedHandler.ServeHTTP(w, r)
 	}
 }
 
-func Verifier(ja *jwtauth.JWTAuth) func(http.Handler) http.Handler {
+// Verifier looks for a token in the request and stores the verified token, or the
+// verification error, in the request context the same way jwtauth.Verifier does
+func Verifier(verifier TokenVerifier) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		return jwtauth.Verify(ja, jwtauth.TokenFromHeader, jwtauth.TokenFromCookie, tokenFromQuery)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var token jwt.Token
+			err := jwtauth.ErrNoTokenFound
+
+			for _, find := range []func(*http.Request) string{jwtauth.TokenFromHeader, jwtauth.TokenFromCookie, tokenFromQuery} {
+				if tokenString := find(r); tokenString != "" {
+					token, err = verifier.VerifyToken(r.Context(), tokenString)
+					break
+				}
+			}
+
+			ctx := jwtauth.NewContext(r.Context(), token, err)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Authenticator rejects requests without a valid token, or whose token jti has been revoked
+func Authenticator(verifier TokenVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _, err := jwtauth.FromContext(r.Context())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if token == nil || token.JwtID() == "" {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			revoked, err := verifier.IsTokenRevoked(r.Context(), token.JwtID())
+			if err != nil {
+				slog.Error("failed to check token revocation", "error", err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			if revoked {
+				http.Error(w, "token has been revoked", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
 	}
 }
 
@@ -6,6 +6,7 @@ import (
 	"github.com/meetia/backend/internal/api/handler"
 	"github.com/meetia/backend/internal/services/auth"
 	"github.com/meetia/backend/internal/services/meeting"
+	"github.com/meetia/backend/internal/services/user"
 	"github.com/meetia/backend/internal/services/webrtc"
 )
 
@@ -14,12 +15,19 @@ func SetupRoutes(
 	authService *auth.AuthService,
 	sfuService *webrtc.SFUService,
 	meetingService *meeting.MeetingService,
+	userService *user.UserService,
 ) {
-	webrtcHandler := handler.NewWebRTCHandler(sfuService, authService.GetTokenAuth())
-	meetingHandler := handler.NewMeetinghandler(meetingService, authService.GetTokenAuth())
+	webrtcHandler := handler.NewWebRTCHandler(sfuService, meetingService, authService)
+	meetingHandler := handler.NewMeetinghandler(meetingService, authService, authService)
 	authHandler := handler.NewAuthHandler(authService)
+	chatHandler := handler.NewChatHandler(meetingService, authService)
+	userHandler := handler.NewUserHandler(userService, authService)
+	calendarHandler := handler.NewCalendarHandler(userService, meetingService)
 
 	authHandler.RegisterRoutes(api)
 	webrtcHandler.RegisterRoutes(api)
 	meetingHandler.RegisterRoutes(api)
+	chatHandler.RegisterRoutes(api)
+	userHandler.RegisterRoutes(api)
+	calendarHandler.RegisterRoutes(api)
 }
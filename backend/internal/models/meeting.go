@@ -17,47 +17,358 @@ const (
 type Meeting struct {
 	bun.BaseModel `bun:"table:meetings,alias:m"`
 
-	ID          string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
-	Title       string    `bun:"title,notnull" json:"title"`
-	HostID      string    `bun:"host_id,notnull" json:"hostId"`
-	MeetingCode string    `bun:"meeting_code,notnull,unique" json:"meetingCode"`
-	Password    string    `bun:"password" json:"password,omitempty"`
-	IsPrivate   bool      `bun:"is_private,notnull" json:"isPrivate"`
-	CreatedAt   time.Time `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
-	UpdatedAt   time.Time `bun:"updated_at,notnull,default:current_timestamp" json:"updatedAt"`
-	ScheduledAt time.Time `bun:"scheduled_at" json:"scheduledAt,omitempty"`
-	EndedAt     time.Time `bun:"ended_at" json:"endedAt,omitempty"`
+	ID              string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Title           string    `bun:"title,notnull" json:"title"`
+	HostID          string    `bun:"host_id,nullzero" json:"hostId"` // empty once the host deleted their account
+	MeetingCode     string    `bun:"meeting_code,notnull,unique" json:"meetingCode"`
+	PasswordHash    string    `bun:"password_hash,nullzero" json:"-"`
+	IsPrivate       bool      `bun:"is_private,notnull" json:"isPrivate"`
+	InviteOnly      bool      `bun:"invite_only,notnull" json:"inviteOnly"`            // only the host and invited users can join
+	WaitingRoom     bool      `bun:"waiting_room,notnull" json:"waitingRoom"`          // joiners wait until a host admits them
+	Locked          bool      `bun:"locked,notnull" json:"locked"`                     // no new joiners are accepted
+	AllowGuests     bool      `bun:"allow_guests,notnull" json:"allowGuests"`          // people without an account may join with a display name
+	Personal        bool      `bun:"personal,notnull" json:"personal"`                 // the permanent room of its host, ending it only resets it
+	MaxParticipants int       `bun:"max_participants,nullzero" json:"maxParticipants"` // users connected at once, 0 for the server default
+	CreatedAt       time.Time `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt       time.Time `bun:"updated_at,notnull,default:current_timestamp" json:"updatedAt"`
+	ScheduledAt     time.Time `bun:"scheduled_at,nullzero" json:"scheduledAt,omitempty"`
+	DurationMinutes int       `bun:"duration_minutes,nullzero" json:"durationMinutes,omitempty"`
+	Timezone        string    `bun:"timezone,nullzero" json:"timezone,omitempty"` // IANA name the schedule was made in, for display
+	EndedAt         time.Time `bun:"ended_at,nullzero" json:"endedAt,omitempty"`
+	SeriesID        string    `bun:"series_id,nullzero" json:"seriesId,omitempty"` // set on occurrences of a meeting series
+
+	BreakoutsOpenedAt time.Time `bun:"breakouts_opened_at,nullzero" json:"breakoutsOpenedAt,omitempty"`
+	BreakoutsCloseAt  time.Time `bun:"breakouts_close_at,nullzero" json:"breakoutsCloseAt,omitempty"` // when a timer or countdown closes the breakout rooms
 
 	// Relations
 	Host         *User                 `bun:"rel:belongs-to,join:host_id=id" json:"host,omitempty"`
 	Participants []*MeetingParticipant `bun:"rel:has-many,join:id=meeting_id" json:"participants,omitempty"`
 }
 
-type MeetingParticipant struct {
-	bun.BaseModel `bun:"table:meeting_participants,alias:mp"`
+type MeetingStatus string
+
+const (
+	MeetingStatusUpcoming MeetingStatus = "upcoming"
+	MeetingStatusLive     MeetingStatus = "live"
+	MeetingStatusPast     MeetingStatus = "past"
+)
+
+// IsScheduled reports whether the meeting has a planned start, instant meetings don't
+func (m *Meeting) IsScheduled() bool {
+	return !m.ScheduledAt.IsZero()
+}
+
+// ScheduledEnd is when a scheduled meeting is planned to finish
+func (m *Meeting) ScheduledEnd() time.Time {
+	return m.ScheduledAt.Add(time.Duration(m.DurationMinutes) * time.Minute)
+}
+
+// Status tells whether the meeting is still to come, can be joined, or is over
+func (m *Meeting) Status(now time.Time) MeetingStatus {
+	switch {
+	case !m.EndedAt.IsZero():
+		return MeetingStatusPast
+	case m.IsScheduled() && now.Before(m.ScheduledAt):
+		return MeetingStatusUpcoming
+	default:
+		return MeetingStatusLive
+	}
+}
+
+// BreakoutsOpen reports whether participants are in breakout rooms
+func (m *Meeting) BreakoutsOpen() bool {
+	return !m.BreakoutsOpenedAt.IsZero()
+}
+
+// MeetingSeries is a recurring meeting. Its occurrences are expanded from the RRULE when listed,
+// and stored as meetings sharing the series code once someone joins them.
+type MeetingSeries struct {
+	bun.BaseModel `bun:"table:meeting_series,alias:ms"`
+
+	ID              string      `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Title           string      `bun:"title,notnull" json:"title"`
+	HostID          string      `bun:"host_id,nullzero" json:"hostId"`
+	MeetingCode     string      `bun:"meeting_code,notnull,unique" json:"meetingCode"`
+	PasswordHash    string      `bun:"password_hash,nullzero" json:"-"`
+	IsPrivate       bool        `bun:"is_private,notnull" json:"isPrivate"`
+	RRule           string      `bun:"rrule,notnull" json:"rrule"`        // RFC 5545 RRULE without DTSTART, e.g. FREQ=WEEKLY;BYDAY=MO
+	StartsAt        time.Time   `bun:"starts_at,notnull" json:"startsAt"` // start of the first occurrence
+	DurationMinutes int         `bun:"duration_minutes,notnull" json:"durationMinutes"`
+	Timezone        string      `bun:"timezone,notnull" json:"timezone"` // the rule repeats on wall clock time in this zone
+	ExDates         []time.Time `bun:"exdates,array" json:"exdates"`     // starts of cancelled occurrences
+	CreatedAt       time.Time   `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt       time.Time   `bun:"updated_at,notnull,default:current_timestamp" json:"updatedAt"`
+	EndedAt         time.Time   `bun:"ended_at,nullzero" json:"endedAt,omitempty"`
+
+	// Relations
+	Host *User `bun:"rel:belongs-to,join:host_id=id" json:"host,omitempty"`
+}
+
+// Duration is the planned length of every occurrence
+func (s *MeetingSeries) Duration() time.Duration {
+	return time.Duration(s.DurationMinutes) * time.Minute
+}
+
+type InvitationStatus string
+
+const (
+	InvitationPending  InvitationStatus = "pending"
+	InvitationAccepted InvitationStatus = "accepted"
+	InvitationDeclined InvitationStatus = "declined"
+)
 
-	ID        string          `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
-	MeetingID string          `bun:"meeting_id,notnull" json:"meetingId"`
-	UserID    string          `bun:"user_id,notnull" json:"userId"`
-	Role      ParticipantRole `bun:"role,notnull" json:"role"` // host, co-host, participant
-	JoinedAt  time.Time       `bun:"joined_at" json:"joinedAt,omitempty"`
-	LeftAt    time.Time       `bun:"left_at" json:"leftAt,omitempty"`
+// MeetingInvitation invites a registered user or an email address to a meeting
+type MeetingInvitation struct {
+	bun.BaseModel `bun:"table:meeting_invitations,alias:mi"`
+
+	ID          string           `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	MeetingID   string           `bun:"meeting_id,notnull" json:"meetingId"`
+	InvitedBy   string           `bun:"invited_by,nullzero" json:"invitedBy"`
+	UserID      string           `bun:"user_id,nullzero" json:"userId,omitempty"` // empty until the address belongs to an account
+	Email       string           `bun:"email,notnull" json:"email"`               // lower case
+	Status      InvitationStatus `bun:"status,notnull" json:"status"`
+	CreatedAt   time.Time        `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
+	RespondedAt time.Time        `bun:"responded_at,nullzero" json:"respondedAt,omitempty"`
 
 	// Relations
 	Meeting *Meeting `bun:"rel:belongs-to,join:meeting_id=id" json:"meeting,omitempty"`
+	Inviter *User    `bun:"rel:belongs-to,join:invited_by=id" json:"inviter,omitempty"`
 	User    *User    `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
 }
 
+type ParticipantStatus string
+
+const (
+	ParticipantWaiting  ParticipantStatus = "waiting"
+	ParticipantAdmitted ParticipantStatus = "admitted"
+	ParticipantDenied   ParticipantStatus = "denied"
+)
+
+type MeetingParticipant struct {
+	bun.BaseModel `bun:"table:meeting_participants,alias:mp"`
+
+	ID        string            `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	MeetingID string            `bun:"meeting_id,notnull" json:"meetingId"`
+	UserID    string            `bun:"user_id,nullzero" json:"userId"`                  // empty for deleted accounts and guests
+	GuestID   string            `bun:"guest_id,nullzero" json:"guestId,omitempty"`      // set instead of UserID for guests
+	Role      ParticipantRole   `bun:"role,notnull" json:"role"`                        // Host, Co-Host or Participant
+	Status    ParticipantStatus `bun:"status,notnull,default:'admitted'" json:"status"` // waiting in the lobby, admitted or denied
+	JoinedAt  time.Time         `bun:"joined_at,nullzero" json:"joinedAt,omitempty"`    // first join, see AttendanceSession for each one
+	LeftAt    time.Time         `bun:"left_at,nullzero" json:"leftAt,omitempty"`        // end of the last session, empty while connected
+
+	// Relations
+	Meeting *Meeting      `bun:"rel:belongs-to,join:meeting_id=id" json:"meeting,omitempty"`
+	User    *User         `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+	Guest   *MeetingGuest `bun:"rel:belongs-to,join:guest_id=id" json:"guest,omitempty"`
+}
+
+// MemberID is who the participant is on signaling, their user id or guest id
+func (p *MeetingParticipant) MemberID() string {
+	if p.GuestID != "" {
+		return p.GuestID
+	}
+	return p.UserID
+}
+
+// DisplayName is the name of the user or guest, the User or Guest relation must be loaded
+func (p *MeetingParticipant) DisplayName() string {
+	return memberDisplayName(p.User, p.Guest)
+}
+
+// MeetingGuest is someone who joined a meeting with a display name instead of an account,
+// guest tokens are only good for that meeting
+type MeetingGuest struct {
+	bun.BaseModel `bun:"table:meeting_guests,alias:mg"`
+
+	ID          string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	MeetingID   string    `bun:"meeting_id,notnull" json:"meetingId"`
+	DisplayName string    `bun:"display_name,notnull" json:"displayName"`
+	CreatedAt   time.Time `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+// memberDisplayName names the user or guest behind a record, records of deleted accounts have neither
+func memberDisplayName(user *User, guest *MeetingGuest) string {
+	switch {
+	case user != nil:
+		return user.DisplayName
+	case guest != nil:
+		return guest.DisplayName
+	default:
+		return DeletedUserDisplayName
+	}
+}
+
+// BreakoutRoom is a group participants of a meeting are split into
+type BreakoutRoom struct {
+	bun.BaseModel `bun:"table:breakout_rooms,alias:br"`
+
+	ID        string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	MeetingID string    `bun:"meeting_id,notnull" json:"meetingId"`
+	Name      string    `bun:"name,notnull" json:"name"`
+	Position  int       `bun:"position,notnull" json:"position"`
+	CreatedAt time.Time `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
+
+	// Relations
+	Assignments []*BreakoutAssignment `bun:"rel:has-many,join:id=room_id" json:"assignments,omitempty"`
+}
+
+// BreakoutAssignment puts a participant into a breakout room
+type BreakoutAssignment struct {
+	bun.BaseModel `bun:"table:breakout_assignments,alias:ba"`
+
+	MeetingID  string    `bun:"meeting_id,pk" json:"meetingId"`
+	UserID     string    `bun:"user_id,pk" json:"userId"`
+	RoomID     string    `bun:"room_id,notnull" json:"roomId"`
+	AssignedAt time.Time `bun:"assigned_at,notnull,default:current_timestamp" json:"assignedAt"`
+
+	// Relations
+	User *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+}
+
+type PollStatus string
+
+const (
+	PollDraft  PollStatus = "draft"
+	PollOpen   PollStatus = "open"
+	PollClosed PollStatus = "closed"
+)
+
+// MeetingPoll is a question hosts put to the participants of a meeting
+type MeetingPoll struct {
+	bun.BaseModel `bun:"table:meeting_polls,alias:pl"`
+
+	ID             string     `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	MeetingID      string     `bun:"meeting_id,notnull" json:"meetingId"`
+	CreatedBy      string     `bun:"created_by,nullzero" json:"createdBy"`
+	Question       string     `bun:"question,notnull" json:"question"`
+	MultipleChoice bool       `bun:"multiple_choice,notnull" json:"multipleChoice"`
+	Anonymous      bool       `bun:"anonymous,notnull" json:"anonymous"` // results never say who voted for what
+	Status         PollStatus `bun:"status,notnull" json:"status"`
+	CreatedAt      time.Time  `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
+	OpenedAt       time.Time  `bun:"opened_at,nullzero" json:"openedAt,omitempty"`
+	ClosedAt       time.Time  `bun:"closed_at,nullzero" json:"closedAt,omitempty"`
+
+	// Relations
+	Options []*MeetingPollOption `bun:"rel:has-many,join:id=poll_id" json:"options,omitempty"`
+	Votes   []*MeetingPollVote   `bun:"rel:has-many,join:id=poll_id" json:"votes,omitempty"`
+}
+
+type MeetingPollOption struct {
+	bun.BaseModel `bun:"table:meeting_poll_options,alias:plo"`
+
+	ID       string `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	PollID   string `bun:"poll_id,notnull" json:"pollId"`
+	Label    string `bun:"label,notnull" json:"label"`
+	Position int    `bun:"position,notnull" json:"position"`
+}
+
+// MeetingPollVote is one option a user picked, multiple choice polls take several per user
+type MeetingPollVote struct {
+	bun.BaseModel `bun:"table:meeting_poll_votes,alias:plv"`
+
+	ID       string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	PollID   string    `bun:"poll_id,notnull" json:"pollId"`
+	BallotID string    `bun:"ballot_id,notnull" json:"-"`     // shared by the options picked in one vote
+	UserID   string    `bun:"user_id,nullzero" json:"userId"` // empty once the voter's account is deleted
+	OptionID string    `bun:"option_id,notnull" json:"optionId"`
+	VotedAt  time.Time `bun:"voted_at,notnull,default:current_timestamp" json:"votedAt"`
+
+	// Relations
+	User *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+}
+
+type QuestionStatus string
+
+const (
+	QuestionOpen      QuestionStatus = "open"
+	QuestionAnswered  QuestionStatus = "answered"
+	QuestionDismissed QuestionStatus = "dismissed"
+)
+
+// MeetingQuestion is a question on the Q&A board of a meeting
+type MeetingQuestion struct {
+	bun.BaseModel `bun:"table:meeting_questions,alias:mq"`
+
+	ID         string         `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	MeetingID  string         `bun:"meeting_id,notnull" json:"meetingId"`
+	UserID     string         `bun:"user_id,nullzero" json:"-"` // the author, also on anonymous questions
+	Text       string         `bun:"text,notnull" json:"text"`
+	Anonymous  bool           `bun:"anonymous,notnull" json:"anonymous"` // the author is never shown
+	Status     QuestionStatus `bun:"status,notnull" json:"status"`
+	CreatedAt  time.Time      `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
+	ResolvedAt time.Time      `bun:"resolved_at,nullzero" json:"resolvedAt,omitempty"` // when it was answered or dismissed
+	Upvotes    int            `bun:"upvotes,scanonly" json:"upvotes"`                  // counted from meeting_question_votes
+
+	// Relations
+	User *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+}
+
+type MeetingQuestionVote struct {
+	bun.BaseModel `bun:"table:meeting_question_votes,alias:mqv"`
+
+	QuestionID string    `bun:"question_id,pk" json:"questionId"`
+	UserID     string    `bun:"user_id,pk" json:"userId"`
+	CreatedAt  time.Time `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+// AttendanceSession is one media connection of a participant to a meeting
+type AttendanceSession struct {
+	bun.BaseModel `bun:"table:attendance_sessions,alias:ats"`
+
+	ID        string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	MeetingID string    `bun:"meeting_id,notnull" json:"meetingId"`
+	UserID    string    `bun:"user_id,nullzero" json:"userId"`             // empty for deleted accounts and guests
+	GuestID   string    `bun:"guest_id,nullzero" json:"guestId,omitempty"` // set instead of UserID for guests
+	JoinedAt  time.Time `bun:"joined_at,notnull,default:current_timestamp" json:"joinedAt"`
+	LeftAt    time.Time `bun:"left_at,nullzero" json:"leftAt,omitempty"` // empty while connected
+
+	// Relations
+	User  *User         `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+	Guest *MeetingGuest `bun:"rel:belongs-to,join:guest_id=id" json:"guest,omitempty"`
+}
+
+// MemberID is the user id or guest id the session belongs to
+func (a *AttendanceSession) MemberID() string {
+	if a.GuestID != "" {
+		return a.GuestID
+	}
+	return a.UserID
+}
+
+// DisplayName is the name of the user or guest, the User or Guest relation must be loaded
+func (a *AttendanceSession) DisplayName() string {
+	return memberDisplayName(a.User, a.Guest)
+}
+
 type MeetingChat struct {
 	bun.BaseModel `bun:"table:meeting_chats,alias:mc"`
 
 	ID        string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
 	MeetingID string    `bun:"meeting_id,notnull" json:"meetingId"`
-	UserID    string    `bun:"user_id,notnull" json:"userId"`
+	UserID    string    `bun:"user_id,nullzero" json:"userId"`             // empty for deleted accounts and guests
+	GuestID   string    `bun:"guest_id,nullzero" json:"guestId,omitempty"` // set instead of UserID for guests
 	Message   string    `bun:"message,notnull" json:"message"`
 	SentAt    time.Time `bun:"sent_at,notnull,default:current_timestamp" json:"sentAt"`
 
 	// Relations
-	Meeting *Meeting `bun:"rel:belongs-to,join:meeting_id=id" json:"meeting,omitempty"`
-	User    *User    `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+	Meeting *Meeting      `bun:"rel:belongs-to,join:meeting_id=id" json:"meeting,omitempty"`
+	User    *User         `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+	Guest   *MeetingGuest `bun:"rel:belongs-to,join:guest_id=id" json:"guest,omitempty"`
+}
+
+// DisplayName is the name of the user or guest who sent the message, the User or Guest relation must be loaded
+func (c *MeetingChat) DisplayName() string {
+	return memberDisplayName(c.User, c.Guest)
+}
+
+// ChatSearchResult is a single full-text match over meeting_chats
+type ChatSearchResult struct {
+	ID           string    `bun:"id" json:"id"`
+	MeetingID    string    `bun:"meeting_id" json:"meetingId"`
+	MeetingTitle string    `bun:"meeting_title" json:"meetingTitle"`
+	UserID       string    `bun:"user_id" json:"userId"`
+	DisplayName  string    `bun:"display_name" json:"displayName"`
+	Snippet      string    `bun:"snippet" json:"snippet"`
+	SentAt       time.Time `bun:"sent_at" json:"sentAt"`
+	Rank         float64   `bun:"rank" json:"rank"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// RateLimit counts recent failed attempts for a key such as an IP address or email
+type RateLimit struct {
+	bun.BaseModel `bun:"table:rate_limits,alias:rl"`
+
+	Key         string    `bun:"key,pk" json:"key"`
+	Failures    int       `bun:"failures,notnull" json:"failures"`
+	LastFailure time.Time `bun:"last_failure,notnull" json:"lastFailure"`
+}
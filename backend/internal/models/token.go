@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+type RefreshToken struct {
+	bun.BaseModel `bun:"table:refresh_tokens,alias:rt"`
+
+	ID         string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	UserID     string    `bun:"user_id,notnull" json:"userId"`
+	FamilyID   string    `bun:"family_id,notnull,type:uuid" json:"familyId"` // all tokens rotated from the same login share a family
+	TokenHash  string    `bun:"token_hash,notnull,unique" json:"-"`
+	ExpiresAt  time.Time `bun:"expires_at,notnull" json:"expiresAt"`
+	CreatedAt  time.Time `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
+	RevokedAt  time.Time `bun:"revoked_at,nullzero" json:"revokedAt,omitempty"`
+	ReplacedBy string    `bun:"replaced_by,nullzero,type:uuid" json:"replacedBy,omitempty"`
+	// AuthenticatedAt is when the user logged in, it carries over to the rotated tokens of the family
+	AuthenticatedAt time.Time `bun:"authenticated_at,notnull" json:"authenticatedAt"`
+
+	// Relations
+	User *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+}
+
+// RevokedToken is an access token that must be rejected before its expiry
+type RevokedToken struct {
+	bun.BaseModel `bun:"table:revoked_tokens,alias:rvt"`
+
+	JTI       string    `bun:"jti,pk" json:"jti"`
+	ExpiresAt time.Time `bun:"expires_at,notnull" json:"expiresAt"`
+	RevokedAt time.Time `bun:"revoked_at,notnull,default:current_timestamp" json:"revokedAt"`
+}
+
+type UserTokenPurpose string
+
+const (
+	UserTokenEmailVerification UserTokenPurpose = "email_verification"
+	UserTokenPasswordReset     UserTokenPurpose = "password_reset"
+)
+
+// UserToken is a single-use token sent to a user by email
+type UserToken struct {
+	bun.BaseModel `bun:"table:user_tokens,alias:ut"`
+
+	ID        string           `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	UserID    string           `bun:"user_id,notnull" json:"userId"`
+	Purpose   UserTokenPurpose `bun:"purpose,notnull" json:"purpose"`
+	TokenHash string           `bun:"token_hash,notnull,unique" json:"-"`
+	ExpiresAt time.Time        `bun:"expires_at,notnull" json:"expiresAt"`
+	UsedAt    time.Time        `bun:"used_at,nullzero" json:"usedAt,omitempty"`
+	CreatedAt time.Time        `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+// PersonalAccessToken is a long-lived, scoped credential a user creates for scripts and bots
+type PersonalAccessToken struct {
+	bun.BaseModel `bun:"table:personal_access_tokens,alias:pat"`
+
+	ID         string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	UserID     string    `bun:"user_id,notnull" json:"userId"`
+	Name       string    `bun:"name,notnull" json:"name"`
+	TokenHash  string    `bun:"token_hash,notnull,unique" json:"-"`
+	TokenHint  string    `bun:"token_hint,notnull" json:"tokenHint"` // first characters of the token, to recognise it in a list
+	Scopes     []string  `bun:"scopes,array,notnull" json:"scopes"`
+	ExpiresAt  time.Time `bun:"expires_at,nullzero" json:"expiresAt,omitempty"`
+	LastUsedAt time.Time `bun:"last_used_at,nullzero" json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
+
+	// Relations
+	User *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+}
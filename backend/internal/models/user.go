@@ -4,16 +4,59 @@ import (
 	"time"
 
 	"github.com/uptrace/bun"
-
 )
 
+// DeletedUserDisplayName is shown in place of the author of messages from deleted accounts
+const DeletedUserDisplayName = "Deleted user"
+
 type User struct {
 	bun.BaseModel `bun:"table:users,alias:u"`
 
-	ID           string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
-	Email        string    `bun:"email,notnull,unique" json:"email"`
-	PasswordHash string    `bun:"password_hash,notnull" json:"-"`
-	DisplayName  string    `bun:"display_name,notnull" json:"displayName"`
-	CreatedAt    time.Time `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
-	UpdatedAt    time.Time `bun:"updated_at,notnull,default:current_timestamp" json:"updatedAt"`
+	ID                string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Email             string    `bun:"email,notnull,unique" json:"email"`
+	PasswordHash      string    `bun:"password_hash,notnull" json:"-"`
+	DisplayName       string    `bun:"display_name,notnull" json:"displayName"`
+	AvatarURL         string    `bun:"avatar_url,nullzero" json:"avatarUrl,omitempty"`
+	EmailVerifiedAt   time.Time `bun:"email_verified_at,nullzero" json:"emailVerifiedAt,omitempty"`
+	SSOOnly           bool      `bun:"sso_only,notnull" json:"ssoOnly"` // created through single sign-on, no password the user knows
+	TOTPSecret        string    `bun:"totp_secret,nullzero" json:"-"`
+	TOTPEnabledAt     time.Time `bun:"totp_enabled_at,nullzero" json:"totpEnabledAt,omitempty"`
+	TOTPLastStep      int64     `bun:"totp_last_step,nullzero" json:"-"`      // last accepted time step, rejects replayed codes
+	CalendarTokenHash string    `bun:"calendar_token_hash,nullzero" json:"-"` // secret of the calendar feed URL, empty while the feed is off
+	CreatedAt         time.Time `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt         time.Time `bun:"updated_at,notnull,default:current_timestamp" json:"updatedAt"`
+}
+
+func (u *User) IsEmailVerified() bool {
+	return !u.EmailVerifiedAt.IsZero()
+}
+
+func (u *User) IsMFAEnabled() bool {
+	return !u.TOTPEnabledAt.IsZero()
+}
+
+// UserIdentity links a user to an account at an external identity provider
+type UserIdentity struct {
+	bun.BaseModel `bun:"table:user_identities,alias:ui"`
+
+	ID          string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	UserID      string    `bun:"user_id,notnull" json:"userId"`
+	Provider    string    `bun:"provider,notnull" json:"provider"`
+	Subject     string    `bun:"subject,notnull" json:"subject"`
+	Email       string    `bun:"email" json:"email,omitempty"`
+	CreatedAt   time.Time `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
+	LastLoginAt time.Time `bun:"last_login_at,nullzero" json:"lastLoginAt,omitempty"`
+
+	// Relations
+	User *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+}
+
+type MFARecoveryCode struct {
+	bun.BaseModel `bun:"table:mfa_recovery_codes,alias:mrc"`
+
+	ID        string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	UserID    string    `bun:"user_id,notnull" json:"userId"`
+	CodeHash  string    `bun:"code_hash,notnull" json:"-"`
+	UsedAt    time.Time `bun:"used_at,nullzero" json:"usedAt,omitempty"`
+	CreatedAt time.Time `bun:"created_at,notnull,default:current_timestamp" json:"createdAt"`
 }
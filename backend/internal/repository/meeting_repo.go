@@ -2,8 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"html"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/uptrace/bun"
 
 	"github.com/meetia/backend/internal/models"
@@ -41,7 +46,10 @@ func (r *MeetingRepository) GetByCode(ctx context.Context, code string) (*models
 	err := r.db.NewSelect().
 		Model(meeting).
 		Relation("Host").
-		Where("meeting_code = ?", code).
+		// codes are stored upper case, so vanity codes match whatever case they are typed in
+		Where("meeting_code = upper(?)", code).
+		// occurrences share the code of their series, which resolves them
+		Where("series_id IS NULL").
 		Scan(ctx)
 
 	if err != nil {
@@ -50,25 +58,233 @@ func (r *MeetingRepository) GetByCode(ctx context.Context, code string) (*models
 	return meeting, nil
 }
 
-func (r *MeetingRepository) GetActiveForUser(ctx context.Context, userID string) ([]*models.Meeting, error) {
-	var meetings []*models.Meeting
+// GetPersonalMeeting returns the personal meeting of a user
+func (r *MeetingRepository) GetPersonalMeeting(ctx context.Context, hostID string) (*models.Meeting, error) {
+	meeting := new(models.Meeting)
 	err := r.db.NewSelect().
-		Model(&meetings).
+		Model(meeting).
 		Relation("Host").
-		Where("host_id = ? OR m.id IN (SELECT meeting_id FROM meeting_participants WHERE user_id = ?)", userID, userID).
-		Where("ended_at IS NULL").
-		Order("created_at DESC").
+		Where("m.host_id = ?", hostID).
+		Where("m.personal").
 		Scan(ctx)
 
 	if err != nil {
 		return nil, err
 	}
-	return meetings, nil
+	return meeting, nil
+}
+
+// CreatePersonalMeeting stores a personal meeting with its host as participant. It reports false
+// when a concurrent request created the user's personal meeting first, nothing is inserted then.
+func (r *MeetingRepository) CreatePersonalMeeting(ctx context.Context, meeting *models.Meeting, host *models.MeetingParticipant) (bool, error) {
+	created := false
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewInsert().
+			Model(meeting).
+			On("CONFLICT (host_id) WHERE personal DO NOTHING").
+			// nothing would be returned on conflict
+			Returning("NULL").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+
+		host.MeetingID = meeting.ID
+		if _, err := tx.NewInsert().Model(host).Exec(ctx); err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+// CodeInUse reports whether a meeting or a series joins with the code
+func (r *MeetingRepository) CodeInUse(ctx context.Context, code string) (bool, error) {
+	var inUse bool
+	err := r.db.NewRaw(
+		"SELECT EXISTS (SELECT 1 FROM meetings WHERE meeting_code = ? AND series_id IS NULL) OR EXISTS (SELECT 1 FROM meeting_series WHERE meeting_code = ?)",
+		code, code,
+	).Scan(ctx, &inUse)
+	return inUse, err
+}
+
+// SetMeetingCode changes the code of a meeting. It reports false when another meeting or a series
+// uses the code already.
+func (r *MeetingRepository) SetMeetingCode(ctx context.Context, meetingID, code string) (bool, error) {
+	res, err := r.db.NewUpdate().
+		Model((*models.Meeting)(nil)).
+		Set("meeting_code = ?", code).
+		Set("updated_at = ?", time.Now()).
+		Where("id = ?", meetingID).
+		Where("NOT EXISTS (SELECT 1 FROM meetings AS other WHERE other.meeting_code = ? AND other.series_id IS NULL AND other.id <> ?)", code, meetingID).
+		Where("NOT EXISTS (SELECT 1 FROM meeting_series AS ms WHERE ms.meeting_code = ?)", code).
+		Exec(ctx)
+	if isUniqueViolation(err) {
+		// a concurrent update took the code after the checks above
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// isUniqueViolation reports whether err is postgres refusing a duplicate value
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// ResetMeeting clears what a session of a meeting left behind so the next one starts fresh: everyone
+// but the host leaves, the lock and breakout rooms go away, open polls close and open questions are
+// dismissed. Chat, attendance and past polls and questions are kept.
+func (r *MeetingRepository) ResetMeeting(ctx context.Context, meetingID string, at time.Time) error {
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewUpdate().
+			Model((*models.Meeting)(nil)).
+			Set("locked = false").
+			Set("breakouts_opened_at = NULL").
+			Set("breakouts_close_at = NULL").
+			Set("updated_at = ?", at).
+			Where("id = ?", meetingID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		// assignments go with their rooms
+		_, err = tx.NewDelete().
+			Model((*models.BreakoutRoom)(nil)).
+			Where("meeting_id = ?", meetingID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewDelete().
+			Model((*models.MeetingParticipant)(nil)).
+			Where("meeting_id = ?", meetingID).
+			Where("user_id IS DISTINCT FROM (SELECT host_id FROM meetings WHERE id = ?)", meetingID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*models.MeetingPoll)(nil)).
+			Set("status = ?", models.PollClosed).
+			Set("closed_at = ?", at).
+			Where("meeting_id = ?", meetingID).
+			Where("status = ?", models.PollOpen).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*models.MeetingQuestion)(nil)).
+			Set("status = ?", models.QuestionDismissed).
+			Set("resolved_at = ?", at).
+			Where("meeting_id = ?", meetingID).
+			Where("status = ?", models.QuestionOpen).
+			Exec(ctx)
+		return err
+	})
+}
+
+// MeetingFilter narrows down the meetings of a user, zero fields don't filter
+type MeetingFilter struct {
+	Status models.MeetingStatus
+	// ActiveOnly returns upcoming and live meetings, it is ignored when Status is set
+	ActiveOnly bool
+	// From and To bound the scheduled start, meetings without a schedule use their creation time
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// ListForUser returns the meetings the user hosts or took part in
+func (r *MeetingRepository) ListForUser(ctx context.Context, userID string, filter MeetingFilter) ([]*models.Meeting, int, error) {
+	now := time.Now()
+	var meetings []*models.Meeting
+	query := r.db.NewSelect().
+		Model(&meetings).
+		Relation("Host").
+		Where("m.host_id = ? OR m.id IN (SELECT meeting_id FROM meeting_participants WHERE user_id = ?)", userID, userID)
+
+	switch filter.Status {
+	case models.MeetingStatusUpcoming:
+		query = query.
+			Where("m.ended_at IS NULL").
+			Where("m.scheduled_at > ?", now).
+			OrderExpr("m.scheduled_at ASC")
+	case models.MeetingStatusLive:
+		query = query.
+			Where("m.ended_at IS NULL").
+			Where("m.scheduled_at IS NULL OR m.scheduled_at <= ?", now).
+			OrderExpr("m.created_at DESC")
+	case models.MeetingStatusPast:
+		query = query.
+			Where("m.ended_at IS NOT NULL").
+			OrderExpr("m.ended_at DESC")
+	default:
+		if filter.ActiveOnly {
+			query = query.Where("m.ended_at IS NULL")
+		}
+		query = query.OrderExpr("COALESCE(m.scheduled_at, m.created_at) DESC")
+	}
+
+	if !filter.From.IsZero() {
+		query = query.Where("COALESCE(m.scheduled_at, m.created_at) >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("COALESCE(m.scheduled_at, m.created_at) < ?", filter.To)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit).Offset(filter.Offset)
+	}
+
+	total, err := query.ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return meetings, total, nil
+}
+
+// EndOverrunMeetings ends scheduled meetings still running grace after their planned end
+func (r *MeetingRepository) EndOverrunMeetings(ctx context.Context, grace time.Duration) ([]string, error) {
+	now := time.Now()
+	var ids []string
+	err := r.db.NewUpdate().
+		Model((*models.Meeting)(nil)).
+		Set("ended_at = ?", now).
+		Set("updated_at = ?", now).
+		Where("ended_at IS NULL").
+		Where("scheduled_at IS NOT NULL").
+		Where("scheduled_at + make_interval(mins => COALESCE(duration_minutes, 0)) < ?", now.Add(-grace)).
+		Returning("id").
+		Scan(ctx, &ids)
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
 }
 
 func (r *MeetingRepository) Update(ctx context.Context, meeting *models.Meeting) error {
 	meeting.UpdatedAt = time.Now()
-	_, err := r.db.NewUpdate().Model(meeting).Where("id = ?", meeting.ID).Exec(ctx)
+	_, err := r.db.NewUpdate().
+		Model(meeting).
+		// breakout state changes on its own, see OpenBreakouts
+		ExcludeColumn("breakouts_opened_at", "breakouts_close_at").
+		Where("id = ?", meeting.ID).
+		Exec(ctx)
 	return err
 }
 
@@ -83,6 +299,769 @@ func (r *MeetingRepository) EndMeeting(ctx context.Context, id string) error {
 	return err
 }
 
+func (r *MeetingRepository) CreateSeries(ctx context.Context, series *models.MeetingSeries) error {
+	_, err := r.db.NewInsert().Model(series).Exec(ctx)
+	return err
+}
+
+func (r *MeetingRepository) GetSeriesByID(ctx context.Context, id string) (*models.MeetingSeries, error) {
+	series := new(models.MeetingSeries)
+	err := r.db.NewSelect().
+		Model(series).
+		Relation("Host").
+		Where("ms.id = ?", id).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+func (r *MeetingRepository) GetSeriesByCode(ctx context.Context, code string) (*models.MeetingSeries, error) {
+	series := new(models.MeetingSeries)
+	err := r.db.NewSelect().
+		Model(series).
+		Relation("Host").
+		Where("ms.meeting_code = upper(?)", code).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// ListSeriesForUser returns the series the user hosts or joined an occurrence of
+func (r *MeetingRepository) ListSeriesForUser(ctx context.Context, userID string, activeOnly bool) ([]*models.MeetingSeries, error) {
+	var series []*models.MeetingSeries
+	query := r.db.NewSelect().
+		Model(&series).
+		Relation("Host").
+		Where(
+			"ms.host_id = ? OR ms.id IN (SELECT m.series_id FROM meetings AS m JOIN meeting_participants AS mp ON mp.meeting_id = m.id WHERE mp.user_id = ?)",
+			userID, userID,
+		).
+		OrderExpr("ms.starts_at ASC")
+	if activeOnly {
+		query = query.Where("ms.ended_at IS NULL")
+	}
+
+	if err := query.Scan(ctx); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// HasJoinedSeries reports whether the user took part in an occurrence of the series
+func (r *MeetingRepository) HasJoinedSeries(ctx context.Context, seriesID, userID string) (bool, error) {
+	return r.db.NewSelect().
+		TableExpr("meeting_participants AS mp").
+		Join("JOIN meetings AS m ON m.id = mp.meeting_id").
+		Where("m.series_id = ?", seriesID).
+		Where("mp.user_id = ?", userID).
+		Exists(ctx)
+}
+
+// UpdateSeries saves the series and copies its title and join settings to the occurrences
+// that haven't ended, past occurrences keep the settings they ran with
+func (r *MeetingRepository) UpdateSeries(ctx context.Context, series *models.MeetingSeries) error {
+	series.UpdatedAt = time.Now()
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewUpdate().Model(series).Where("id = ?", series.ID).Exec(ctx); err != nil {
+			return err
+		}
+
+		_, err := tx.NewUpdate().
+			Model((*models.Meeting)(nil)).
+			Set("title = ?", series.Title).
+			Set("meeting_code = ?", series.MeetingCode).
+			Set("password_hash = ?", bun.NullZero(series.PasswordHash)).
+			Set("is_private = ?", series.IsPrivate).
+			Set("updated_at = ?", series.UpdatedAt).
+			Where("series_id = ?", series.ID).
+			Where("ended_at IS NULL").
+			Exec(ctx)
+		return err
+	})
+}
+
+// EndSeries stops the series and ends its occurrences that are still open
+func (r *MeetingRepository) EndSeries(ctx context.Context, id string) error {
+	now := time.Now()
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewUpdate().
+			Model((*models.MeetingSeries)(nil)).
+			Set("ended_at = ?", now).
+			Set("updated_at = ?", now).
+			Where("id = ?", id).
+			Where("ended_at IS NULL").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*models.Meeting)(nil)).
+			Set("ended_at = ?", now).
+			Set("updated_at = ?", now).
+			Where("series_id = ?", id).
+			Where("ended_at IS NULL").
+			Exec(ctx)
+		return err
+	})
+}
+
+// CreateOccurrence stores the meeting of a series occurrence. It reports false when a
+// concurrent join stored the same occurrence first, the meeting is not inserted then.
+func (r *MeetingRepository) CreateOccurrence(ctx context.Context, meeting *models.Meeting) (bool, error) {
+	res, err := r.db.NewInsert().
+		Model(meeting).
+		On("CONFLICT (series_id, scheduled_at) DO NOTHING").
+		// nothing would be returned on conflict
+		Returning("NULL").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *MeetingRepository) GetOccurrence(ctx context.Context, seriesID string, startAt time.Time) (*models.Meeting, error) {
+	meeting := new(models.Meeting)
+	err := r.db.NewSelect().
+		Model(meeting).
+		Relation("Host").
+		Where("m.series_id = ?", seriesID).
+		Where("m.scheduled_at = ?", startAt).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return meeting, nil
+}
+
+// ListOccurrences returns the stored occurrences of a series starting in [from, to)
+func (r *MeetingRepository) ListOccurrences(ctx context.Context, seriesID string, from, to time.Time) ([]*models.Meeting, error) {
+	var meetings []*models.Meeting
+	err := r.db.NewSelect().
+		Model(&meetings).
+		Where("m.series_id = ?", seriesID).
+		Where("m.scheduled_at >= ?", from).
+		Where("m.scheduled_at < ?", to).
+		OrderExpr("m.scheduled_at ASC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return meetings, nil
+}
+
+// CreateInvitation stores an invitation, it reports false when the address was already invited
+func (r *MeetingRepository) CreateInvitation(ctx context.Context, invitation *models.MeetingInvitation) (bool, error) {
+	res, err := r.db.NewInsert().
+		Model(invitation).
+		On("CONFLICT (meeting_id, email) DO NOTHING").
+		Returning("NULL").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *MeetingRepository) GetInvitation(ctx context.Context, id string) (*models.MeetingInvitation, error) {
+	invitation := new(models.MeetingInvitation)
+	err := r.db.NewSelect().
+		Model(invitation).
+		Relation("Meeting").
+		Relation("Meeting.Host").
+		Relation("Inviter").
+		Where("mi.id = ?", id).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// ListInvitations returns the invitations of a meeting, for its host
+func (r *MeetingRepository) ListInvitations(ctx context.Context, meetingID string) ([]*models.MeetingInvitation, error) {
+	var invitations []*models.MeetingInvitation
+	err := r.db.NewSelect().
+		Model(&invitations).
+		Relation("User").
+		Where("mi.meeting_id = ?", meetingID).
+		OrderExpr("mi.created_at ASC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// FindInvitation returns the invitation of a meeting addressed to the user's account or email address
+func (r *MeetingRepository) FindInvitation(ctx context.Context, meetingID, userID, email string) (*models.MeetingInvitation, error) {
+	invitation := new(models.MeetingInvitation)
+	err := r.db.NewSelect().
+		Model(invitation).
+		Where("mi.meeting_id = ?", meetingID).
+		Apply(invitedUser(userID, email)).
+		OrderExpr("mi.user_id IS NULL").
+		Limit(1).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// invitedUser matches invitations to the user's account or, when email isn't empty, their address
+func invitedUser(userID, email string) func(*bun.SelectQuery) *bun.SelectQuery {
+	return func(q *bun.SelectQuery) *bun.SelectQuery {
+		if email == "" {
+			return q.Where("mi.user_id = ?", userID)
+		}
+		return q.Where("mi.user_id = ? OR mi.email = lower(?)", userID, email)
+	}
+}
+
+// ListInvitationsForUser returns the invitations addressed to the user's account or email address,
+// newest first. An empty status returns all of them.
+func (r *MeetingRepository) ListInvitationsForUser(ctx context.Context, userID, email string, status models.InvitationStatus) ([]*models.MeetingInvitation, error) {
+	var invitations []*models.MeetingInvitation
+	query := r.db.NewSelect().
+		Model(&invitations).
+		Relation("Meeting").
+		Relation("Meeting.Host").
+		Relation("Inviter").
+		Apply(invitedUser(userID, email)).
+		OrderExpr("mi.created_at DESC")
+	if status != "" {
+		query = query.Where("mi.status = ?", status)
+	}
+
+	if err := query.Scan(ctx); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (r *MeetingRepository) UpdateInvitation(ctx context.Context, invitation *models.MeetingInvitation) error {
+	_, err := r.db.NewUpdate().Model(invitation).ExcludeColumn("created_at").Where("id = ?", invitation.ID).Exec(ctx)
+	return err
+}
+
+// DeleteInvitation withdraws an invitation of the meeting, it reports false if there was no such invitation
+func (r *MeetingRepository) DeleteInvitation(ctx context.Context, id, meetingID string) (bool, error) {
+	res, err := r.db.NewDelete().
+		Model((*models.MeetingInvitation)(nil)).
+		Where("id = ?", id).
+		Where("meeting_id = ?", meetingID).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *MeetingRepository) CreateBreakoutRooms(ctx context.Context, rooms []*models.BreakoutRoom) error {
+	_, err := r.db.NewInsert().Model(&rooms).Exec(ctx)
+	return err
+}
+
+// ListBreakoutRooms returns the breakout rooms of a meeting in order, with who is assigned to them
+func (r *MeetingRepository) ListBreakoutRooms(ctx context.Context, meetingID string) ([]*models.BreakoutRoom, error) {
+	var rooms []*models.BreakoutRoom
+	err := r.db.NewSelect().
+		Model(&rooms).
+		Relation("Assignments", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.OrderExpr("ba.assigned_at ASC")
+		}).
+		Relation("Assignments.User").
+		Where("br.meeting_id = ?", meetingID).
+		OrderExpr("br.position ASC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return rooms, nil
+}
+
+// DeleteBreakoutRoom removes a breakout room of the meeting and its assignments, it reports false if there was no such room
+func (r *MeetingRepository) DeleteBreakoutRoom(ctx context.Context, id, meetingID string) (bool, error) {
+	res, err := r.db.NewDelete().
+		Model((*models.BreakoutRoom)(nil)).
+		Where("id = ?", id).
+		Where("meeting_id = ?", meetingID).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// AssignBreakouts puts users into breakout rooms and takes others out of theirs. With replace,
+// everyone not in assignments is taken out.
+func (r *MeetingRepository) AssignBreakouts(ctx context.Context, meetingID string, assignments []*models.BreakoutAssignment, unassign []string, replace bool) error {
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if replace || len(unassign) > 0 {
+			query := tx.NewDelete().
+				Model((*models.BreakoutAssignment)(nil)).
+				Where("meeting_id = ?", meetingID)
+			if !replace {
+				query = query.Where("user_id IN (?)", bun.In(unassign))
+			}
+			if _, err := query.Exec(ctx); err != nil {
+				return err
+			}
+		}
+
+		if len(assignments) == 0 {
+			return nil
+		}
+		_, err := tx.NewInsert().
+			Model(&assignments).
+			On("CONFLICT (meeting_id, user_id) DO UPDATE").
+			Set("room_id = EXCLUDED.room_id").
+			Set("assigned_at = EXCLUDED.assigned_at").
+			Exec(ctx)
+		return err
+	})
+}
+
+// OpenBreakouts marks the breakout rooms of a meeting open, with a zero closeAt they stay open until
+// closed. It reports false when they were open already.
+func (r *MeetingRepository) OpenBreakouts(ctx context.Context, meetingID string, openedAt, closeAt time.Time) (bool, error) {
+	res, err := r.db.NewUpdate().
+		Model((*models.Meeting)(nil)).
+		Set("breakouts_opened_at = ?", openedAt).
+		Set("breakouts_close_at = ?", bun.NullZero(closeAt)).
+		Where("id = ?", meetingID).
+		Where("breakouts_opened_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ScheduleBreakoutsClose sets when the open breakout rooms of a meeting close, it reports false when they aren't open
+func (r *MeetingRepository) ScheduleBreakoutsClose(ctx context.Context, meetingID string, closeAt time.Time) (bool, error) {
+	res, err := r.db.NewUpdate().
+		Model((*models.Meeting)(nil)).
+		Set("breakouts_close_at = ?", closeAt).
+		Where("id = ?", meetingID).
+		Where("breakouts_opened_at IS NOT NULL").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FinishBreakouts closes the breakout rooms of a meeting if they are still due to close at closeAt.
+// It reports false when they were closed already or rescheduled meanwhile.
+func (r *MeetingRepository) FinishBreakouts(ctx context.Context, meetingID string, closeAt time.Time) (bool, error) {
+	res, err := r.db.NewUpdate().
+		Model((*models.Meeting)(nil)).
+		Set("breakouts_opened_at = NULL").
+		Set("breakouts_close_at = NULL").
+		Where("id = ?", meetingID).
+		Where("breakouts_close_at = ?", closeAt).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListDueBreakouts returns the meetings whose breakout rooms should have closed by now, with their close time
+func (r *MeetingRepository) ListDueBreakouts(ctx context.Context, now time.Time) ([]*models.Meeting, error) {
+	var meetings []*models.Meeting
+	err := r.db.NewSelect().
+		Model(&meetings).
+		Column("id", "breakouts_close_at").
+		Where("breakouts_close_at <= ?", now).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return meetings, nil
+}
+
+// CreatePoll stores a poll with its options
+func (r *MeetingRepository) CreatePoll(ctx context.Context, poll *models.MeetingPoll) error {
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(poll).Exec(ctx); err != nil {
+			return err
+		}
+		_, err := tx.NewInsert().Model(&poll.Options).Exec(ctx)
+		return err
+	})
+}
+
+// GetPoll returns a poll of the meeting with its options and votes
+func (r *MeetingRepository) GetPoll(ctx context.Context, id, meetingID string) (*models.MeetingPoll, error) {
+	poll := new(models.MeetingPoll)
+	err := r.db.NewSelect().
+		Model(poll).
+		Relation("Options", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.OrderExpr("plo.position ASC")
+		}).
+		Relation("Votes", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.OrderExpr("plv.voted_at ASC")
+		}).
+		Relation("Votes.User").
+		Where("pl.id = ?", id).
+		Where("pl.meeting_id = ?", meetingID).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return poll, nil
+}
+
+// ListPolls returns the polls of a meeting with their options and votes, oldest first
+func (r *MeetingRepository) ListPolls(ctx context.Context, meetingID string) ([]*models.MeetingPoll, error) {
+	var polls []*models.MeetingPoll
+	err := r.db.NewSelect().
+		Model(&polls).
+		Relation("Options", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.OrderExpr("plo.position ASC")
+		}).
+		Relation("Votes", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.OrderExpr("plv.voted_at ASC")
+		}).
+		Relation("Votes.User").
+		Where("pl.meeting_id = ?", meetingID).
+		OrderExpr("pl.created_at ASC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return polls, nil
+}
+
+// SetPollStatus moves a poll of the meeting from one status to the next, stamping when it opened or closed.
+// It reports false when the poll wasn't in the from status.
+func (r *MeetingRepository) SetPollStatus(ctx context.Context, id, meetingID string, from, to models.PollStatus, at time.Time) (bool, error) {
+	query := r.db.NewUpdate().
+		Model((*models.MeetingPoll)(nil)).
+		Set("status = ?", to).
+		Where("id = ?", id).
+		Where("meeting_id = ?", meetingID).
+		Where("status = ?", from)
+	switch to {
+	case models.PollOpen:
+		query = query.Set("opened_at = ?", at)
+	case models.PollClosed:
+		query = query.Set("closed_at = ?", at)
+	}
+
+	res, err := query.Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ReplaceVotes swaps a user's votes on a poll for new ones, it reports false when the poll isn't open
+func (r *MeetingRepository) ReplaceVotes(ctx context.Context, pollID, userID string, votes []*models.MeetingPollVote) (bool, error) {
+	open := false
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// lock the poll so it can't close halfway through the vote
+		var status models.PollStatus
+		err := tx.NewSelect().
+			Model((*models.MeetingPoll)(nil)).
+			Column("status").
+			Where("id = ?", pollID).
+			For("SHARE").
+			Scan(ctx, &status)
+		if err != nil || status != models.PollOpen {
+			return err
+		}
+		open = true
+
+		_, err = tx.NewDelete().
+			Model((*models.MeetingPollVote)(nil)).
+			Where("poll_id = ?", pollID).
+			Where("user_id = ?", userID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = tx.NewInsert().Model(&votes).Exec(ctx)
+		return err
+	})
+	return open, err
+}
+
+func (r *MeetingRepository) CreateQuestion(ctx context.Context, question *models.MeetingQuestion) error {
+	_, err := r.db.NewInsert().Model(question).Exec(ctx)
+	return err
+}
+
+// GetQuestion returns a question of the meeting with its author and upvotes
+func (r *MeetingRepository) GetQuestion(ctx context.Context, id, meetingID string) (*models.MeetingQuestion, error) {
+	question := new(models.MeetingQuestion)
+	err := r.db.NewSelect().
+		Model(question).
+		ColumnExpr("mq.*").
+		ColumnExpr("(SELECT count(*) FROM meeting_question_votes AS mqv WHERE mqv.question_id = mq.id) AS upvotes").
+		Relation("User").
+		Where("mq.id = ?", id).
+		Where("mq.meeting_id = ?", meetingID).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+// ListQuestions returns the Q&A board of a meeting, most upvoted first and oldest first among equals.
+// Dismissed questions are left out unless asked for.
+func (r *MeetingRepository) ListQuestions(ctx context.Context, meetingID string, withDismissed bool) ([]*models.MeetingQuestion, error) {
+	var questions []*models.MeetingQuestion
+	query := r.db.NewSelect().
+		Model(&questions).
+		ColumnExpr("mq.*").
+		ColumnExpr("(SELECT count(*) FROM meeting_question_votes AS mqv WHERE mqv.question_id = mq.id) AS upvotes").
+		Relation("User").
+		Where("mq.meeting_id = ?", meetingID).
+		OrderExpr("upvotes DESC, mq.created_at ASC")
+	if !withDismissed {
+		query = query.Where("mq.status <> ?", models.QuestionDismissed)
+	}
+
+	if err := query.Scan(ctx); err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+// ListUpvotedQuestions returns the ids of the questions of a meeting the user upvoted
+func (r *MeetingRepository) ListUpvotedQuestions(ctx context.Context, meetingID, userID string) ([]string, error) {
+	var ids []string
+	err := r.db.NewSelect().
+		Model((*models.MeetingQuestionVote)(nil)).
+		Column("mqv.question_id").
+		Join("JOIN meeting_questions AS mq ON mq.id = mqv.question_id").
+		Where("mq.meeting_id = ?", meetingID).
+		Where("mqv.user_id = ?", userID).
+		Scan(ctx, &ids)
+
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// UpvoteQuestion records the user's upvote, it reports false when they had upvoted already
+func (r *MeetingRepository) UpvoteQuestion(ctx context.Context, questionID, userID string) (bool, error) {
+	res, err := r.db.NewInsert().
+		Model(&models.MeetingQuestionVote{QuestionID: questionID, UserID: userID, CreatedAt: time.Now()}).
+		On("CONFLICT DO NOTHING").
+		Returning("NULL").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RemoveUpvote takes back the user's upvote, it reports false when there was none
+func (r *MeetingRepository) RemoveUpvote(ctx context.Context, questionID, userID string) (bool, error) {
+	res, err := r.db.NewDelete().
+		Model((*models.MeetingQuestionVote)(nil)).
+		Where("question_id = ?", questionID).
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// SetQuestionStatus marks a question of the meeting answered, dismissed or open again
+func (r *MeetingRepository) SetQuestionStatus(ctx context.Context, id, meetingID string, status models.QuestionStatus, resolvedAt time.Time) (bool, error) {
+	res, err := r.db.NewUpdate().
+		Model((*models.MeetingQuestion)(nil)).
+		Set("status = ?", status).
+		Set("resolved_at = ?", bun.NullZero(resolvedAt)).
+		Where("id = ?", id).
+		Where("meeting_id = ?", meetingID).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// StartAttendance opens an attendance session for the user or guest unless one is open already, which
+// happens when a connection is replaced before the old one went away. It reports whether it opened one.
+func (r *MeetingRepository) StartAttendance(ctx context.Context, meetingID, memberID string, at time.Time) (bool, error) {
+	started := false
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		open, err := tx.NewSelect().
+			Model((*models.AttendanceSession)(nil)).
+			Where("meeting_id = ?", meetingID).
+			Where("(user_id = ? OR guest_id = ?)", memberID, memberID).
+			Where("left_at IS NULL").
+			Exists(ctx)
+		if err != nil || open {
+			return err
+		}
+
+		guest, err := tx.NewSelect().
+			Model((*models.MeetingGuest)(nil)).
+			Where("id = ?", memberID).
+			Where("meeting_id = ?", meetingID).
+			Exists(ctx)
+		if err != nil {
+			return err
+		}
+
+		session := &models.AttendanceSession{MeetingID: meetingID, UserID: memberID, JoinedAt: at}
+		if guest {
+			session.UserID, session.GuestID = "", memberID
+		}
+		if _, err := tx.NewInsert().Model(session).Exec(ctx); err != nil {
+			return err
+		}
+		started = true
+
+		_, err = tx.NewUpdate().
+			Model((*models.MeetingParticipant)(nil)).
+			Set("left_at = NULL").
+			Where("meeting_id = ?", meetingID).
+			Where("(user_id = ? OR guest_id = ?)", memberID, memberID).
+			Exec(ctx)
+		return err
+	})
+	return started, err
+}
+
+// EndAttendance closes the open attendance sessions of a meeting, only the user's or guest's unless
+// memberID is empty, and records when the participants left
+func (r *MeetingRepository) EndAttendance(ctx context.Context, meetingID, memberID string, at time.Time) error {
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var memberIDs []string
+		query := tx.NewUpdate().
+			Model((*models.AttendanceSession)(nil)).
+			Set("left_at = ?", at).
+			Where("meeting_id = ?", meetingID).
+			Where("left_at IS NULL")
+		if memberID != "" {
+			query = query.Where("(user_id = ? OR guest_id = ?)", memberID, memberID)
+		}
+		if err := query.Returning("COALESCE(user_id, guest_id)").Scan(ctx, &memberIDs); err != nil {
+			return err
+		}
+		memberIDs = slices.DeleteFunc(memberIDs, func(id string) bool { return id == "" })
+		if len(memberIDs) == 0 {
+			return nil
+		}
+
+		_, err := tx.NewUpdate().
+			Model((*models.MeetingParticipant)(nil)).
+			Set("left_at = ?", at).
+			Where("meeting_id = ?", meetingID).
+			Where("(user_id IN (?) OR guest_id IN (?))", bun.In(memberIDs), bun.In(memberIDs)).
+			Exec(ctx)
+		return err
+	})
+}
+
+// EndStaleAttendance closes every session still open, for when the server starts without connections
+func (r *MeetingRepository) EndStaleAttendance(ctx context.Context, at time.Time) (int, error) {
+	res, err := r.db.NewUpdate().
+		Model((*models.AttendanceSession)(nil)).
+		Set("left_at = ?", at).
+		Where("left_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// ListAttendance returns the attendance sessions of a meeting in the order they started
+func (r *MeetingRepository) ListAttendance(ctx context.Context, meetingID string) ([]*models.AttendanceSession, error) {
+	var sessions []*models.AttendanceSession
+	err := r.db.NewSelect().
+		Model(&sessions).
+		Relation("User").
+		Relation("Guest").
+		Where("ats.meeting_id = ?", meetingID).
+		OrderExpr("ats.joined_at ASC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *MeetingRepository) GetGuest(ctx context.Context, id string) (*models.MeetingGuest, error) {
+	guest := new(models.MeetingGuest)
+	err := r.db.NewSelect().
+		Model(guest).
+		Where("mg.id = ?", id).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return guest, nil
+}
+
+// AddGuest stores a guest and makes them a participant of the meeting
+func (r *MeetingRepository) AddGuest(ctx context.Context, guest *models.MeetingGuest, participant *models.MeetingParticipant) error {
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(guest).Exec(ctx); err != nil {
+			return err
+		}
+		participant.GuestID = guest.ID
+		_, err := tx.NewInsert().Model(participant).Exec(ctx)
+		return err
+	})
+}
+
 func (r *MeetingRepository) AddParticipant(ctx context.Context, participant *models.MeetingParticipant) error {
 	_, err := r.db.NewInsert().Model(participant).Exec(ctx)
 	return err
@@ -98,7 +1077,92 @@ func (r *MeetingRepository) GetParticipants(ctx context.Context, meetingID strin
 	err := r.db.NewSelect().
 		Model(&participants).
 		Relation("User").
+		Relation("Guest").
+		Where("mp.meeting_id = ?", meetingID).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return participants, nil
+}
+
+// GetParticipant looks up a participant by user id or guest id
+func (r *MeetingRepository) GetParticipant(ctx context.Context, meetingID, memberID string) (*models.MeetingParticipant, error) {
+	participant := new(models.MeetingParticipant)
+	err := r.db.NewSelect().
+		Model(participant).
+		Where("meeting_id = ?", meetingID).
+		Where("(user_id = ? OR guest_id = ?)", memberID, memberID).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return participant, nil
+}
+
+// SetParticipantRole changes the role of a user admitted to the meeting, it reports false if there is no such participant
+func (r *MeetingRepository) SetParticipantRole(ctx context.Context, meetingID, userID string, role models.ParticipantRole) (bool, error) {
+	res, err := r.db.NewUpdate().
+		Model((*models.MeetingParticipant)(nil)).
+		Set("role = ?", role).
 		Where("meeting_id = ?", meetingID).
+		Where("user_id = ?", userID).
+		Where("status = ?", models.ParticipantAdmitted).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// TransferHost makes another participant the host of the meeting, the previous host stays on as co-host.
+// It reports false when fromUserID is no longer the host, e.g. after a concurrent transfer.
+func (r *MeetingRepository) TransferHost(ctx context.Context, meetingID, fromUserID, toUserID string) (bool, error) {
+	transferred := false
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewUpdate().
+			Model((*models.Meeting)(nil)).
+			Set("host_id = ?", toUserID).
+			Set("updated_at = ?", time.Now()).
+			Where("id = ?", meetingID).
+			Where("host_id = ?", fromUserID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*models.MeetingParticipant)(nil)).
+			Set("role = CASE WHEN user_id = ? THEN ? ELSE ? END", toUserID, models.MeetingParticipantHost, models.MeetingParticipantCoHost).
+			Where("meeting_id = ?", meetingID).
+			Where("user_id IN (?, ?)", fromUserID, toUserID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		transferred = true
+		return nil
+	})
+	return transferred, err
+}
+
+// ListParticipantsByStatus returns the participants of a meeting with the status, longest waiting first
+func (r *MeetingRepository) ListParticipantsByStatus(ctx context.Context, meetingID string, status models.ParticipantStatus) ([]*models.MeetingParticipant, error) {
+	var participants []*models.MeetingParticipant
+	err := r.db.NewSelect().
+		Model(&participants).
+		Relation("User").
+		Relation("Guest").
+		Where("mp.meeting_id = ?", meetingID).
+		Where("mp.status = ?", status).
+		OrderExpr("mp.joined_at ASC").
 		Scan(ctx)
 
 	if err != nil {
@@ -107,6 +1171,25 @@ func (r *MeetingRepository) GetParticipants(ctx context.Context, meetingID strin
 	return participants, nil
 }
 
+// SetParticipantStatus moves participants of a meeting from one status to another, all of them
+// when memberIDs is nil. It returns the users and guests that changed, others keep the status they had.
+func (r *MeetingRepository) SetParticipantStatus(ctx context.Context, meetingID string, memberIDs []string, from, to models.ParticipantStatus) ([]string, error) {
+	query := r.db.NewUpdate().
+		Model((*models.MeetingParticipant)(nil)).
+		Set("status = ?", to).
+		Where("meeting_id = ?", meetingID).
+		Where("status = ?", from)
+	if memberIDs != nil {
+		query = query.Where("(user_id IN (?) OR guest_id IN (?))", bun.In(memberIDs), bun.In(memberIDs))
+	}
+
+	var changed []string
+	if err := query.Returning("COALESCE(user_id, guest_id)").Scan(ctx, &changed); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
 func (r *MeetingRepository) SaveChat(ctx context.Context, chat *models.MeetingChat) error {
 	_, err := r.db.NewInsert().Model(chat).Exec(ctx)
 	return err
@@ -117,8 +1200,9 @@ func (r *MeetingRepository) GetChatHistory(ctx context.Context, meetingID string
 	err := r.db.NewSelect().
 		Model(&chats).
 		Relation("User").
-		Where("meeting_id = ?", meetingID).
-		Order("sent_at ASC").
+		Relation("Guest").
+		Where("mc.meeting_id = ?", meetingID).
+		Order("mc.sent_at ASC").
 		Scan(ctx)
 
 	if err != nil {
@@ -126,3 +1210,64 @@ func (r *MeetingRepository) GetChatHistory(ctx context.Context, meetingID string
 	}
 	return chats, nil
 }
+
+// SearchChats runs a full-text search over the chats of meetings the user hosted or was admitted to.
+// It returns one page of results ordered by relevance and the total number of matches.
+func (r *MeetingRepository) SearchChats(ctx context.Context, userID, query string, limit, offset int) ([]*models.ChatSearchResult, int, error) {
+	baseQuery := func() *bun.SelectQuery {
+		return r.db.NewSelect().
+			TableExpr("meeting_chats AS mc").
+			Where("mc.search_vector @@ websearch_to_tsquery('english', ?)", query).
+			WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+				return q.
+					Where("mc.meeting_id IN (SELECT meeting_id FROM meeting_participants WHERE user_id = ? AND status = ?)", userID, models.ParticipantAdmitted).
+					WhereOr("mc.meeting_id IN (SELECT id FROM meetings WHERE host_id = ?)", userID)
+			})
+	}
+
+	total, err := baseQuery().Count(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var results []*models.ChatSearchResult
+	err = baseQuery().
+		Join("JOIN meetings AS m ON m.id = mc.meeting_id").
+		Join("LEFT JOIN users AS u ON u.id = mc.user_id").
+		Join("LEFT JOIN meeting_guests AS g ON g.id = mc.guest_id").
+		ColumnExpr("mc.id, mc.meeting_id, mc.user_id, mc.sent_at").
+		ColumnExpr("m.title AS meeting_title").
+		ColumnExpr("COALESCE(u.display_name, g.display_name, ?) AS display_name", models.DeletedUserDisplayName).
+		ColumnExpr(
+			"ts_headline('english', translate(mc.message, ?, ''), websearch_to_tsquery('english', ?), ?) AS snippet",
+			snippetStartSel+snippetStopSel, query, snippetOptions,
+		).
+		ColumnExpr("ts_rank(mc.search_vector, websearch_to_tsquery('english', ?)) AS rank", query).
+		OrderExpr("rank DESC, mc.sent_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(ctx, &results)
+
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, result := range results {
+		result.Snippet = highlightSnippet(result.Snippet)
+	}
+	return results, total, nil
+}
+
+// ts_headline marks matches with control characters, they are removed from the message first
+// so only the markers ts_headline adds turn into <mark> tags
+const (
+	snippetStartSel = "\x02"
+	snippetStopSel  = "\x03"
+	snippetOptions  = `StartSel="` + snippetStartSel + `", StopSel="` + snippetStopSel + `", MaxFragments=2, MaxWords=30, MinWords=10`
+)
+
+// highlightSnippet escapes the message excerpt as HTML and wraps the matches in <mark> tags
+func highlightSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, snippetStartSel, "<mark>")
+	return strings.ReplaceAll(snippet, snippetStopSel, "</mark>")
+}
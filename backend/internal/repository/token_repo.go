@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+var errAlreadyRevoked = errors.New("refresh token already revoked")
+
+type TokenRepository struct {
+	db *bun.DB
+}
+
+func NewTokenRepository(db *bun.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+func (r *TokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	_, err := r.db.NewInsert().Model(token).Exec(ctx)
+	return err
+}
+
+func (r *TokenRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	token := new(models.RefreshToken)
+	err := r.db.NewSelect().
+		Model(token).
+		Relation("User").
+		Where("token_hash = ?", hash).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// RotateRefreshToken atomically revokes the old token and stores its replacement.
+// It returns false if the old token had already been revoked by a concurrent request.
+func (r *TokenRepository) RotateRefreshToken(ctx context.Context, oldID string, replacement *models.RefreshToken) (bool, error) {
+	rotated := false
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(replacement).Exec(ctx); err != nil {
+			return err
+		}
+
+		res, err := tx.NewUpdate().
+			Model((*models.RefreshToken)(nil)).
+			Set("revoked_at = ?", time.Now()).
+			Set("replaced_by = ?", replacement.ID).
+			Where("id = ?", oldID).
+			Where("revoked_at IS NULL").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// roll back the replacement, the old token was used twice
+			return errAlreadyRevoked
+		}
+		rotated = true
+		return nil
+	})
+
+	if errors.Is(err, errAlreadyRevoked) {
+		return false, nil
+	}
+	return rotated, err
+}
+
+func (r *TokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	_, err := r.db.NewUpdate().
+		Model((*models.RefreshToken)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("family_id = ?", familyID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	return err
+}
+
+func (r *TokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	_, err := r.db.NewUpdate().
+		Model((*models.RefreshToken)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	return err
+}
+
+func (r *TokenRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	_, err := r.db.NewInsert().
+		Model(&models.RevokedToken{
+			JTI:       jti,
+			ExpiresAt: expiresAt,
+			RevokedAt: time.Now(),
+		}).
+		On("CONFLICT (jti) DO NOTHING").
+		Exec(ctx)
+	return err
+}
+
+// ConsumeToken denylists a single use token by its jti. It reports false when the token was
+// used already, concurrent calls with the same jti succeed at most once.
+func (r *TokenRepository) ConsumeToken(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	res, err := r.db.NewInsert().
+		Model(&models.RevokedToken{
+			JTI:       jti,
+			ExpiresAt: expiresAt,
+			RevokedAt: time.Now(),
+		}).
+		On("CONFLICT (jti) DO NOTHING").
+		// nothing would be returned on conflict
+		Returning("NULL").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *TokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return r.db.NewSelect().
+		Model((*models.RevokedToken)(nil)).
+		Where("jti = ?", jti).
+		Where("expires_at > ?", time.Now()).
+		Exists(ctx)
+}
+
+func (r *TokenRepository) CreateUserToken(ctx context.Context, token *models.UserToken) error {
+	_, err := r.db.NewInsert().Model(token).Exec(ctx)
+	return err
+}
+
+// ConsumeUserToken marks an unused, unexpired token as used and returns it.
+// Concurrent calls with the same token succeed at most once.
+func (r *TokenRepository) ConsumeUserToken(ctx context.Context, purpose models.UserTokenPurpose, hash string) (*models.UserToken, error) {
+	token := new(models.UserToken)
+	err := r.db.NewUpdate().
+		Model(token).
+		Set("used_at = ?", time.Now()).
+		Where("token_hash = ?", hash).
+		Where("purpose = ?", purpose).
+		Where("used_at IS NULL").
+		Where("expires_at > ?", time.Now()).
+		Returning("*").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// InvalidateUserTokens marks every outstanding token of a purpose as used, so only the newest one works
+func (r *TokenRepository) InvalidateUserTokens(ctx context.Context, userID string, purpose models.UserTokenPurpose) error {
+	_, err := r.db.NewUpdate().
+		Model((*models.UserToken)(nil)).
+		Set("used_at = ?", time.Now()).
+		Where("user_id = ?", userID).
+		Where("purpose = ?", purpose).
+		Where("used_at IS NULL").
+		Exec(ctx)
+	return err
+}
+
+// DeleteExpired removes refresh tokens and denylist entries that can no longer be used
+func (r *TokenRepository) DeleteExpired(ctx context.Context) error {
+	now := time.Now()
+	if _, err := r.db.NewDelete().
+		Model((*models.RevokedToken)(nil)).
+		Where("expires_at <= ?", now).
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	if _, err := r.db.NewDelete().
+		Model((*models.UserToken)(nil)).
+		Where("expires_at <= ?", now).
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	_, err := r.db.NewDelete().
+		Model((*models.RefreshToken)(nil)).
+		Where("expires_at <= ?", now).
+		Exec(ctx)
+	return err
+}
+
+func (r *TokenRepository) CreatePersonalToken(ctx context.Context, token *models.PersonalAccessToken) error {
+	_, err := r.db.NewInsert().Model(token).Exec(ctx)
+	return err
+}
+
+func (r *TokenRepository) ListPersonalTokens(ctx context.Context, userID string) ([]*models.PersonalAccessToken, error) {
+	var tokens []*models.PersonalAccessToken
+	err := r.db.NewSelect().
+		Model(&tokens).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *TokenRepository) GetPersonalTokenByHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error) {
+	token := new(models.PersonalAccessToken)
+	err := r.db.NewSelect().
+		Model(token).
+		Relation("User").
+		Where("pat.token_hash = ?", hash).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// DeletePersonalToken revokes a token of the user, it reports false if there was no such token
+func (r *TokenRepository) DeletePersonalToken(ctx context.Context, id, userID string) (bool, error) {
+	res, err := r.db.NewDelete().
+		Model((*models.PersonalAccessToken)(nil)).
+		Where("id = ?", id).
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// TouchPersonalToken records a use of the token, at most once per interval to spare a write per request
+func (r *TokenRepository) TouchPersonalToken(ctx context.Context, id string, interval time.Duration) error {
+	now := time.Now()
+	_, err := r.db.NewUpdate().
+		Model((*models.PersonalAccessToken)(nil)).
+		Set("last_used_at = ?", now).
+		Where("id = ?", id).
+		Where("last_used_at IS NULL OR last_used_at < ?", now.Add(-interval)).
+		Exec(ctx)
+	return err
+}
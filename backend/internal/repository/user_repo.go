@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/uptrace/bun"
 
@@ -30,6 +31,10 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User,
 	return user, nil
 }
 
+func (r *UserRepository) Exists(ctx context.Context, id string) (bool, error) {
+	return r.db.NewSelect().Model((*models.User)(nil)).Where("id = ?", id).Exists(ctx)
+}
+
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	user := new(models.User)
 	err := r.db.NewSelect().Model(user).Where("email = ?", email).Scan(ctx)
@@ -39,12 +44,139 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 	return user, nil
 }
 
+func (r *UserRepository) GetByCalendarTokenHash(ctx context.Context, hash string) (*models.User, error) {
+	user := new(models.User)
+	err := r.db.NewSelect().Model(user).Where("calendar_token_hash = ?", hash).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	_, err := r.db.NewUpdate().Model(user).Where("id = ?", user.ID).Exec(ctx)
 	return err
 }
 
+// Delete removes the user. Their personal meeting is ended first, it would otherwise stay open
+// without a host once the foreign key clears host_id.
 func (r *UserRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.NewDelete().Model((*models.User)(nil)).Where("id = ?", id).Exec(ctx)
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		now := time.Now()
+		if _, err := tx.NewUpdate().
+			Model((*models.Meeting)(nil)).
+			Set("personal = false").
+			Set("ended_at = COALESCE(ended_at, ?)", now).
+			Set("updated_at = ?", now).
+			Where("host_id = ?", id).
+			Where("personal").
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		_, err := tx.NewDelete().Model((*models.User)(nil)).Where("id = ?", id).Exec(ctx)
+		return err
+	})
+}
+
+func (r *UserRepository) GetIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	identity := new(models.UserIdentity)
+	err := r.db.NewSelect().
+		Model(identity).
+		Relation("User").
+		Where("provider = ?", provider).
+		Where("subject = ?", subject).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (r *UserRepository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	_, err := r.db.NewInsert().Model(identity).Exec(ctx)
+	return err
+}
+
+func (r *UserRepository) UpdateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	_, err := r.db.NewUpdate().Model(identity).ExcludeColumn("created_at").Where("id = ?", identity.ID).Exec(ctx)
 	return err
 }
+
+// CreateWithIdentity inserts a new user together with their first external identity
+func (r *UserRepository) CreateWithIdentity(ctx context.Context, user *models.User, identity *models.UserIdentity) error {
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(user).Exec(ctx); err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		_, err := tx.NewInsert().Model(identity).Exec(ctx)
+		return err
+	})
+}
+
+// ReplaceRecoveryCodes deletes the user's recovery codes and stores a new set
+func (r *UserRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codes []*models.MFARecoveryCode) error {
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().
+			Model((*models.MFARecoveryCode)(nil)).
+			Where("user_id = ?", userID).
+			Exec(ctx); err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		_, err := tx.NewInsert().Model(&codes).Exec(ctx)
+		return err
+	})
+}
+
+// UseRecoveryCode marks an unused recovery code as used, it reports whether a code matched
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	res, err := r.db.NewUpdate().
+		Model((*models.MFARecoveryCode)(nil)).
+		Set("used_at = ?", time.Now()).
+		Where("user_id = ?", userID).
+		Where("code_hash = ?", codeHash).
+		Where("used_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *UserRepository) CountUnusedRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	return r.db.NewSelect().
+		Model((*models.MFARecoveryCode)(nil)).
+		Where("user_id = ?", userID).
+		Where("used_at IS NULL").
+		Count(ctx)
+}
+
+// AdvanceTOTPStep records the time step of an accepted code. It fails to update when a code
+// for the same or a later step was already accepted, which stops a code from being replayed.
+func (r *UserRepository) AdvanceTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	res, err := r.db.NewUpdate().
+		Model((*models.User)(nil)).
+		Set("totp_last_step = ?", step).
+		Where("id = ?", userID).
+		Where("totp_last_step IS NULL OR totp_last_step < ?", step).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
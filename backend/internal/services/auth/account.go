@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+var (
+	ErrIncorrectPassword        = errors.New("current password is incorrect")
+	ErrPasswordRequired         = errors.New("current password is required")
+	ErrReauthenticationRequired = errors.New("sign in again to confirm this change")
+	ErrUserNotFound             = errors.New("user not found")
+)
+
+// AuthTimeClaim is when the user logged in, access tokens refreshed from the same login keep it
+const AuthTimeClaim = "auth_time"
+
+// reauthenticationWindow is how recent a login has to be to confirm a change without a password
+const reauthenticationWindow = 5 * time.Minute
+
+// Confirmation proves a sensitive account change is made by the account owner. Accounts with a
+// password confirm with it, accounts created through single sign-on with a recent login.
+type Confirmation struct {
+	Password string
+	// AuthenticatedAt is the AuthTimeClaim of the access token in hand
+	AuthenticatedAt time.Time
+}
+
+// Confirm checks the confirmation of a sensitive change, see Confirmation
+func (s *AuthService) Confirm(ctx context.Context, user *models.User, confirmation Confirmation) error {
+	if user.SSOOnly {
+		if time.Since(confirmation.AuthenticatedAt) > reauthenticationWindow {
+			return ErrReauthenticationRequired
+		}
+		return nil
+	}
+
+	if confirmation.Password == "" {
+		return ErrPasswordRequired
+	}
+	return s.CheckPassword(ctx, user, confirmation.Password)
+}
+
+// CheckPassword confirms a sensitive account change with the user's current password.
+// Wrong guesses count towards the same limits as failed logins.
+func (s *AuthService) CheckPassword(ctx context.Context, user *models.User, password string) error {
+	emailKey := strings.ToLower(strings.TrimSpace(user.Email))
+	if err := s.limiters.email.Allow(ctx, emailKey); err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		if err := s.limiters.email.Fail(ctx, emailKey); err != nil {
+			slog.Error("failed to record rate limit failure", "error", err)
+		}
+		return ErrIncorrectPassword
+	}
+
+	s.resetLimiter(ctx, s.limiters.email, emailKey)
+	return nil
+}
+
+// ChangePassword replaces the password of a signed in user. Every other session is signed out,
+// the returned tokens replace the caller's.
+func (s *AuthService) ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) (*models.User, *TokenPair, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, ErrUserNotFound
+	}
+
+	if err := s.CheckPassword(ctx, user, currentPassword); err != nil {
+		return nil, nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user.PasswordHash = string(hashedPassword)
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.tokenRepo.InvalidateUserTokens(ctx, user.ID, models.UserTokenPasswordReset); err != nil {
+		return nil, nil, err
+	}
+	if err := s.tokenRepo.RevokeUserRefreshTokens(ctx, user.ID); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.issueTokens(ctx, user, uuid.NewString())
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
+
+// ChangeEmail moves the account to a new address, which has to be verified again
+func (s *AuthService) ChangeEmail(ctx context.Context, userID string, confirmation Confirmation, newEmail string) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if strings.EqualFold(user.Email, newEmail) {
+		return user, nil
+	}
+
+	if err := s.Confirm(ctx, user, confirmation); err != nil {
+		return nil, err
+	}
+
+	existingUser, err := s.userRepo.GetByEmail(ctx, newEmail)
+	if err == nil && existingUser != nil {
+		return nil, ErrUserAlreadyExists
+	}
+
+	user.Email = newEmail
+	user.EmailVerifiedAt = time.Time{}
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// links sent to the old address must not verify the new one
+	if err := s.tokenRepo.InvalidateUserTokens(ctx, user.ID, models.UserTokenEmailVerification); err != nil {
+		return nil, err
+	}
+	if err := s.SendVerificationEmail(ctx, user); err != nil {
+		slog.Error("failed to send verification email", "user_id", user.ID, "error", err)
+	}
+	return user, nil
+}
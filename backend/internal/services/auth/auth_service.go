@@ -2,46 +2,137 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"log/slog"
+	"strings"
 	"time"
 
-	"github.com/go-chi/jwtauth/v5"
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v2/jwt"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/meetia/backend/internal/models"
 	"github.com/meetia/backend/internal/repository"
+	"github.com/meetia/backend/internal/services/mail"
+	"github.com/meetia/backend/internal/services/ratelimit"
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid email or password")
-	ErrUserAlreadyExists  = errors.New("user with this email already exists")
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+	ErrUserAlreadyExists   = errors.New("user with this email already exists")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrEmailNotVerified    = errors.New("email address has not been verified")
 )
 
+// Config controls token lifetimes and account policies of the AuthService
+type Config struct {
+	AccessTokenTTL           time.Duration
+	RefreshTokenTTL          time.Duration
+	GuestTokenTTL            time.Duration
+	EmailVerificationTTL     time.Duration
+	PasswordResetTTL         time.Duration
+	RequireEmailVerification bool
+	// AppBaseURL is the frontend URL used to build links in emails
+	AppBaseURL    string
+	OIDCProviders []OIDCProviderConfig
+	// the account is locked for LockoutDuration after LockoutThreshold failed logins in a row,
+	// 0 turns the lockout off
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+}
+
+// loginLimiters throttle password and second factor guessing
+type loginLimiters struct {
+	ip      *ratelimit.Limiter
+	email   *ratelimit.Limiter
+	lockout *ratelimit.Limiter // nil when the lockout is off
+	mfa     *ratelimit.Limiter
+}
+
+func newLoginLimiters(store ratelimit.Store, cfg Config) loginLimiters {
+	limiters := loginLimiters{
+		ip: ratelimit.NewLimiter(store, "login-ip", ratelimit.Policy{
+			FreeAttempts: 20,
+			BaseDelay:    time.Second,
+			MaxDelay:     15 * time.Minute,
+			ResetAfter:   time.Hour,
+		}),
+		email: ratelimit.NewLimiter(store, "login-email", ratelimit.Policy{
+			FreeAttempts: 3,
+			BaseDelay:    time.Second,
+			MaxDelay:     5 * time.Minute,
+			ResetAfter:   time.Hour,
+		}),
+		mfa: ratelimit.NewLimiter(store, "login-mfa", ratelimit.Policy{
+			FreeAttempts: 5,
+			BaseDelay:    2 * time.Second,
+			MaxDelay:     15 * time.Minute,
+			ResetAfter:   time.Hour,
+		}),
+	}
+	if cfg.LockoutThreshold > 0 {
+		limiters.lockout = ratelimit.NewLimiter(store, "login-lockout", ratelimit.Policy{
+			FreeAttempts: cfg.LockoutThreshold - 1,
+			BaseDelay:    cfg.LockoutDuration,
+			MaxDelay:     cfg.LockoutDuration,
+			ResetAfter:   max(cfg.LockoutDuration, time.Hour),
+		})
+	}
+	return limiters
+}
+
+// TokenPair is the set of credentials handed to a client after authenticating
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
 type AuthService struct {
-	userRepo  *repository.UserRepository
-	tokenAuth *jwtauth.JWTAuth
-	tokenExp  time.Duration
+	userRepo      *repository.UserRepository
+	tokenRepo     *repository.TokenRepository
+	keys          *KeyStore
+	mailer        mail.Mailer
+	cfg           Config
+	oidcProviders map[string]*oidcProvider
+	limiters      loginLimiters
 }
 
-func NewAuthService(userRepo *repository.UserRepository, secret string, tokenExp time.Duration) *AuthService {
+func NewAuthService(
+	userRepo *repository.UserRepository,
+	tokenRepo *repository.TokenRepository,
+	keys *KeyStore,
+	mailer mail.Mailer,
+	limitStore ratelimit.Store,
+	cfg Config,
+) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		tokenAuth: jwtauth.New("HS256", []byte(secret), nil),
-		tokenExp:  tokenExp,
+		userRepo:      userRepo,
+		tokenRepo:     tokenRepo,
+		keys:          keys,
+		mailer:        mailer,
+		cfg:           cfg,
+		oidcProviders: newOIDCProviders(cfg.OIDCProviders),
+		limiters:      newLoginLimiters(limitStore, cfg),
 	}
 }
 
-func (s *AuthService) Register(ctx context.Context, email, password, displayName string) (*models.User, string, error) {
+func (s *AuthService) Register(ctx context.Context, email, password, displayName string) (*models.User, *TokenPair, error) {
 	// Check if user already exists
 	existingUser, err := s.userRepo.GetByEmail(ctx, email)
 	if err == nil && existingUser != nil {
-		return nil, "", ErrUserAlreadyExists
+		return nil, nil, ErrUserAlreadyExists
 	}
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	// create new user
@@ -54,49 +145,274 @@ func (s *AuthService) Register(ctx context.Context, email, password, displayName
 	}
 
 	if err := s.userRepo.Create(ctx, user); err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	// Generate JWT token
-	token, err := s.GenerateToken(user)
+	if err := s.SendVerificationEmail(ctx, user); err != nil {
+		// the account exists, the user can ask for another verification email
+		slog.Error("failed to send verification email", "user_id", user.ID, "error", err)
+	}
+
+	// no tokens until the address is verified, the user signs in afterwards
+	if s.cfg.RequireEmailVerification {
+		return user, nil, nil
+	}
+
+	tokens, err := s.issueTokens(ctx, user, uuid.NewString())
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	return user, token, nil
+	return user, tokens, nil
 }
 
-func (s *AuthService) GenerateToken(user *models.User) (string, error) {
+// GenerateToken creates a short-lived access token for the user
+func (s *AuthService) GenerateToken(user *models.User, authenticatedAt time.Time) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.cfg.AccessTokenTTL)
 	claims := map[string]interface{}{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"exp":     time.Now().Add(s.tokenExp).Unix(),
+		tokenUseClaim: tokenUseAccess,
+		"jti":         uuid.NewString(),
+		"user_id":     user.ID,
+		"email":       user.Email,
+		"iat":         now.Unix(),
+		"exp":         expiresAt.Unix(),
+		AuthTimeClaim: authenticatedAt.Unix(),
 	}
 
-	_, tokenString, err := s.tokenAuth.Encode(claims)
-	return tokenString, err
+	tokenString, err := s.keys.Sign(claims)
+	return tokenString, expiresAt, err
 }
 
-func (s *AuthService) Login(ctx context.Context, email, password string) (*models.User, string, error) {
+// Login checks the user's password. Accounts with two-factor authentication get an MFA challenge
+// instead of tokens, see VerifyMFA. Repeated failures from the same address or for the same
+// account are slowed down and eventually lock the account, returning a *ratelimit.LimitedError.
+func (s *AuthService) Login(ctx context.Context, clientIP, email, password string) (*LoginResult, error) {
+	emailKey := strings.ToLower(strings.TrimSpace(email))
+	limits := map[*ratelimit.Limiter]string{
+		s.limiters.ip:    clientIP,
+		s.limiters.email: emailKey,
+	}
+	if s.limiters.lockout != nil {
+		limits[s.limiters.lockout] = emailKey
+	}
+	if err := ratelimit.AllowAll(ctx, limits); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
-		return nil, "", ErrInvalidCredentials
+		ratelimit.FailAll(ctx, limits)
+		return nil, ErrInvalidCredentials
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return nil, "", ErrInvalidCredentials
+		ratelimit.FailAll(ctx, limits)
+		return nil, ErrInvalidCredentials
+	}
+
+	// the address is not reset, one good password says nothing about the other accounts it tried
+	s.resetLimiter(ctx, s.limiters.email, emailKey)
+	s.resetLimiter(ctx, s.limiters.lockout, emailKey)
+
+	if s.cfg.RequireEmailVerification && !user.IsEmailVerified() {
+		return nil, ErrEmailNotVerified
+	}
+
+	return s.completeLogin(ctx, user)
+}
+
+// Refresh exchanges a refresh token for a new token pair. Each refresh token can be used once;
+// presenting a token that was already rotated revokes every token issued from the same login.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*models.User, *TokenPair, error) {
+	stored, err := s.tokenRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
 	}
 
-	// Generate JWT token
-	token, err := s.GenerateToken(user)
+	if !stored.RevokedAt.IsZero() {
+		return nil, nil, s.revokeReusedFamily(ctx, stored)
+	}
+
+	if time.Now().After(stored.ExpiresAt) || stored.User == nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	// sessions from before the address changed or verification was required end here
+	if s.cfg.RequireEmailVerification && !stored.User.IsEmailVerified() {
+		return nil, nil, ErrEmailNotVerified
+	}
+
+	next, raw, err := s.newRefreshToken(stored.UserID, stored.FamilyID, stored.AuthenticatedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rotated, err := s.tokenRepo.RotateRefreshToken(ctx, stored.ID, next)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !rotated {
+		return nil, nil, s.revokeReusedFamily(ctx, stored)
+	}
+
+	accessToken, expiresAt, err := s.GenerateToken(stored.User, stored.AuthenticatedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return stored.User, &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: raw,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// Logout revokes the presented access token and the refresh token family it belongs to
+func (s *AuthService) Logout(ctx context.Context, jti string, accessExp time.Time, refreshToken string) error {
+	if jti != "" {
+		if err := s.tokenRepo.RevokeAccessToken(ctx, jti, accessExp); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, err := s.tokenRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		// the access token is already revoked, an unknown refresh token is nothing to clean up
+		return nil
+	}
+	return s.tokenRepo.RevokeRefreshTokenFamily(ctx, stored.FamilyID)
+}
+
+func (s *AuthService) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.tokenRepo.IsAccessTokenRevoked(ctx, jti)
+}
+
+// StartTokenCleanup periodically deletes expired refresh tokens and denylist entries until ctx is done
+func (s *AuthService) StartTokenCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.tokenRepo.DeleteExpired(ctx); err != nil {
+					slog.Error("failed to delete expired tokens", "error", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// VerifyToken checks the signature and claims of an access token. Personal access tokens are
+// accepted too, their claims carry the granted scopes.
+func (s *AuthService) VerifyToken(ctx context.Context, tokenString string) (jwt.Token, error) {
+	if strings.HasPrefix(tokenString, PersonalTokenPrefix) {
+		return s.verifyPersonalToken(ctx, tokenString)
+	}
+	token, err := s.keys.VerifyUse(tokenString, tokenUseAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	// guest tokens carry a meeting_guests id, there is no account to look up
+	if _, guest := token.PrivateClaims()[guestClaim]; guest {
+		return token, nil
+	}
+
+	// access tokens of a deleted account stop working right away instead of when they expire
+	userID, _ := token.PrivateClaims()["user_id"].(string)
+	if userID == "" {
+		return nil, ErrUserNotFound
+	}
+	exists, err := s.userRepo.Exists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+	return token, nil
+}
+
+// JWKS returns the public keys other services can use to verify our tokens
+func (s *AuthService) JWKS() ([]map[string]interface{}, error) {
+	return s.keys.JWKS()
+}
+
+func (s *AuthService) resetLimiter(ctx context.Context, limiter *ratelimit.Limiter, id string) {
+	if limiter == nil {
+		return
+	}
+	if err := limiter.Reset(ctx, id); err != nil {
+		slog.Error("failed to reset rate limit", "error", err)
+	}
+}
+
+// issueTokens starts a new session for a user who just authenticated
+func (s *AuthService) issueTokens(ctx context.Context, user *models.User, familyID string) (*TokenPair, error) {
+	now := time.Now()
+	accessToken, expiresAt, err := s.GenerateToken(user, now)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, raw, err := s.newRefreshToken(user.ID, familyID, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tokenRepo.CreateRefreshToken(ctx, refresh); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: raw,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+func (s *AuthService) newRefreshToken(userID, familyID string, authenticatedAt time.Time) (*models.RefreshToken, string, error) {
+	raw, err := generateOpaqueToken()
 	if err != nil {
 		return nil, "", err
 	}
 
-	return user, token, nil
+	return &models.RefreshToken{
+		ID:              uuid.NewString(),
+		UserID:          userID,
+		FamilyID:        familyID,
+		TokenHash:       hashToken(raw),
+		ExpiresAt:       time.Now().Add(s.cfg.RefreshTokenTTL),
+		CreatedAt:       time.Now(),
+		AuthenticatedAt: authenticatedAt,
+	}, raw, nil
+}
+
+func (s *AuthService) revokeReusedFamily(ctx context.Context, token *models.RefreshToken) error {
+	slog.Warn("refresh token reuse detected, revoking token family", "user_id", token.UserID, "family_id", token.FamilyID)
+	if err := s.tokenRepo.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// generateOpaqueToken returns a random url-safe token with 256 bits of entropy
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
-func (s *AuthService) GetTokenAuth() *jwtauth.JWTAuth {
-	return s.tokenAuth
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+var (
+	ErrUnsupportedKeyType = errors.New("unsupported signing key type, use an RSA or Ed25519 key")
+	ErrWrongTokenUse      = errors.New("token was not issued for this purpose")
+)
+
+// tokenUseClaim tells apart the different kinds of tokens signed with the same keys,
+// so e.g. a login state cookie can never be presented as an access token
+const tokenUseClaim = "token_use"
+
+const (
+	tokenUseAccess    = "access"
+	tokenUseOIDCLogin = "oidc_login"
+	// tokenUseMFAChallenge proves the password was correct, it is exchanged for real tokens with a second factor
+	tokenUseMFAChallenge = "mfa_challenge"
+)
+
+// KeyStore holds the key used to sign new tokens and every public key still accepted for
+// verification. Rotating keys is done by moving the old signing key to the verification list
+// and configuring a new signing key; tokens signed with the old key stay valid until they expire.
+type KeyStore struct {
+	signingKey jwk.Key
+	publicKeys jwk.Set
+}
+
+// LoadKeyStore reads PEM encoded keys from disk. The signing key must be a private key,
+// verification keys may be either private or public keys.
+func LoadKeyStore(signingKeyFile string, verificationKeyFiles []string) (*KeyStore, error) {
+	signingKey, err := loadKeyFile(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if isPrivate, err := jwk.IsPrivateKey(signingKey); err != nil || !isPrivate {
+		return nil, fmt.Errorf("signing key %s is not a private key", signingKeyFile)
+	}
+
+	keys := []jwk.Key{signingKey}
+	for _, file := range verificationKeyFiles {
+		if file == "" {
+			continue
+		}
+		key, err := loadKeyFile(file)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return newKeyStore(signingKey, keys)
+}
+
+// NewEphemeralKeyStore generates an in-memory Ed25519 key. Tokens signed with it become
+// invalid when the process restarts, so it is only meant for local development.
+func NewEphemeralKeyStore() (*KeyStore, error) {
+	_, raw, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := prepareKey(key); err != nil {
+		return nil, err
+	}
+
+	return newKeyStore(key, []jwk.Key{key})
+}
+
+func newKeyStore(signingKey jwk.Key, keys []jwk.Key) (*KeyStore, error) {
+	publicKeys := jwk.NewSet()
+	for _, key := range keys {
+		public, err := jwk.PublicKeyOf(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := prepareKey(public); err != nil {
+			return nil, err
+		}
+		if _, exists := publicKeys.LookupKeyID(public.KeyID()); exists {
+			continue
+		}
+		if err := publicKeys.AddKey(public); err != nil {
+			return nil, err
+		}
+	}
+
+	return &KeyStore{
+		signingKey: signingKey,
+		publicKeys: publicKeys,
+	}, nil
+}
+
+func loadKeyFile(path string) (jwk.Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file %s: %w", path, err)
+	}
+
+	key, err := jwk.ParseKey(data, jwk.WithPEM(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key file %s: %w", path, err)
+	}
+
+	if err := prepareKey(key); err != nil {
+		return nil, fmt.Errorf("key file %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// prepareKey sets the algorithm, usage and a thumbprint based kid on a key
+func prepareKey(key jwk.Key) error {
+	var alg jwa.SignatureAlgorithm
+	switch key.KeyType() {
+	case jwa.RSA:
+		alg = jwa.RS256
+	case jwa.OKP:
+		if okpCurve(key) != jwa.Ed25519 {
+			return ErrUnsupportedKeyType
+		}
+		alg = jwa.EdDSA
+	default:
+		return ErrUnsupportedKeyType
+	}
+
+	if err := key.Set(jwk.AlgorithmKey, alg); err != nil {
+		return err
+	}
+	if err := key.Set(jwk.KeyUsageKey, jwk.ForSignature); err != nil {
+		return err
+	}
+	if key.KeyID() == "" {
+		return jwk.AssignKeyID(key)
+	}
+	return nil
+}
+
+func okpCurve(key jwk.Key) jwa.EllipticCurveAlgorithm {
+	switch k := key.(type) {
+	case jwk.OKPPrivateKey:
+		return k.Crv()
+	case jwk.OKPPublicKey:
+		return k.Crv()
+	default:
+		return jwa.InvalidEllipticCurve
+	}
+}
+
+// Sign encodes the claims into a token signed with the current signing key
+func (k *KeyStore) Sign(claims map[string]interface{}) (string, error) {
+	token := jwt.New()
+	for name, value := range claims {
+		if err := token.Set(name, value); err != nil {
+			return "", err
+		}
+	}
+
+	signed, err := jwt.Sign(token, jwt.WithKey(k.signingKey.Algorithm(), k.signingKey))
+	if err != nil {
+		return "", err
+	}
+	return string(signed), nil
+}
+
+// Verify parses a token, checks its signature against the key named by its kid and validates its claims
+func (k *KeyStore) Verify(tokenString string) (jwt.Token, error) {
+	return jwt.ParseString(tokenString, jwt.WithKeySet(k.publicKeys), jwt.WithValidate(true))
+}
+
+// VerifyUse verifies a token and checks that it was issued for the given use
+func (k *KeyStore) VerifyUse(tokenString string, use string) (jwt.Token, error) {
+	token, err := k.Verify(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if v, _ := token.Get(tokenUseClaim); v != use {
+		return nil, ErrWrongTokenUse
+	}
+	return token, nil
+}
+
+// JWKS returns the public keys in JSON Web Key form
+func (k *KeyStore) JWKS() ([]map[string]interface{}, error) {
+	data, err := json.Marshal(k.publicKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	return set.Keys, nil
+}
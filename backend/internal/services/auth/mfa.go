@@ -0,0 +1,310 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/ratelimit"
+)
+
+var (
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled      = errors.New("two-factor authentication enrollment has not been started")
+	ErrMFANotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrInvalidMFACode      = errors.New("invalid two-factor authentication code")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired two-factor authentication challenge")
+)
+
+const (
+	mfaIssuer         = "Meetia"
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+	// recovery codes are two groups of five characters from this alphabet, about 50 bits each
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+// LoginResult is the outcome of a password or SSO login. When the account has two-factor authentication
+// enabled Tokens is nil and MFAToken has to be exchanged for tokens with VerifyMFA.
+type LoginResult struct {
+	User              *models.User
+	Tokens            *TokenPair
+	MFAToken          string
+	MFATokenExpiresAt time.Time
+}
+
+// TOTPEnrollment is what a user needs to add the account to an authenticator app
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// EnrollTOTP generates a new TOTP secret for the user. It only takes effect once confirmed with ConfirmTOTP.
+func (s *AuthService) EnrollTOTP(ctx context.Context, userID string) (*TOTPEnrollment, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsMFAEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    totpURI(mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once the user proves their app generates valid codes.
+// It returns the recovery codes, which are only ever shown this once.
+func (s *AuthService) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsMFAEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	if err := s.checkMFACode(ctx, user, code, false); err != nil {
+		return nil, err
+	}
+
+	user.TOTPEnabledAt = time.Now()
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return s.regenerateRecoveryCodes(ctx, user.ID)
+}
+
+// DisableTOTP turns two-factor authentication off, it requires a current code or a recovery code
+func (s *AuthService) DisableTOTP(ctx context.Context, userID, code string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.IsMFAEnabled() {
+		return ErrMFANotEnabled
+	}
+
+	if err := s.checkMFACode(ctx, user, code, true); err != nil {
+		return err
+	}
+
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = time.Time{}
+	user.TOTPLastStep = 0
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+	return s.userRepo.ReplaceRecoveryCodes(ctx, user.ID, nil)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, it requires a current TOTP code
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsMFAEnabled() {
+		return nil, ErrMFANotEnabled
+	}
+
+	if err := s.checkMFACode(ctx, user, code, false); err != nil {
+		return nil, err
+	}
+	return s.regenerateRecoveryCodes(ctx, user.ID)
+}
+
+// VerifyMFA completes a login that returned an MFA challenge, using a TOTP code or a recovery code.
+// A challenge is used up by the first attempt, a wrong code means logging in again.
+func (s *AuthService) VerifyMFA(ctx context.Context, mfaToken, code string) (*models.User, *TokenPair, error) {
+	challenge, err := s.keys.VerifyUse(mfaToken, tokenUseMFAChallenge)
+	if err != nil {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+
+	// consumed before the code is checked, so concurrent requests can't both redeem it
+	consumed, err := s.tokenRepo.ConsumeToken(ctx, challenge.JwtID(), challenge.Expiration())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !consumed {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+
+	user, err := s.userRepo.GetByID(ctx, challenge.Subject())
+	if err != nil || !user.IsMFAEnabled() {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+
+	if err := s.checkMFACode(ctx, user, code, true); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.issueTokens(ctx, user, uuid.NewString())
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
+
+// completeLogin signs in a user whose first factor checked out, or returns an MFA challenge
+// when the account has two-factor authentication enabled
+func (s *AuthService) completeLogin(ctx context.Context, user *models.User) (*LoginResult, error) {
+	if user.IsMFAEnabled() {
+		mfaToken, expiresAt, err := s.newMFAChallenge(user)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{
+			User:              user,
+			MFAToken:          mfaToken,
+			MFATokenExpiresAt: expiresAt,
+		}, nil
+	}
+
+	tokens, err := s.issueTokens(ctx, user, uuid.NewString())
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{
+		User:   user,
+		Tokens: tokens,
+	}, nil
+}
+
+func (s *AuthService) newMFAChallenge(user *models.User) (string, time.Time, error) {
+	expiresAt := time.Now().Add(mfaChallengeTTL)
+	token, err := s.keys.Sign(map[string]interface{}{
+		tokenUseClaim: tokenUseMFAChallenge,
+		"jti":         uuid.NewString(),
+		"sub":         user.ID,
+		"exp":         expiresAt.Unix(),
+	})
+	return token, expiresAt, err
+}
+
+// checkMFACode accepts a TOTP code, or an unused recovery code when allowRecovery is set.
+// Wrong codes are throttled per user so the code space can't be brute forced.
+func (s *AuthService) checkMFACode(ctx context.Context, user *models.User, code string, allowRecovery bool) error {
+	if err := s.limiters.mfa.Allow(ctx, user.ID); err != nil {
+		return err
+	}
+
+	err := s.matchMFACode(ctx, user, code, allowRecovery)
+	switch {
+	case errors.Is(err, ErrInvalidMFACode):
+		ratelimit.FailAll(ctx, map[*ratelimit.Limiter]string{s.limiters.mfa: user.ID})
+	case err == nil:
+		s.resetLimiter(ctx, s.limiters.mfa, user.ID)
+	}
+	return err
+}
+
+func (s *AuthService) matchMFACode(ctx context.Context, user *models.User, code string, allowRecovery bool) error {
+	code = strings.TrimSpace(code)
+	if len(strings.ReplaceAll(code, " ", "")) == totpDigits {
+		return s.checkTOTP(ctx, user, code)
+	}
+	if !allowRecovery {
+		return ErrInvalidMFACode
+	}
+
+	used, err := s.userRepo.UseRecoveryCode(ctx, user.ID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *AuthService) checkTOTP(ctx context.Context, user *models.User, code string) error {
+	step, ok := validateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	advanced, err := s.userRepo.AdvanceTOTPStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return ErrInvalidMFACode
+	}
+	user.TOTPLastStep = step
+	return nil
+}
+
+func (s *AuthService) regenerateRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	records := make([]*models.MFARecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		records[i] = &models.MFARecoveryCode{
+			UserID:    userID,
+			CodeHash:  hashToken(normalizeRecoveryCode(code)),
+			CreatedAt: time.Now(),
+		}
+	}
+
+	if err := s.userRepo.ReplaceRecoveryCodes(ctx, userID, records); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func generateRecoveryCode() (string, error) {
+	// bytes at or above limit are skipped so every character is equally likely
+	limit := 256 - 256%len(recoveryCodeAlphabet)
+	code := make([]byte, 0, 11)
+	b := make([]byte, 1)
+	for len(code) < 11 {
+		if len(code) == 5 {
+			code = append(code, '-')
+			continue
+		}
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		if int(b[0]) >= limit {
+			continue
+		}
+		code = append(code, recoveryCodeAlphabet[int(b[0])%len(recoveryCodeAlphabet)])
+	}
+	return string(code), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
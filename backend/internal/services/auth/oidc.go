@@ -0,0 +1,300 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/oauth2"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+var (
+	ErrUnknownOIDCProvider    = errors.New("unknown identity provider")
+	ErrInvalidOIDCState       = errors.New("invalid or expired login state")
+	ErrOIDCEmailNotVerified   = errors.New("identity provider did not return a verified email address")
+	ErrOIDCAccountNotVerified = errors.New("an account with this email exists but its address is not verified, sign in with the password and verify it first")
+	ErrOIDCProviderFailure    = errors.New("identity provider request failed")
+)
+
+const (
+	oidcLoginStateTTL        = 10 * time.Minute
+	oidcProviderInitCooldown = 30 * time.Second
+)
+
+var oidcDefaultScopes = []string{oidc.ScopeOpenID, "email", "profile"}
+
+// OIDCProviderConfig describes an OpenID Connect identity provider
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// OIDCLogin is the state that must survive the round trip to the identity provider.
+// Callers keep Cookie on the browser and send it back with the callback request.
+type OIDCLogin struct {
+	AuthURL string
+	Cookie  string
+}
+
+// oidcProvider lazily runs discovery so the server can start while an identity provider is unreachable
+type oidcProvider struct {
+	cfg OIDCProviderConfig
+
+	mu         sync.Mutex
+	oauth      *oauth2.Config
+	verifier   *oidc.IDTokenVerifier
+	lastFailed time.Time
+}
+
+func newOIDCProviders(configs []OIDCProviderConfig) map[string]*oidcProvider {
+	providers := make(map[string]*oidcProvider, len(configs))
+	for _, cfg := range configs {
+		if len(cfg.Scopes) == 0 {
+			cfg.Scopes = oidcDefaultScopes
+		}
+		providers[cfg.Name] = &oidcProvider{cfg: cfg}
+	}
+	return providers
+}
+
+func (p *oidcProvider) init(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+	if time.Since(p.lastFailed) < oidcProviderInitCooldown {
+		return nil, nil, ErrOIDCProviderFailure
+	}
+
+	provider, err := oidc.NewProvider(ctx, p.cfg.Issuer)
+	if err != nil {
+		p.lastFailed = time.Now()
+		slog.Error("oidc discovery failed", "provider", p.cfg.Name, "issuer", p.cfg.Issuer, "error", err)
+		return nil, nil, fmt.Errorf("%w: %v", ErrOIDCProviderFailure, err)
+	}
+
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.cfg.Scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	return p.oauth, p.verifier, nil
+}
+
+// OIDCProviders returns the names of the configured identity providers
+func (s *AuthService) OIDCProviders() []string {
+	names := make([]string, 0, len(s.oidcProviders))
+	for name := range s.oidcProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OIDCCompletionURL is the frontend page users are sent to once an OIDC login finishes.
+// The outcome is passed in the URL fragment so tokens never reach server logs.
+func (s *AuthService) OIDCCompletionURL(fragment url.Values) string {
+	return strings.TrimRight(s.cfg.AppBaseURL, "/") + "/auth/oidc/callback#" + fragment.Encode()
+}
+
+// OIDCLoginSecure reports whether the login state cookie should be marked Secure
+func (s *AuthService) OIDCLoginSecure(providerName string) bool {
+	provider, ok := s.oidcProviders[providerName]
+	return ok && strings.HasPrefix(provider.cfg.RedirectURL, "https://")
+}
+
+// StartOIDCLogin builds the authorization URL for an authorization code + PKCE login
+func (s *AuthService) StartOIDCLogin(ctx context.Context, providerName string) (*OIDCLogin, error) {
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	oauthCfg, _, err := provider.init(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	cookie, err := s.keys.Sign(map[string]interface{}{
+		tokenUseClaim: tokenUseOIDCLogin,
+		"provider":    providerName,
+		"state":       state,
+		"nonce":       nonce,
+		"verifier":    verifier,
+		"exp":         time.Now().Add(oidcLoginStateTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCLogin{
+		AuthURL: oauthCfg.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)),
+		Cookie:  cookie,
+	}, nil
+}
+
+// CompleteOIDCLogin exchanges the authorization code, verifies the ID token and signs the user in,
+// provisioning a new account or linking an existing, verified one by verified email address.
+// Accounts with two-factor authentication get an MFA challenge like a password login.
+func (s *AuthService) CompleteOIDCLogin(ctx context.Context, providerName, code, state, cookie string) (*LoginResult, error) {
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	loginState, err := s.keys.VerifyUse(cookie, tokenUseOIDCLogin)
+	if err != nil {
+		return nil, ErrInvalidOIDCState
+	}
+	claims := loginState.PrivateClaims()
+	if claims["provider"] != providerName || claims["state"] != state || state == "" {
+		return nil, ErrInvalidOIDCState
+	}
+	nonce, _ := claims["nonce"].(string)
+	verifier, _ := claims["verifier"].(string)
+
+	oauthCfg, idVerifier, err := provider.init(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	oauthToken, err := oauthCfg.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCProviderFailure, err)
+	}
+
+	rawIDToken, ok := oauthToken.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: no id_token in token response", ErrOIDCProviderFailure)
+	}
+
+	idToken, err := idVerifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCProviderFailure, err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, ErrInvalidOIDCState
+	}
+
+	var profile struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := idToken.Claims(&profile); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCProviderFailure, err)
+	}
+
+	user, err := s.resolveOIDCUser(ctx, providerName, idToken.Subject, profile.Email, profile.EmailVerified, profile.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user)
+}
+
+func (s *AuthService) resolveOIDCUser(ctx context.Context, provider, subject, email string, emailVerified bool, name string) (*models.User, error) {
+	identity, err := s.userRepo.GetIdentity(ctx, provider, subject)
+	if err == nil && identity.User != nil {
+		identity.LastLoginAt = time.Now()
+		if email != "" {
+			identity.Email = email
+		}
+		if err := s.userRepo.UpdateIdentity(ctx, identity); err != nil {
+			return nil, err
+		}
+		return identity.User, nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// an unverified email could belong to someone else, never link or provision on it
+	if email == "" || !emailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	identity = &models.UserIdentity{
+		Provider:    provider,
+		Subject:     subject,
+		Email:       email,
+		CreatedAt:   time.Now(),
+		LastLoginAt: time.Now(),
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err == nil {
+		// whoever registered an unverified address may not own it, linking would let them keep
+		// signing in with their password once the owner uses SSO
+		if !user.IsEmailVerified() {
+			return nil, ErrOIDCAccountNotVerified
+		}
+		identity.UserID = user.ID
+		if err := s.userRepo.CreateIdentity(ctx, identity); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// SSO users get a random password they don't know, they can set one through a password reset.
+	// Until then they confirm sensitive changes by signing in again, see Confirm.
+	randomPassword, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	unusablePassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	user = &models.User{
+		Email:           email,
+		PasswordHash:    string(unusablePassword),
+		DisplayName:     name,
+		EmailVerifiedAt: time.Now(),
+		SSOOnly:         true,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	if err := s.userRepo.CreateWithIdentity(ctx, user, identity); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,296 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/repository"
+	"github.com/meetia/backend/internal/services/auth/oidctest"
+	"github.com/meetia/backend/internal/services/mail"
+	"github.com/meetia/backend/internal/services/ratelimit"
+)
+
+const testOIDCProvider = "mock"
+
+// newOIDCTestService starts a mock identity provider and an AuthService using it. db may be nil
+// for tests that fail before any account is looked up.
+func newOIDCTestService(t *testing.T, db *bun.DB) (*AuthService, *oidctest.Provider) {
+	t.Helper()
+
+	var provider *oidctest.Provider
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provider.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := oidctest.NewProvider(server.URL, "meetia")
+	if err != nil {
+		t.Fatalf("creating mock provider: %v", err)
+	}
+
+	keys, err := NewEphemeralKeyStore()
+	if err != nil {
+		t.Fatalf("creating key store: %v", err)
+	}
+
+	svc := NewAuthService(
+		repository.NewUserRepository(db),
+		repository.NewTokenRepository(db),
+		keys,
+		mail.NewLogMailer(),
+		ratelimit.NewMemoryStore(),
+		Config{
+			AccessTokenTTL:  time.Minute,
+			RefreshTokenTTL: time.Hour,
+			OIDCProviders: []OIDCProviderConfig{{
+				Name:        testOIDCProvider,
+				Issuer:      server.URL,
+				ClientID:    "meetia",
+				RedirectURL: "http://app.test/api/auth/oidc/mock/callback",
+			}},
+		},
+	)
+	return svc, provider
+}
+
+// testDB connects to the migrated database in TEST_DATABASE_URL, tests needing accounts are skipped without it
+func testDB(t *testing.T) *bun.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("parsing TEST_DATABASE_URL: %v", err)
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("connecting to database: %v", err)
+	}
+	db := bun.NewDB(stdlib.OpenDBFromPool(pool), pgdialect.New())
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// authorizeOIDC starts a login and follows it through the mock provider's authorize endpoint.
+// edit may change the authorization request before it is sent.
+func authorizeOIDC(t *testing.T, svc *AuthService, edit func(q url.Values)) (code, state, cookie string) {
+	t.Helper()
+
+	login, err := svc.StartOIDCLogin(context.Background(), testOIDCProvider)
+	if err != nil {
+		t.Fatalf("starting login: %v", err)
+	}
+
+	authURL, err := url.Parse(login.AuthURL)
+	if err != nil {
+		t.Fatalf("parsing auth URL: %v", err)
+	}
+	if edit != nil {
+		q := authURL.Query()
+		edit(q)
+		authURL.RawQuery = q.Encode()
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL.String())
+	if err != nil {
+		t.Fatalf("authorizing: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+
+	callback, err := resp.Location()
+	if err != nil {
+		t.Fatalf("reading callback: %v", err)
+	}
+	return callback.Query().Get("code"), callback.Query().Get("state"), login.Cookie
+}
+
+// loginAs returns an authorization request editor signing in with the given email
+func loginAs(email string) func(q url.Values) {
+	return func(q url.Values) {
+		q.Set("login_hint", email)
+	}
+}
+
+func createTestUser(t *testing.T, db *bun.DB, user *models.User) {
+	t.Helper()
+
+	users := repository.NewUserRepository(db)
+	if err := users.Create(context.Background(), user); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	t.Cleanup(func() { users.Delete(context.Background(), user.ID) })
+}
+
+func testEmail() string {
+	return "sso-" + uuid.NewString() + "@example.com"
+}
+
+func TestCompleteOIDCLoginRejectsStateMismatch(t *testing.T) {
+	svc, _ := newOIDCTestService(t, nil)
+	code, _, cookie := authorizeOIDC(t, svc, nil)
+
+	_, err := svc.CompleteOIDCLogin(context.Background(), testOIDCProvider, code, "forged-state", cookie)
+	if !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidOIDCState)
+	}
+}
+
+func TestCompleteOIDCLoginRejectsForeignCookie(t *testing.T) {
+	svc, _ := newOIDCTestService(t, nil)
+	code, state, _ := authorizeOIDC(t, svc, nil)
+	// the state cookie of another login attempt
+	_, _, otherCookie := authorizeOIDC(t, svc, nil)
+
+	_, err := svc.CompleteOIDCLogin(context.Background(), testOIDCProvider, code, state, otherCookie)
+	if !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidOIDCState)
+	}
+}
+
+func TestCompleteOIDCLoginRejectsNonceMismatch(t *testing.T) {
+	svc, _ := newOIDCTestService(t, nil)
+	code, state, cookie := authorizeOIDC(t, svc, func(q url.Values) {
+		q.Set("nonce", "forged-nonce")
+	})
+
+	_, err := svc.CompleteOIDCLogin(context.Background(), testOIDCProvider, code, state, cookie)
+	if !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidOIDCState)
+	}
+}
+
+func TestCompleteOIDCLoginProvisionsNewUser(t *testing.T) {
+	db := testDB(t)
+	svc, _ := newOIDCTestService(t, db)
+	email := testEmail()
+
+	code, state, cookie := authorizeOIDC(t, svc, loginAs(email))
+	result, err := svc.CompleteOIDCLogin(context.Background(), testOIDCProvider, code, state, cookie)
+	if err != nil {
+		t.Fatalf("completing login: %v", err)
+	}
+	t.Cleanup(func() { repository.NewUserRepository(db).Delete(context.Background(), result.User.ID) })
+
+	if result.Tokens == nil {
+		t.Fatal("expected tokens for a new user")
+	}
+	if result.User.Email != email || !result.User.IsEmailVerified() {
+		t.Fatalf("got user %q verified=%v, want %q verified", result.User.Email, result.User.IsEmailVerified(), email)
+	}
+	if !result.User.SSOOnly {
+		t.Fatal("expected a provisioned user to be marked SSO only")
+	}
+
+	// the second login finds the user through the identity
+	code, state, cookie = authorizeOIDC(t, svc, loginAs(email))
+	again, err := svc.CompleteOIDCLogin(context.Background(), testOIDCProvider, code, state, cookie)
+	if err != nil {
+		t.Fatalf("completing second login: %v", err)
+	}
+	if again.User.ID != result.User.ID {
+		t.Fatalf("second login signed in %s, want %s", again.User.ID, result.User.ID)
+	}
+}
+
+func TestCompleteOIDCLoginRejectsUnverifiedProviderEmail(t *testing.T) {
+	db := testDB(t)
+	svc, provider := newOIDCTestService(t, db)
+	provider.EmailVerified = false
+
+	code, state, cookie := authorizeOIDC(t, svc, loginAs(testEmail()))
+	_, err := svc.CompleteOIDCLogin(context.Background(), testOIDCProvider, code, state, cookie)
+	if !errors.Is(err, ErrOIDCEmailNotVerified) {
+		t.Fatalf("got %v, want %v", err, ErrOIDCEmailNotVerified)
+	}
+}
+
+func TestCompleteOIDCLoginLinksVerifiedAccount(t *testing.T) {
+	db := testDB(t)
+	svc, _ := newOIDCTestService(t, db)
+
+	user := &models.User{
+		Email:           testEmail(),
+		PasswordHash:    "unused",
+		DisplayName:     "Existing",
+		EmailVerifiedAt: time.Now(),
+	}
+	createTestUser(t, db, user)
+
+	code, state, cookie := authorizeOIDC(t, svc, loginAs(user.Email))
+	result, err := svc.CompleteOIDCLogin(context.Background(), testOIDCProvider, code, state, cookie)
+	if err != nil {
+		t.Fatalf("completing login: %v", err)
+	}
+	if result.User.ID != user.ID {
+		t.Fatalf("signed in %s, want the existing account %s", result.User.ID, user.ID)
+	}
+	if result.Tokens == nil {
+		t.Fatal("expected tokens for an account without two-factor authentication")
+	}
+}
+
+func TestCompleteOIDCLoginRefusesUnverifiedAccount(t *testing.T) {
+	db := testDB(t)
+	svc, _ := newOIDCTestService(t, db)
+
+	// someone registered the address without ever confirming it
+	user := &models.User{
+		Email:        testEmail(),
+		PasswordHash: "unused",
+		DisplayName:  "Squatter",
+	}
+	createTestUser(t, db, user)
+
+	code, state, cookie := authorizeOIDC(t, svc, loginAs(user.Email))
+	_, err := svc.CompleteOIDCLogin(context.Background(), testOIDCProvider, code, state, cookie)
+	if !errors.Is(err, ErrOIDCAccountNotVerified) {
+		t.Fatalf("got %v, want %v", err, ErrOIDCAccountNotVerified)
+	}
+}
+
+func TestCompleteOIDCLoginRequiresSecondFactor(t *testing.T) {
+	db := testDB(t)
+	svc, _ := newOIDCTestService(t, db)
+
+	user := &models.User{
+		Email:           testEmail(),
+		PasswordHash:    "unused",
+		DisplayName:     "Two factor",
+		EmailVerifiedAt: time.Now(),
+		TOTPSecret:      "JBSWY3DPEHPK3PXP",
+		TOTPEnabledAt:   time.Now(),
+	}
+	createTestUser(t, db, user)
+
+	code, state, cookie := authorizeOIDC(t, svc, loginAs(user.Email))
+	result, err := svc.CompleteOIDCLogin(context.Background(), testOIDCProvider, code, state, cookie)
+	if err != nil {
+		t.Fatalf("completing login: %v", err)
+	}
+	if result.Tokens != nil || result.MFAToken == "" {
+		t.Fatal("expected an MFA challenge instead of tokens")
+	}
+}
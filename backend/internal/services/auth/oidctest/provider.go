@@ -0,0 +1,238 @@
+// Package oidctest is a minimal OpenID Connect provider for local development and tests of the
+// SSO login flow. It signs in every authorization request without asking for credentials, as the
+// user given by the login_hint parameter or DefaultEmail.
+package oidctest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+type authorization struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	challenge     string
+	email         string
+	expiresAt     time.Time
+	emailVerified bool
+}
+
+// Provider is an OpenID Connect provider serving discovery, authorize, token and jwks endpoints
+type Provider struct {
+	Issuer   string
+	ClientID string
+	// DefaultEmail signs in authorization requests without a login_hint
+	DefaultEmail string
+	// EmailVerified is the email_verified claim of the ID tokens
+	EmailVerified bool
+
+	key  jwk.Key
+	jwks jwk.Set
+	mux  *http.ServeMux
+
+	mu    sync.Mutex
+	codes map[string]*authorization
+}
+
+func NewProvider(issuer, clientID string) (*Provider, error) {
+	_, raw, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := jwk.AssignKeyID(key); err != nil {
+		return nil, err
+	}
+	if err := key.Set(jwk.AlgorithmKey, jwa.EdDSA); err != nil {
+		return nil, err
+	}
+
+	public, err := jwk.PublicKeyOf(key)
+	if err != nil {
+		return nil, err
+	}
+	jwks := jwk.NewSet()
+	if err := jwks.AddKey(public); err != nil {
+		return nil, err
+	}
+
+	p := &Provider{
+		Issuer:        strings.TrimRight(issuer, "/"),
+		ClientID:      clientID,
+		DefaultEmail:  "sso.user@example.com",
+		EmailVerified: true,
+		key:           key,
+		jwks:          jwks,
+		codes:         make(map[string]*authorization),
+	}
+
+	p.mux = http.NewServeMux()
+	p.mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	p.mux.HandleFunc("GET /authorize", p.authorize)
+	p.mux.HandleFunc("POST /token", p.token)
+	p.mux.HandleFunc("GET /jwks", p.keys)
+	return p, nil
+}
+
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mux.ServeHTTP(w, r)
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer,
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"token_endpoint":                        p.Issuer + "/token",
+		"jwks_uri":                              p.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"EdDSA"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+	})
+}
+
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != p.ClientID {
+		http.Error(w, "unsupported response_type or unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirectURI.Host == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	email := q.Get("login_hint")
+	if email == "" {
+		email = p.DefaultEmail
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.codes[code] = &authorization{
+		clientID:      p.ClientID,
+		redirectURI:   redirectURI.String(),
+		nonce:         q.Get("nonce"),
+		challenge:     q.Get("code_challenge"),
+		email:         email,
+		emailVerified: p.EmailVerified,
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+	p.mu.Unlock()
+
+	params := redirectURI.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirectURI.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	clientID := r.PostForm.Get("client_id")
+	if user, _, ok := r.BasicAuth(); ok {
+		clientID = user
+	}
+
+	p.mu.Lock()
+	auth, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	if !ok || time.Now().After(auth.expiresAt) ||
+		r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("redirect_uri") != auth.redirectURI ||
+		clientID != auth.clientID {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	name, _, _ := strings.Cut(auth.email, "@")
+	now := time.Now()
+	idToken, err := jwt.NewBuilder().
+		Issuer(p.Issuer).
+		Subject("mock|"+auth.email).
+		Audience([]string{auth.clientID}).
+		IssuedAt(now).
+		Expiration(now.Add(5*time.Minute)).
+		Claim("nonce", auth.nonce).
+		Claim("email", auth.email).
+		Claim("email_verified", auth.emailVerified).
+		Claim("name", name).
+		Build()
+	if err != nil {
+		tokenError(w, "server_error")
+		return
+	}
+
+	signed, err := jwt.Sign(idToken, jwt.WithKey(jwa.EdDSA, p.key))
+	if err != nil {
+		tokenError(w, "server_error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     string(signed),
+	})
+}
+
+func (p *Provider) keys(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, p.jwks)
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/mail"
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrInvalidResetToken        = errors.New("invalid or expired password reset token")
+	ErrEmailAlreadyVerified     = errors.New("email address is already verified")
+)
+
+// SendVerificationEmail emails the user a link to confirm their address.
+// Any previously sent verification link stops working.
+func (s *AuthService) SendVerificationEmail(ctx context.Context, user *models.User) error {
+	if user.IsEmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	raw, err := s.createUserToken(ctx, user.ID, models.UserTokenEmailVerification, s.cfg.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.appLink("/verify-email", raw)
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your Meetia email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nConfirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+			user.DisplayName, link, s.cfg.EmailVerificationTTL,
+		),
+	})
+}
+
+// ResendVerificationEmail sends a fresh verification link to a signed in user
+func (s *AuthService) ResendVerificationEmail(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.SendVerificationEmail(ctx, user)
+}
+
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	userToken, err := s.tokenRepo.ConsumeUserToken(ctx, models.UserTokenEmailVerification, hashToken(token))
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userToken.UserID)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if !user.IsEmailVerified() {
+		user.EmailVerifiedAt = time.Now()
+		user.UpdatedAt = time.Now()
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// ForgotPassword emails a password reset link if an account exists for the address.
+// It reports success either way so callers cannot probe which emails are registered.
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+
+	raw, err := s.createUserToken(ctx, user.ID, models.UserTokenPasswordReset, s.cfg.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.appLink("/reset-password", raw)
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Meetia password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to reset the password of your account. If it was you, open the link below:\n\n%s\n\nThe link expires in %s. If you did not ask for this you can ignore this email.\n",
+			user.DisplayName, link, s.cfg.PasswordResetTTL,
+		),
+	})
+	if err != nil {
+		// failing loudly here would reveal that the account exists
+		slog.Error("failed to send password reset email", "user_id", user.ID, "error", err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token and signs the user out everywhere
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	userToken, err := s.tokenRepo.ConsumeUserToken(ctx, models.UserTokenPasswordReset, hashToken(token))
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userToken.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = string(hashedPassword)
+	user.SSOOnly = false
+	// receiving the reset email proves ownership of the address
+	if !user.IsEmailVerified() {
+		user.EmailVerifiedAt = time.Now()
+	}
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	if err := s.tokenRepo.InvalidateUserTokens(ctx, user.ID, models.UserTokenPasswordReset); err != nil {
+		return err
+	}
+	return s.tokenRepo.RevokeUserRefreshTokens(ctx, user.ID)
+}
+
+func (s *AuthService) createUserToken(ctx context.Context, userID string, purpose models.UserTokenPurpose, ttl time.Duration) (string, error) {
+	if err := s.tokenRepo.InvalidateUserTokens(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	raw, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	token := &models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(ttl),
+		CreatedAt: time.Now(),
+	}
+	if err := s.tokenRepo.CreateUserToken(ctx, token); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+func (s *AuthService) appLink(path, token string) string {
+	return s.cfg.AppBaseURL + path + "?token=" + url.QueryEscape(token)
+}
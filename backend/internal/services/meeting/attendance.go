@@ -0,0 +1,132 @@
+package meeting
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+// AttendanceRecord sums up the attendance sessions of one user in a meeting
+type AttendanceRecord struct {
+	UserID      string // the guest id for guests
+	DisplayName string
+	Guest       bool
+	Role        models.ParticipantRole
+	FirstJoined time.Time
+	LastLeft    time.Time // end of the last closed session
+	Connected   bool
+	Sessions    int
+	Duration    time.Duration
+}
+
+// PeerJoined opens an attendance session when a user's media connects to the meeting
+func (s *MeetingService) PeerJoined(meetingID string, userID string) {
+	if _, err := s.meetingRepo.StartAttendance(context.Background(), meetingID, userID, time.Now()); err != nil {
+		slog.Error("failed to start attendance session", "meeting_id", meetingID, "user_id", userID, "error", err)
+	}
+}
+
+// PeerLeft closes the user's attendance session when their media connection goes away
+func (s *MeetingService) PeerLeft(meetingID string, userID string) {
+	if err := s.meetingRepo.EndAttendance(context.Background(), meetingID, userID, time.Now()); err != nil {
+		slog.Error("failed to end attendance session", "meeting_id", meetingID, "user_id", userID, "error", err)
+	}
+}
+
+// AttendanceReport returns who attended a meeting and for how long, in the order they first joined
+// (hosts only). Sessions still open count until the meeting ended, or until now.
+func (s *MeetingService) AttendanceReport(ctx context.Context, meetingID string, userID string) (*models.Meeting, []*AttendanceRecord, error) {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermViewAttendance)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sessions, err := s.meetingRepo.ListAttendance(ctx, meeting.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	participants, err := s.meetingRepo.GetParticipants(ctx, meeting.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	roles := make(map[string]models.ParticipantRole, len(participants))
+	for _, p := range participants {
+		roles[p.MemberID()] = p.Role
+	}
+
+	until := time.Now()
+	if !meeting.EndedAt.IsZero() {
+		until = meeting.EndedAt
+	}
+
+	// sessions of deleted accounts have no user and are summed up together
+	var records []*AttendanceRecord
+	byUser := make(map[string]*AttendanceRecord)
+	for _, session := range sessions {
+		memberID := session.MemberID()
+		record, exists := byUser[memberID]
+		if !exists {
+			record = &AttendanceRecord{
+				UserID:      memberID,
+				DisplayName: session.DisplayName(),
+				Guest:       session.GuestID != "",
+				Role:        roles[memberID],
+				FirstJoined: session.JoinedAt,
+			}
+			if session.UserID != "" && session.UserID == meeting.HostID {
+				record.Role = models.MeetingParticipantHost
+			}
+			byUser[memberID] = record
+			records = append(records, record)
+		}
+
+		leftAt := session.LeftAt
+		if leftAt.IsZero() {
+			leftAt = until
+			record.Connected = meeting.EndedAt.IsZero()
+		} else if leftAt.After(record.LastLeft) {
+			record.LastLeft = leftAt
+		}
+		if leftAt.After(session.JoinedAt) {
+			record.Duration += leftAt.Sub(session.JoinedAt)
+		}
+		record.Sessions++
+	}
+
+	return meeting, records, nil
+}
+
+// WriteAttendanceCSV writes an attendance report to w, with timestamps converted to loc
+func WriteAttendanceCSV(w io.Writer, loc *time.Location, records []*AttendanceRecord) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"user_id", "display_name", "guest", "role", "first_joined", "last_left", "connected", "sessions", "duration_seconds"}); err != nil {
+		return err
+	}
+	for _, record := range records {
+		lastLeft := ""
+		if !record.LastLeft.IsZero() {
+			lastLeft = record.LastLeft.In(loc).Format(time.RFC3339)
+		}
+		row := []string{
+			record.UserID,
+			csvText(record.DisplayName),
+			strconv.FormatBool(record.Guest),
+			string(record.Role),
+			record.FirstJoined.In(loc).Format(time.RFC3339),
+			lastLeft,
+			strconv.FormatBool(record.Connected),
+			strconv.Itoa(record.Sessions),
+			strconv.FormatInt(int64(record.Duration/time.Second), 10),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
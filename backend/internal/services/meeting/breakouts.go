@@ -0,0 +1,415 @@
+package meeting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+var (
+	ErrBreakoutRoomNotFound = errors.New("breakout room not found")
+	ErrInvalidBreakout      = errors.New("invalid breakout rooms")
+	ErrNoBreakoutRooms      = errors.New("the meeting has no breakout rooms")
+	ErrBreakoutsOpen        = errors.New("breakout rooms are open")
+	ErrBreakoutsClosed      = errors.New("breakout rooms are not open")
+)
+
+// MaxBreakoutRooms is how many breakout rooms a meeting can have
+const MaxBreakoutRooms = 50
+
+// Events pushed over signaling while breakout rooms are used
+const (
+	// EventBreakoutsOpened tells everyone the breakout rooms opened, with a BreakoutTimer
+	EventBreakoutsOpened = "breakouts-opened"
+	// EventBreakoutsClosing starts the countdown before everyone returns, with a BreakoutTimer
+	EventBreakoutsClosing = "breakouts-closing"
+	// EventBreakoutsClosed tells everyone they are back in the meeting
+	EventBreakoutsClosed = "breakouts-closed"
+	// EventBreakoutMoved tells a participant which room their media moved to, with a BreakoutMove
+	EventBreakoutMoved = "breakout-moved"
+	// EventBreakoutBroadcast is a message from a host to every room, with a BreakoutBroadcast
+	EventBreakoutBroadcast = "breakout-broadcast"
+)
+
+// BreakoutTimer tells when the breakout rooms close, a zero CloseAt means not before a host closes them
+type BreakoutTimer struct {
+	CloseAt time.Time `json:"closeAt,omitempty"`
+}
+
+// BreakoutMove names the room a participant is in now, the meeting itself when RoomID is empty
+type BreakoutMove struct {
+	RoomID string `json:"roomId,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+// BreakoutBroadcast is a message a host sent to all rooms
+type BreakoutBroadcast struct {
+	Message string `json:"message"`
+	From    string `json:"from"`
+}
+
+// CreateBreakoutRooms adds rooms after the existing ones, named as given or numbered when names is empty
+func (s *MeetingService) CreateBreakoutRooms(ctx context.Context, meetingID string, userID string, count int, names []string) ([]*models.BreakoutRoom, error) {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermManageBreakouts)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.meetingRepo.ListBreakoutRooms(ctx, meeting.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > 0 {
+		count = len(names)
+	}
+	if count < 1 {
+		return nil, fmt.Errorf("%w: create at least one room", ErrInvalidBreakout)
+	}
+	if len(existing)+count > MaxBreakoutRooms {
+		return nil, fmt.Errorf("%w: a meeting can have up to %d rooms", ErrInvalidBreakout, MaxBreakoutRooms)
+	}
+
+	rooms := make([]*models.BreakoutRoom, count)
+	for i := range rooms {
+		position := len(existing) + i + 1
+		name := fmt.Sprintf("Room %d", position)
+		if len(names) > 0 {
+			name = names[i]
+		}
+		rooms[i] = &models.BreakoutRoom{
+			ID:        uuid.NewString(),
+			MeetingID: meeting.ID,
+			Name:      name,
+			Position:  position,
+			CreatedAt: time.Now(),
+		}
+	}
+	if err := s.meetingRepo.CreateBreakoutRooms(ctx, rooms); err != nil {
+		return nil, err
+	}
+
+	return s.meetingRepo.ListBreakoutRooms(ctx, meeting.ID)
+}
+
+// ListBreakoutRooms returns the meeting with its breakout rooms and who is assigned to them,
+// to anyone admitted to the meeting
+func (s *MeetingService) ListBreakoutRooms(ctx context.Context, meetingID string, userID string) (*models.Meeting, []*models.BreakoutRoom, error) {
+	meeting, err := s.meetingRepo.GetByID(ctx, meetingID)
+	if err != nil {
+		return nil, nil, ErrMeetingNotFound
+	}
+	if s.roleOf(ctx, meeting, userID) == "" {
+		return nil, nil, ErrNotAuthorized
+	}
+
+	rooms, err := s.meetingRepo.ListBreakoutRooms(ctx, meeting.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return meeting, rooms, nil
+}
+
+// DeleteBreakoutRoom removes a room and its assignments while the rooms are closed
+func (s *MeetingService) DeleteBreakoutRoom(ctx context.Context, meetingID string, userID string, roomID string) error {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermManageBreakouts)
+	if err != nil {
+		return err
+	}
+	if meeting.BreakoutsOpen() {
+		return ErrBreakoutsOpen
+	}
+
+	deleted, err := s.meetingRepo.DeleteBreakoutRoom(ctx, roomID, meeting.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrBreakoutRoomNotFound
+	}
+	return nil
+}
+
+// AssignBreakouts puts participants into rooms, assignments maps user ids to room ids and an empty
+// room id takes the user out of their room. While the rooms are open their media moves right away.
+func (s *MeetingService) AssignBreakouts(ctx context.Context, meetingID string, userID string, assignments map[string]string) ([]*models.BreakoutRoom, error) {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermManageBreakouts)
+	if err != nil {
+		return nil, err
+	}
+
+	rooms, err := s.meetingRepo.ListBreakoutRooms(ctx, meeting.ID)
+	if err != nil {
+		return nil, err
+	}
+	roomNames := map[string]string{}
+	for _, room := range rooms {
+		roomNames[room.ID] = room.Name
+	}
+
+	participants, err := s.meetingRepo.ListParticipantsByStatus(ctx, meeting.ID, models.ParticipantAdmitted)
+	if err != nil {
+		return nil, err
+	}
+	admitted := map[string]bool{}
+	for _, p := range participants {
+		admitted[p.UserID] = true
+	}
+
+	var assigned []*models.BreakoutAssignment
+	var unassigned []string
+	for assignee, roomID := range assignments {
+		if !admitted[assignee] {
+			return nil, fmt.Errorf("%w: %s is not in the meeting", ErrInvalidBreakout, assignee)
+		}
+		if roomID == "" {
+			unassigned = append(unassigned, assignee)
+			continue
+		}
+		if _, ok := roomNames[roomID]; !ok {
+			return nil, ErrBreakoutRoomNotFound
+		}
+		assigned = append(assigned, &models.BreakoutAssignment{
+			MeetingID:  meeting.ID,
+			UserID:     assignee,
+			RoomID:     roomID,
+			AssignedAt: time.Now(),
+		})
+	}
+
+	if err := s.meetingRepo.AssignBreakouts(ctx, meeting.ID, assigned, unassigned, false); err != nil {
+		return nil, err
+	}
+
+	if meeting.BreakoutsOpen() {
+		for assignee, roomID := range assignments {
+			s.moveToBreakout(meeting.ID, assignee, roomID, roomNames[roomID])
+		}
+	}
+	return s.meetingRepo.ListBreakoutRooms(ctx, meeting.ID)
+}
+
+// ShuffleBreakouts spreads the participants evenly over the rooms at random, replacing every
+// assignment. Hosts and co-hosts stay unassigned, they can move between rooms on their own.
+func (s *MeetingService) ShuffleBreakouts(ctx context.Context, meetingID string, userID string) ([]*models.BreakoutRoom, error) {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermManageBreakouts)
+	if err != nil {
+		return nil, err
+	}
+
+	rooms, err := s.meetingRepo.ListBreakoutRooms(ctx, meeting.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rooms) == 0 {
+		return nil, ErrNoBreakoutRooms
+	}
+
+	participants, err := s.meetingRepo.ListParticipantsByStatus(ctx, meeting.ID, models.ParticipantAdmitted)
+	if err != nil {
+		return nil, err
+	}
+	var attendees []string
+	for _, p := range participants {
+		// guests and deleted accounts have no user to assign
+		if p.UserID != "" && p.UserID != meeting.HostID && !Can(p.Role, PermManageBreakouts) {
+			attendees = append(attendees, p.UserID)
+		}
+	}
+	rand.Shuffle(len(attendees), func(i, j int) {
+		attendees[i], attendees[j] = attendees[j], attendees[i]
+	})
+
+	assigned := make([]*models.BreakoutAssignment, len(attendees))
+	for i, attendee := range attendees {
+		assigned[i] = &models.BreakoutAssignment{
+			MeetingID:  meeting.ID,
+			UserID:     attendee,
+			RoomID:     rooms[i%len(rooms)].ID,
+			AssignedAt: time.Now(),
+		}
+	}
+	if err := s.meetingRepo.AssignBreakouts(ctx, meeting.ID, assigned, nil, true); err != nil {
+		return nil, err
+	}
+
+	rooms, err = s.meetingRepo.ListBreakoutRooms(ctx, meeting.ID)
+	if err != nil {
+		return nil, err
+	}
+	if meeting.BreakoutsOpen() {
+		s.moveAssigned(meeting.ID, rooms)
+	}
+	return rooms, nil
+}
+
+// OpenBreakouts sends the assigned participants into their rooms, after duration they return
+// on their own. With a zero duration the rooms stay open until a host closes them.
+func (s *MeetingService) OpenBreakouts(ctx context.Context, meetingID string, userID string, duration time.Duration) (*models.Meeting, error) {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermManageBreakouts)
+	if err != nil {
+		return nil, err
+	}
+	if meeting.Status(time.Now()) == models.MeetingStatusPast {
+		return nil, ErrMeetingEnded
+	}
+
+	rooms, err := s.meetingRepo.ListBreakoutRooms(ctx, meeting.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rooms) == 0 {
+		return nil, ErrNoBreakoutRooms
+	}
+
+	now := time.Now().Truncate(time.Microsecond)
+	var closeAt time.Time
+	if duration > 0 {
+		closeAt = now.Add(duration)
+	}
+	opened, err := s.meetingRepo.OpenBreakouts(ctx, meeting.ID, now, closeAt)
+	if err != nil {
+		return nil, err
+	}
+	if !opened {
+		return nil, ErrBreakoutsOpen
+	}
+	meeting.BreakoutsOpenedAt = now
+	meeting.BreakoutsCloseAt = closeAt
+
+	if err := s.notifyAdmitted(ctx, meeting.ID, EventBreakoutsOpened, BreakoutTimer{CloseAt: closeAt}); err != nil {
+		return nil, err
+	}
+	s.moveAssigned(meeting.ID, rooms)
+	if !closeAt.IsZero() {
+		s.scheduleBreakoutsClose(meeting.ID, closeAt)
+	}
+	return meeting, nil
+}
+
+// CloseBreakouts brings everyone back to the meeting after a countdown, right away when it is zero
+func (s *MeetingService) CloseBreakouts(ctx context.Context, meetingID string, userID string, countdown time.Duration) (*models.Meeting, error) {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermManageBreakouts)
+	if err != nil {
+		return nil, err
+	}
+
+	closeAt := time.Now().Add(countdown).Truncate(time.Microsecond)
+	scheduled, err := s.meetingRepo.ScheduleBreakoutsClose(ctx, meeting.ID, closeAt)
+	if err != nil {
+		return nil, err
+	}
+	if !scheduled {
+		return nil, ErrBreakoutsClosed
+	}
+
+	if countdown <= 0 {
+		if err := s.finishBreakouts(ctx, meeting.ID, closeAt); err != nil {
+			return nil, err
+		}
+		return s.meetingRepo.GetByID(ctx, meeting.ID)
+	}
+
+	if err := s.notifyAdmitted(ctx, meeting.ID, EventBreakoutsClosing, BreakoutTimer{CloseAt: closeAt}); err != nil {
+		return nil, err
+	}
+	s.scheduleBreakoutsClose(meeting.ID, closeAt)
+	meeting.BreakoutsCloseAt = closeAt
+	return meeting, nil
+}
+
+// BroadcastToBreakouts sends a message to every participant while the rooms are open
+func (s *MeetingService) BroadcastToBreakouts(ctx context.Context, meetingID string, userID string, message string) error {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermManageBreakouts)
+	if err != nil {
+		return err
+	}
+	if !meeting.BreakoutsOpen() {
+		return ErrBreakoutsClosed
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.notifyAdmitted(ctx, meeting.ID, EventBreakoutBroadcast, BreakoutBroadcast{Message: message, From: user.DisplayName})
+}
+
+// BreakoutRoomOf returns the room the user belongs in while the breakout rooms are open, empty otherwise.
+// Signaling places users there when they connect.
+func (s *MeetingService) BreakoutRoomOf(ctx context.Context, meetingID string, userID string) (string, error) {
+	meeting, err := s.meetingRepo.GetByID(ctx, meetingID)
+	if err != nil {
+		return "", ErrMeetingNotFound
+	}
+	if !meeting.BreakoutsOpen() {
+		return "", nil
+	}
+
+	rooms, err := s.meetingRepo.ListBreakoutRooms(ctx, meeting.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, room := range rooms {
+		for _, a := range room.Assignments {
+			if a.UserID == userID {
+				return room.ID, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// closeDueBreakouts closes breakout rooms whose timer ran out without the server noticing,
+// e.g. because it restarted
+func (s *MeetingService) closeDueBreakouts(ctx context.Context) {
+	meetings, err := s.meetingRepo.ListDueBreakouts(ctx, time.Now())
+	if err != nil {
+		slog.Error("failed to list due breakout rooms", "error", err)
+		return
+	}
+	for _, m := range meetings {
+		if err := s.finishBreakouts(ctx, m.ID, m.BreakoutsCloseAt); err != nil {
+			slog.Error("failed to close breakout rooms", "meeting_id", m.ID, "error", err)
+		}
+	}
+}
+
+func (s *MeetingService) scheduleBreakoutsClose(meetingID string, closeAt time.Time) {
+	time.AfterFunc(time.Until(closeAt), func() {
+		if err := s.finishBreakouts(context.Background(), meetingID, closeAt); err != nil {
+			slog.Error("failed to close breakout rooms", "meeting_id", meetingID, "error", err)
+		}
+	})
+}
+
+// finishBreakouts brings everyone back unless the rooms were closed or rescheduled meanwhile
+func (s *MeetingService) finishBreakouts(ctx context.Context, meetingID string, closeAt time.Time) error {
+	finished, err := s.meetingRepo.FinishBreakouts(ctx, meetingID, closeAt)
+	if err != nil || !finished {
+		return err
+	}
+
+	s.signaling.CloseBreakouts(meetingID)
+	return s.notifyAdmitted(ctx, meetingID, EventBreakoutsClosed, nil)
+}
+
+// moveAssigned moves everyone assigned to a room into it
+func (s *MeetingService) moveAssigned(meetingID string, rooms []*models.BreakoutRoom) {
+	for _, room := range rooms {
+		for _, a := range room.Assignments {
+			s.moveToBreakout(meetingID, a.UserID, room.ID, room.Name)
+		}
+	}
+}
+
+func (s *MeetingService) moveToBreakout(meetingID string, userID string, roomID string, name string) {
+	s.signaling.MoveToBreakout(meetingID, roomID, userID)
+	s.signaling.Notify(meetingID, []string{userID}, EventBreakoutMoved, BreakoutMove{RoomID: roomID, Name: name})
+}
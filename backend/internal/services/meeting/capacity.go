@@ -0,0 +1,106 @@
+package meeting
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+var (
+	ErrMeetingLocked   = errors.New("meeting locked")
+	ErrMeetingFull     = errors.New("meeting full")
+	ErrInvalidCapacity = errors.New("participant limit is above what the server allows")
+)
+
+// EventLockChanged tells everyone in the meeting it was locked or unlocked, with a LockState
+const EventLockChanged = "lock-changed"
+
+// LockState says whether a meeting takes new joiners
+type LockState struct {
+	Locked bool `json:"locked"`
+}
+
+// SetLocked locks or unlocks a meeting (hosts and co-hosts only). Participants who already joined
+// may still reconnect while it is locked.
+func (s *MeetingService) SetLocked(ctx context.Context, meetingID string, userID string, locked bool) (*models.Meeting, error) {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermLockMeeting)
+	if err != nil {
+		return nil, err
+	}
+	if meeting.Locked == locked {
+		return meeting, nil
+	}
+
+	meeting.Locked = locked
+	if err := s.meetingRepo.Update(ctx, meeting); err != nil {
+		return nil, err
+	}
+
+	if err := s.notifyAdmitted(ctx, meeting.ID, EventLockChanged, LockState{Locked: locked}); err != nil {
+		return nil, err
+	}
+	return meeting, nil
+}
+
+// SetMaxParticipants caps how many users may be connected to a meeting at once, 0 goes back to the
+// server default. Only the host can do this and the cap can't go above the server default.
+func (s *MeetingService) SetMaxParticipants(ctx context.Context, meetingID string, userID string, maxParticipants int) (*models.Meeting, error) {
+	if maxParticipants < 0 || (s.cfg.MaxParticipants > 0 && maxParticipants > s.cfg.MaxParticipants) {
+		return nil, ErrInvalidCapacity
+	}
+
+	meeting, err := s.authorize(ctx, meetingID, userID, PermChangeSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	meeting.MaxParticipants = maxParticipants
+	if err := s.meetingRepo.Update(ctx, meeting); err != nil {
+		return nil, err
+	}
+	return meeting, nil
+}
+
+// Capacity is how many users may be connected to the meeting at once, 0 when there is no cap
+func (s *MeetingService) Capacity(meeting *models.Meeting) int {
+	if meeting.MaxParticipants > 0 {
+		return meeting.MaxParticipants
+	}
+	return s.cfg.MaxParticipants
+}
+
+// PeerLimit returns the cap the SFU applies when the user connects their media, 0 for the host
+// who always gets into their own meeting
+func (s *MeetingService) PeerLimit(ctx context.Context, meetingID string, userID string) (int, error) {
+	meeting, err := s.meetingRepo.GetByID(ctx, meetingID)
+	if err != nil {
+		return 0, ErrMeetingNotFound
+	}
+	if meeting.HostID == userID {
+		return 0, nil
+	}
+	return s.Capacity(meeting), nil
+}
+
+// checkAdmittance refuses new joiners of a locked meeting and anyone joining a full one. The host
+// is never turned away, participant is nil for users joining for the first time.
+func (s *MeetingService) checkAdmittance(meeting *models.Meeting, participant *models.MeetingParticipant, userID string) error {
+	if meeting.HostID == userID {
+		return nil
+	}
+	if meeting.Locked && participant == nil {
+		return ErrMeetingLocked
+	}
+
+	capacity := s.Capacity(meeting)
+	if capacity == 0 {
+		return nil
+	}
+	connected := s.signaling.ConnectedUsers(meeting.ID)
+	if !slices.Contains(connected, userID) && len(connected) >= capacity {
+		return ErrMeetingFull
+	}
+	return nil
+}
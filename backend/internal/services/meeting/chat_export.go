@@ -0,0 +1,172 @@
+package meeting
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+var ErrUnsupportedExportFormat = errors.New("unsupported export format")
+
+type ExportFormat string
+
+const (
+	ExportFormatText ExportFormat = "txt"
+	ExportFormatJSON ExportFormat = "json"
+	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatHTML ExportFormat = "html"
+)
+
+// ContentType returns the MIME type used when serving a transcript in this format
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportFormatJSON:
+		return "application/json; charset=utf-8"
+	case ExportFormatCSV:
+		return "text/csv; charset=utf-8"
+	case ExportFormatHTML:
+		return "text/html; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+type transcriptEntry struct {
+	SentAt      string `json:"sentAt"`
+	UserID      string `json:"userId"`
+	DisplayName string `json:"displayName"`
+	Message     string `json:"message"`
+}
+
+// WriteChatTranscript writes the chat history of a meeting to w in the given format,
+// with timestamps converted to loc.
+func WriteChatTranscript(w io.Writer, format ExportFormat, loc *time.Location, meeting *models.Meeting, chats []*models.MeetingChat) error {
+	switch format {
+	case ExportFormatText:
+		return writeTextTranscript(w, loc, meeting, chats)
+	case ExportFormatJSON:
+		return writeJSONTranscript(w, loc, meeting, chats)
+	case ExportFormatCSV:
+		return writeCSVTranscript(w, loc, chats)
+	case ExportFormatHTML:
+		return writeHTMLTranscript(w, loc, meeting, chats)
+	default:
+		return ErrUnsupportedExportFormat
+	}
+}
+
+func chatDisplayName(chat *models.MeetingChat) string {
+	if chat.User == nil && chat.Guest == nil && chat.UserID != "" {
+		return chat.UserID
+	}
+	return chat.DisplayName()
+}
+
+func writeTextTranscript(w io.Writer, loc *time.Location, meeting *models.Meeting, chats []*models.MeetingChat) error {
+	if _, err := fmt.Fprintf(w, "%s (%s)\n\n", meeting.Title, meeting.MeetingCode); err != nil {
+		return err
+	}
+	for _, chat := range chats {
+		_, err := fmt.Fprintf(w, "[%s] %s: %s\n", chat.SentAt.In(loc).Format("2006-01-02 15:04:05 MST"), chatDisplayName(chat), chat.Message)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSONTranscript(w io.Writer, loc *time.Location, meeting *models.Meeting, chats []*models.MeetingChat) error {
+	header, err := json.Marshal(map[string]string{
+		"meetingId":   meeting.ID,
+		"title":       meeting.Title,
+		"meetingCode": meeting.MeetingCode,
+		"timezone":    loc.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// write the header object without its closing brace so messages can be streamed one at a time
+	if _, err := fmt.Fprintf(w, "%s,\"messages\":[", header[:len(header)-1]); err != nil {
+		return err
+	}
+	for i, chat := range chats {
+		entry, err := json.Marshal(transcriptEntry{
+			SentAt:      chat.SentAt.In(loc).Format(time.RFC3339),
+			UserID:      chat.UserID,
+			DisplayName: chatDisplayName(chat),
+			Message:     chat.Message,
+		})
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(entry); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+func writeCSVTranscript(w io.Writer, loc *time.Location, chats []*models.MeetingChat) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"sent_at", "user_id", "display_name", "message"}); err != nil {
+		return err
+	}
+	for _, chat := range chats {
+		record := []string{
+			chat.SentAt.In(loc).Format(time.RFC3339),
+			chat.UserID,
+			csvText(chatDisplayName(chat)),
+			csvText(chat.Message),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvText keeps spreadsheets from evaluating text participants wrote as a formula, cells starting
+// with one of the characters that begin a formula get a leading apostrophe
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func writeHTMLTranscript(w io.Writer, loc *time.Location, meeting *models.Meeting, chats []*models.MeetingChat) error {
+	title := html.EscapeString(meeting.Title)
+	_, err := fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s - chat transcript</title>\n</head>\n<body>\n<h1>%s</h1>\n<ul>\n", title, title)
+	if err != nil {
+		return err
+	}
+	for _, chat := range chats {
+		sentAt := chat.SentAt.In(loc)
+		_, err := fmt.Fprintf(w, "<li><time datetime=\"%s\">%s</time> <strong>%s</strong>: %s</li>\n",
+			sentAt.Format(time.RFC3339),
+			sentAt.Format("2006-01-02 15:04:05 MST"),
+			html.EscapeString(chatDisplayName(chat)),
+			html.EscapeString(chat.Message),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "</ul>\n</body>\n</html>\n")
+	return err
+}
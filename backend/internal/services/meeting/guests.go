@@ -0,0 +1,112 @@
+package meeting
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/ratelimit"
+)
+
+var (
+	ErrGuestsNotAllowed = errors.New("this meeting does not allow guests")
+	ErrInvalidGuestName = errors.New("guests need a display name")
+)
+
+// SetAllowGuests lets people without an account join the meeting with a display name, or stops
+// new guests from joining. Only the host can do this, guests who joined stay in the meeting.
+func (s *MeetingService) SetAllowGuests(ctx context.Context, meetingID string, userID string, allow bool) (*models.Meeting, error) {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermChangeSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	meeting.AllowGuests = allow
+	if err := s.meetingRepo.Update(ctx, meeting); err != nil {
+		return nil, err
+	}
+	return meeting, nil
+}
+
+// JoinAsGuest adds someone without an account to a meeting that allows guests. The same checks as
+// for users apply, except invite only meetings never take guests. In meetings with a waiting room
+// the guest waits until a host admits them. Every join counts against the client's guest join limit.
+func (s *MeetingService) JoinAsGuest(ctx context.Context, clientIP string, meetingCode string, displayName string, password string) (*models.Meeting, *models.MeetingParticipant, error) {
+	displayName = strings.TrimSpace(displayName)
+	if displayName == "" {
+		return nil, nil, ErrInvalidGuestName
+	}
+
+	limits := map[*ratelimit.Limiter]string{
+		s.joinIPLimiter:    clientIP,
+		s.joinCodeLimiter:  meetingCode,
+		s.guestJoinLimiter: clientIP,
+	}
+	if err := ratelimit.AllowAll(ctx, limits); err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	meeting, err := s.meetingRepo.GetByCode(ctx, meetingCode)
+	if err != nil {
+		meeting, err = s.seriesOccurrence(ctx, meetingCode, now)
+	}
+	if err != nil {
+		if errors.Is(err, ErrMeetingNotFound) {
+			ratelimit.FailAll(ctx, map[*ratelimit.Limiter]string{s.joinIPLimiter: clientIP})
+		}
+		return nil, nil, err
+	}
+
+	if meeting.IsPrivate && !checkMeetingPassword(meeting.PasswordHash, password) {
+		ratelimit.FailAll(ctx, limits)
+		return nil, nil, ErrInvalidPassword
+	}
+	if !meeting.AllowGuests {
+		return nil, nil, ErrGuestsNotAllowed
+	}
+	if meeting.InviteOnly {
+		return nil, nil, ErrNotInvited
+	}
+
+	guest := &models.MeetingGuest{
+		ID:          uuid.NewString(),
+		MeetingID:   meeting.ID,
+		DisplayName: displayName,
+		CreatedAt:   now,
+	}
+	if err := s.checkJoinWindow(meeting, guest.ID, now); err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkAdmittance(meeting, nil, guest.ID); err != nil {
+		return nil, nil, err
+	}
+
+	participant := &models.MeetingParticipant{
+		MeetingID: meeting.ID,
+		Role:      models.MeetingParticipantNormal,
+		Status:    models.ParticipantAdmitted,
+		JoinedAt:  now,
+		Guest:     guest,
+	}
+	if meeting.WaitingRoom {
+		participant.Status = models.ParticipantWaiting
+	}
+	if err := s.meetingRepo.AddGuest(ctx, guest, participant); err != nil {
+		return nil, nil, err
+	}
+	// a denied guest can only come back as a new one, which keeps the lobby from being flooded
+	ratelimit.FailAll(ctx, map[*ratelimit.Limiter]string{s.guestJoinLimiter: clientIP})
+
+	if participant.Status == models.ParticipantWaiting {
+		if err := s.notifyLobbyJoined(ctx, meeting, participant); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return meeting, participant, nil
+}
@@ -0,0 +1,270 @@
+package meeting
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+// CalendarContentType is the MIME type of the iCalendar files written by WriteCalendar
+const CalendarContentType = "text/calendar; charset=utf-8"
+
+const (
+	icsProductID  = "-//Meetia//Meetia API//EN"
+	icsUIDDomain  = "meetia"
+	icsUTCFormat  = "20060102T150405Z"
+	icsDateFormat = "20060102T150405"
+	// icsLineLimit is the longest line in octets before it has to be folded
+	icsLineLimit = 75
+	// icsTimezoneSpan is how far past the start of a series its timezone transitions are listed
+	icsTimezoneSpan = 10 * 365 * 24 * time.Hour
+)
+
+// JoinURL is the link to the join page of the frontend with the code filled in
+func (s *MeetingService) JoinURL(code string) string {
+	return strings.TrimRight(s.cfg.AppBaseURL, "/") + "/join?code=" + url.QueryEscape(code)
+}
+
+// GetMeetingInvite returns the meeting for its calendar invite to the host, admitted participants
+// and users invited to it who haven't declined
+func (s *MeetingService) GetMeetingInvite(ctx context.Context, meetingID string, userID string) (*models.Meeting, error) {
+	meeting, err := s.participantMeeting(ctx, meetingID, userID)
+	if !errors.Is(err, ErrNotAuthorized) {
+		return meeting, err
+	}
+
+	meeting, err = s.meetingRepo.GetByID(ctx, meetingID)
+	if err != nil {
+		return nil, ErrMeetingNotFound
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrNotAuthorized
+	}
+	invitation, err := s.meetingRepo.FindInvitation(ctx, meeting.ID, userID, invitationEmail(user))
+	if err != nil || invitation.Status == models.InvitationDeclined {
+		return nil, ErrNotAuthorized
+	}
+	return meeting, nil
+}
+
+// WriteCalendar writes an RFC 5545 calendar with an event for every meeting and series. Series
+// repeat by their RRULE, their occurrences stored as meetings should not be passed again.
+// name labels the calendar in clients subscribing to it and may be empty.
+func (s *MeetingService) WriteCalendar(w io.Writer, name string, meetings []*models.Meeting, series []*models.MeetingSeries) error {
+	c := &icsWriter{}
+	c.line("BEGIN:VCALENDAR")
+	c.line("VERSION:2.0")
+	c.line("PRODID:" + icsProductID)
+	c.line("CALSCALE:GREGORIAN")
+	c.line("METHOD:PUBLISH")
+	if name != "" {
+		c.line("X-WR-CALNAME:" + icsText(name))
+	}
+
+	// every timezone a series repeats in needs its definition in the calendar
+	var timezones []string
+	for _, ser := range series {
+		if !slices.Contains(timezones, ser.Timezone) {
+			timezones = append(timezones, ser.Timezone)
+			c.timezone(ser.Timezone, ser.StartsAt)
+		}
+	}
+
+	for _, m := range meetings {
+		s.writeMeetingEvent(c, m)
+	}
+	for _, ser := range series {
+		s.writeSeriesEvent(c, ser)
+	}
+
+	c.line("END:VCALENDAR")
+	_, err := io.WriteString(w, c.String())
+	return err
+}
+
+func (s *MeetingService) writeMeetingEvent(c *icsWriter, m *models.Meeting) {
+	// instant meetings start when they are created
+	start := m.CreatedAt
+	duration := s.cfg.DefaultDuration
+	if m.IsScheduled() {
+		start = m.ScheduledAt
+		duration = time.Duration(m.DurationMinutes) * time.Minute
+	}
+
+	c.line("BEGIN:VEVENT")
+	c.line("UID:" + m.ID + "@" + icsUIDDomain)
+	c.line("DTSTAMP:" + m.UpdatedAt.UTC().Format(icsUTCFormat))
+	c.line("DTSTART:" + start.UTC().Format(icsUTCFormat))
+	c.line("DTEND:" + start.Add(duration).UTC().Format(icsUTCFormat))
+	s.writeEventDetails(c, m.Title, m.MeetingCode, m.Host)
+	if !m.EndedAt.IsZero() {
+		c.line("STATUS:CANCELLED")
+	} else {
+		c.line("STATUS:CONFIRMED")
+	}
+	c.line("END:VEVENT")
+}
+
+func (s *MeetingService) writeSeriesEvent(c *icsWriter, ser *models.MeetingSeries) {
+	loc, err := time.LoadLocation(ser.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	tzid := ";TZID=" + ser.Timezone + ":"
+
+	c.line("BEGIN:VEVENT")
+	c.line("UID:" + ser.ID + "@" + icsUIDDomain)
+	c.line("DTSTAMP:" + ser.UpdatedAt.UTC().Format(icsUTCFormat))
+	c.line("DTSTART" + tzid + ser.StartsAt.In(loc).Format(icsDateFormat))
+	c.line("DTEND" + tzid + ser.StartsAt.Add(ser.Duration()).In(loc).Format(icsDateFormat))
+	c.line("RRULE:" + ser.RRule)
+	for _, exdate := range ser.ExDates {
+		c.line("EXDATE" + tzid + exdate.In(loc).Format(icsDateFormat))
+	}
+	s.writeEventDetails(c, ser.Title, ser.MeetingCode, ser.Host)
+	if !ser.EndedAt.IsZero() {
+		c.line("STATUS:CANCELLED")
+	} else {
+		c.line("STATUS:CONFIRMED")
+	}
+	c.line("END:VEVENT")
+}
+
+func (s *MeetingService) writeEventDetails(c *icsWriter, title, code string, host *models.User) {
+	joinURL := s.JoinURL(code)
+	c.line("SUMMARY:" + icsText(title))
+	c.line("DESCRIPTION:" + icsText("Join the meeting: "+joinURL+"\nMeeting code: "+code))
+	c.line("LOCATION:" + icsText(joinURL))
+	c.line("URL:" + joinURL)
+	if host != nil {
+		c.line("ORGANIZER;CN=" + icsParam(host.DisplayName) + ":mailto:" + host.Email)
+	}
+}
+
+// icsWriter collects the content lines of a calendar, folding and terminating them as RFC 5545 requires
+type icsWriter struct {
+	strings.Builder
+}
+
+func (c *icsWriter) line(content string) {
+	limit := icsLineLimit
+	for len(content) > limit {
+		// fold without splitting a UTF-8 sequence
+		cut := limit
+		for cut > 0 && !isRuneStart(content[cut]) {
+			cut--
+		}
+		c.WriteString(content[:cut])
+		c.WriteString("\r\n ")
+		content = content[cut:]
+		// the space starting a continuation line counts towards the limit
+		limit = icsLineLimit - 1
+	}
+	c.WriteString(content)
+	c.WriteString("\r\n")
+}
+
+// timezone writes a VTIMEZONE for an IANA zone, listing its offset changes from the given start
+// for icsTimezoneSpan. Clients need it to place TZID times of recurring events.
+func (c *icsWriter) timezone(name string, from time.Time) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	c.line("BEGIN:VTIMEZONE")
+	c.line("TZID:" + name)
+
+	// the first observance covers the time before the first transition
+	start := from.In(loc)
+	_, offset := start.Zone()
+	c.observance(start, offset)
+
+	for _, transition := range zoneTransitions(loc, start, start.Add(icsTimezoneSpan)) {
+		_, before := transition.Add(-time.Second).Zone()
+		c.observance(transition, before)
+	}
+
+	c.line("END:VTIMEZONE")
+}
+
+// observance writes a STANDARD or DAYLIGHT block for the offset that starts at t
+func (c *icsWriter) observance(t time.Time, offsetFrom int) {
+	kind := "STANDARD"
+	if t.IsDST() {
+		kind = "DAYLIGHT"
+	}
+	abbreviation, offsetTo := t.Zone()
+
+	c.line("BEGIN:" + kind)
+	// DTSTART is the local time of the transition in the offset it replaces
+	c.line("DTSTART:" + t.UTC().Add(time.Duration(offsetFrom)*time.Second).Format(icsDateFormat))
+	c.line("TZOFFSETFROM:" + icsOffset(offsetFrom))
+	c.line("TZOFFSETTO:" + icsOffset(offsetTo))
+	c.line("TZNAME:" + icsText(abbreviation))
+	c.line("END:" + kind)
+}
+
+// zoneTransitions finds the instants the UTC offset of loc changes between from and to
+func zoneTransitions(loc *time.Location, from, to time.Time) []time.Time {
+	var transitions []time.Time
+	_, offset := from.In(loc).Zone()
+	for t := from; t.Before(to); t = t.Add(24 * time.Hour) {
+		next := t.Add(24 * time.Hour)
+		_, nextOffset := next.In(loc).Zone()
+		if nextOffset == offset {
+			continue
+		}
+
+		// offsets change at most once a day, narrow it down to the second
+		lo, hi := t, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, midOffset := mid.In(loc).Zone(); midOffset == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		transitions = append(transitions, hi.Truncate(time.Second).In(loc))
+		offset = nextOffset
+	}
+	return transitions
+}
+
+func icsOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	offset := time.Date(0, 1, 1, 0, 0, seconds, 0, time.UTC)
+	return sign + offset.Format("1504")
+}
+
+// icsText escapes a TEXT value
+func icsText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(value)
+}
+
+// icsParam quotes a parameter value, which can't contain double quotes
+func icsParam(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, "'") + `"`
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,258 @@
+package meeting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	netmail "net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/services/mail"
+)
+
+var (
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvalidInvitee     = errors.New("invalid invitee")
+	ErrNoInvitees         = errors.New("at least one user or email address is required")
+	ErrNotInvited         = errors.New("this meeting is invite only")
+)
+
+// Invitees are the people a host invites, registered users by id or anyone by email address
+type Invitees struct {
+	UserIDs []string
+	Emails  []string
+}
+
+// InviteToMeeting invites users to a meeting and emails them, only the host can do this.
+// Addresses that were already invited keep their invitation and get no second email.
+// It returns every invitation of the meeting.
+func (s *MeetingService) InviteToMeeting(ctx context.Context, meetingID string, hostID string, invitees Invitees) ([]*models.MeetingInvitation, error) {
+	if len(invitees.UserIDs) == 0 && len(invitees.Emails) == 0 {
+		return nil, ErrNoInvitees
+	}
+
+	meeting, err := s.authorize(ctx, meetingID, hostID, PermManageInvitations)
+	if err != nil {
+		return nil, err
+	}
+	if meeting.Status(time.Now()) == models.MeetingStatusPast {
+		return nil, ErrMeetingEnded
+	}
+
+	// resolve everyone to an address first, so a typo doesn't leave half the list invited
+	recipients := map[string]*models.User{}
+	var emails []string
+	for _, id := range invitees.UserIDs {
+		user, err := s.userRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown user %s", ErrInvalidInvitee, id)
+		}
+		email := strings.ToLower(user.Email)
+		if _, ok := recipients[email]; !ok {
+			emails = append(emails, email)
+		}
+		recipients[email] = user
+	}
+	for _, raw := range invitees.Emails {
+		address, err := netmail.ParseAddress(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not an email address", ErrInvalidInvitee, raw)
+		}
+		email := strings.ToLower(address.Address)
+		if _, ok := recipients[email]; ok {
+			continue
+		}
+		// the account, if the address has one, so the invitation shows up for its owner. An unverified
+		// address may have been registered by someone else, its owner is matched once they verify it.
+		user, err := s.userRepo.GetByEmail(ctx, address.Address)
+		if err != nil || !user.IsEmailVerified() {
+			user = nil
+		}
+		recipients[email] = user
+		emails = append(emails, email)
+	}
+
+	for _, email := range emails {
+		user := recipients[email]
+		if user != nil && user.ID == hostID {
+			continue
+		}
+
+		invitation := &models.MeetingInvitation{
+			ID:        uuid.NewString(),
+			MeetingID: meeting.ID,
+			InvitedBy: hostID,
+			Email:     email,
+			Status:    models.InvitationPending,
+			CreatedAt: time.Now(),
+		}
+		if user != nil {
+			invitation.UserID = user.ID
+		}
+
+		created, err := s.meetingRepo.CreateInvitation(ctx, invitation)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			s.sendInvitation(ctx, meeting, invitation, user)
+		}
+	}
+
+	return s.meetingRepo.ListInvitations(ctx, meeting.ID)
+}
+
+// ListMeetingInvitations returns the invitations of a meeting to its host
+func (s *MeetingService) ListMeetingInvitations(ctx context.Context, meetingID string, hostID string) ([]*models.MeetingInvitation, error) {
+	meeting, err := s.authorize(ctx, meetingID, hostID, PermManageInvitations)
+	if err != nil {
+		return nil, err
+	}
+	return s.meetingRepo.ListInvitations(ctx, meeting.ID)
+}
+
+// RevokeInvitation withdraws an invitation, in an invite only meeting the invitee can no longer join
+func (s *MeetingService) RevokeInvitation(ctx context.Context, meetingID string, hostID string, invitationID string) error {
+	meeting, err := s.authorize(ctx, meetingID, hostID, PermManageInvitations)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.meetingRepo.DeleteInvitation(ctx, invitationID, meeting.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// SetInviteOnly restricts joining to the host and invited users, or lifts the restriction
+func (s *MeetingService) SetInviteOnly(ctx context.Context, meetingID string, hostID string, inviteOnly bool) (*models.Meeting, error) {
+	meeting, err := s.authorize(ctx, meetingID, hostID, PermChangeSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	meeting.InviteOnly = inviteOnly
+	if err := s.meetingRepo.Update(ctx, meeting); err != nil {
+		return nil, err
+	}
+	return meeting, nil
+}
+
+// ListUserInvitations returns the invitations addressed to the user, an empty status returns all of them
+func (s *MeetingService) ListUserInvitations(ctx context.Context, userID string, status models.InvitationStatus) ([]*models.MeetingInvitation, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrNotAuthorized
+	}
+	return s.meetingRepo.ListInvitationsForUser(ctx, userID, invitationEmail(user), status)
+}
+
+// RespondToInvitation accepts or declines an invitation addressed to the user. Invitations sent
+// to an address are linked to the account answering them.
+func (s *MeetingService) RespondToInvitation(ctx context.Context, invitationID string, userID string, accept bool) (*models.MeetingInvitation, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrNotAuthorized
+	}
+
+	invitation, err := s.meetingRepo.GetInvitation(ctx, invitationID)
+	if err != nil {
+		return nil, ErrInvitationNotFound
+	}
+	// hide invitations of other users
+	if invitation.UserID != userID && (invitationEmail(user) == "" || invitation.Email != invitationEmail(user)) {
+		return nil, ErrInvitationNotFound
+	}
+
+	invitation.Status = models.InvitationDeclined
+	if accept {
+		invitation.Status = models.InvitationAccepted
+	}
+	invitation.UserID = userID
+	invitation.RespondedAt = time.Now()
+	if err := s.meetingRepo.UpdateInvitation(ctx, invitation); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// checkInvitation lets only the host and invited users into invite only meetings.
+// Joining accepts a pending invitation, a declined one has to be accepted first.
+func (s *MeetingService) checkInvitation(ctx context.Context, meeting *models.Meeting, userID string) error {
+	if !meeting.InviteOnly || meeting.HostID == userID {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrNotInvited
+	}
+	invitation, err := s.meetingRepo.FindInvitation(ctx, meeting.ID, userID, invitationEmail(user))
+	if err != nil || invitation.Status == models.InvitationDeclined {
+		return ErrNotInvited
+	}
+
+	if invitation.Status == models.InvitationPending {
+		invitation.Status = models.InvitationAccepted
+		invitation.UserID = userID
+		invitation.RespondedAt = time.Now()
+		if err := s.meetingRepo.UpdateInvitation(ctx, invitation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invitationEmail is the address invitations are matched to the user by. Unverified addresses match
+// nothing, whoever registers or switches to an invited address must prove they own it first.
+func invitationEmail(user *models.User) string {
+	if !user.IsEmailVerified() {
+		return ""
+	}
+	return strings.ToLower(user.Email)
+}
+
+// sendInvitation emails an invitee, a failed email doesn't undo the invitation
+func (s *MeetingService) sendInvitation(ctx context.Context, meeting *models.Meeting, invitation *models.MeetingInvitation, invitee *models.User) {
+	greeting := "Hi,"
+	if invitee != nil {
+		greeting = "Hi " + invitee.DisplayName + ","
+	}
+	inviter := "The host"
+	if meeting.Host != nil {
+		inviter = meeting.Host.DisplayName
+	}
+
+	details := ""
+	if meeting.IsScheduled() {
+		loc, err := time.LoadLocation(meeting.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		details += fmt.Sprintf("\n\nIt starts %s and runs for %d minutes.", meeting.ScheduledAt.In(loc).Format("Mon, 02 Jan 2006 15:04 MST"), meeting.DurationMinutes)
+	}
+	if meeting.IsPrivate {
+		details += "\n\nThe meeting needs a password, ask " + inviter + " for it."
+	}
+
+	err := s.mailer.Send(ctx, mail.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("%s invited you to %q on Meetia", inviter, meeting.Title),
+		Body: fmt.Sprintf(
+			"%s\n\n%s invited you to the meeting %q.%s\n\nJoin it with the link below:\n\n%s\n\nYou can accept or decline the invitation in your dashboard:\n\n%s\n",
+			greeting, inviter, meeting.Title, details, s.JoinURL(meeting.MeetingCode), strings.TrimRight(s.cfg.AppBaseURL, "/")+"/dashboard",
+		),
+	})
+	if err != nil {
+		slog.Error("failed to send meeting invitation", "invitation_id", invitation.ID, "error", err)
+	}
+}
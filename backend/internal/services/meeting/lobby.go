@@ -0,0 +1,158 @@
+package meeting
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+var (
+	ErrAdmissionDenied = errors.New("the host denied you entry to this meeting")
+	ErrNotParticipant  = errors.New("not a participant of this meeting")
+)
+
+// Events pushed over signaling while people wait in the lobby
+const (
+	// EventLobbyJoined tells hosts and co-hosts someone is waiting, with a LobbyEntry
+	EventLobbyJoined = "lobby-joined"
+	// EventLobbyUpdated tells hosts and co-hosts who left the lobby, with a LobbyUpdate
+	EventLobbyUpdated = "lobby-updated"
+	// EventAdmitted tells a waiting participant they may connect
+	EventAdmitted = "admitted"
+	// EventDenied tells a waiting participant they were turned away
+	EventDenied = "denied"
+)
+
+// LobbyEntry is someone waiting to be admitted
+type LobbyEntry struct {
+	UserID       string    `json:"userId"` // the guest id for guests
+	DisplayName  string    `json:"displayName"`
+	Guest        bool      `json:"guest,omitempty"`
+	WaitingSince time.Time `json:"waitingSince"`
+}
+
+// LobbyUpdate lists the users that were admitted or denied
+type LobbyUpdate struct {
+	UserIDs []string                 `json:"userIds"`
+	Status  models.ParticipantStatus `json:"status"`
+}
+
+// SetWaitingRoom turns the lobby of a meeting on or off, only the host can do this.
+// Turning it off admits everyone still waiting.
+func (s *MeetingService) SetWaitingRoom(ctx context.Context, meetingID string, hostID string, enabled bool) (*models.Meeting, error) {
+	meeting, err := s.authorize(ctx, meetingID, hostID, PermChangeSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	meeting.WaitingRoom = enabled
+	if err := s.meetingRepo.Update(ctx, meeting); err != nil {
+		return nil, err
+	}
+
+	if !enabled {
+		if _, err := s.decideAdmission(ctx, meeting, nil, models.ParticipantAdmitted); err != nil {
+			return nil, err
+		}
+	}
+	return meeting, nil
+}
+
+// ListLobby returns the participants waiting for admission, longest waiting first
+func (s *MeetingService) ListLobby(ctx context.Context, meetingID string, userID string) ([]*models.MeetingParticipant, error) {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermManageLobby)
+	if err != nil {
+		return nil, err
+	}
+	return s.meetingRepo.ListParticipantsByStatus(ctx, meeting.ID, models.ParticipantWaiting)
+}
+
+// AdmitParticipants lets waiting users into the meeting, everyone waiting when userIDs is nil.
+// It returns the users that were admitted, users that weren't waiting are skipped.
+func (s *MeetingService) AdmitParticipants(ctx context.Context, meetingID string, moderatorID string, userIDs []string) ([]string, error) {
+	meeting, err := s.authorize(ctx, meetingID, moderatorID, PermManageLobby)
+	if err != nil {
+		return nil, err
+	}
+	return s.decideAdmission(ctx, meeting, userIDs, models.ParticipantAdmitted)
+}
+
+// DenyParticipants turns waiting users away, everyone waiting when userIDs is nil. Denied users
+// can't join the meeting again. It returns the users that were denied.
+func (s *MeetingService) DenyParticipants(ctx context.Context, meetingID string, moderatorID string, userIDs []string) ([]string, error) {
+	meeting, err := s.authorize(ctx, meetingID, moderatorID, PermManageLobby)
+	if err != nil {
+		return nil, err
+	}
+	return s.decideAdmission(ctx, meeting, userIDs, models.ParticipantDenied)
+}
+
+// SignalingStatus tells whether the user may connect to the signaling of a meeting, waiting
+// participants may connect to hear about their admission but get no peer connection
+func (s *MeetingService) SignalingStatus(ctx context.Context, meetingID string, userID string) (models.ParticipantStatus, error) {
+	meeting, err := s.meetingRepo.GetByID(ctx, meetingID)
+	if err != nil {
+		return "", ErrMeetingNotFound
+	}
+	if meeting.Status(time.Now()) == models.MeetingStatusPast {
+		return "", ErrMeetingEnded
+	}
+
+	participant, err := s.meetingRepo.GetParticipant(ctx, meeting.ID, userID)
+	if err != nil {
+		return "", ErrNotParticipant
+	}
+	if participant.Status == models.ParticipantDenied {
+		return "", ErrAdmissionDenied
+	}
+	return participant.Status, nil
+}
+
+func (s *MeetingService) decideAdmission(ctx context.Context, meeting *models.Meeting, userIDs []string, status models.ParticipantStatus) ([]string, error) {
+	changed, err := s.meetingRepo.SetParticipantStatus(ctx, meeting.ID, userIDs, models.ParticipantWaiting, status)
+	if err != nil {
+		return nil, err
+	}
+	if len(changed) == 0 {
+		return changed, nil
+	}
+
+	event := EventAdmitted
+	if status == models.ParticipantDenied {
+		event = EventDenied
+	}
+	s.signaling.Notify(meeting.ID, changed, event, nil)
+
+	// other hosts drop them from their lobby too
+	moderators, err := s.holders(ctx, meeting, PermManageLobby)
+	if err != nil {
+		return nil, err
+	}
+	s.signaling.Notify(meeting.ID, moderators, EventLobbyUpdated, LobbyUpdate{UserIDs: changed, Status: status})
+	return changed, nil
+}
+
+// notifyLobbyJoined tells hosts and co-hosts someone is waiting to be admitted
+func (s *MeetingService) notifyLobbyJoined(ctx context.Context, meeting *models.Meeting, participant *models.MeetingParticipant) error {
+	if participant.User == nil && participant.UserID != "" {
+		user, err := s.userRepo.GetByID(ctx, participant.UserID)
+		if err != nil {
+			return err
+		}
+		participant.User = user
+	}
+	moderators, err := s.holders(ctx, meeting, PermManageLobby)
+	if err != nil {
+		return err
+	}
+
+	s.signaling.Notify(meeting.ID, moderators, EventLobbyJoined, LobbyEntry{
+		UserID:       participant.MemberID(),
+		DisplayName:  participant.DisplayName(),
+		Guest:        participant.GuestID != "",
+		WaitingSince: participant.JoinedAt,
+	})
+	return nil
+}
@@ -2,36 +2,123 @@ package meeting
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
 	"time"
 
 	gonanoid "github.com/matoous/go-nanoid/v2"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/meetia/backend/internal/models"
 	"github.com/meetia/backend/internal/repository"
+	"github.com/meetia/backend/internal/services/mail"
+	"github.com/meetia/backend/internal/services/ratelimit"
 )
 
 var (
-	ErrMeetingNotFound = errors.New("meeting not found")
-	ErrNotAuthorized   = errors.New("not authorized to access this meeting")
-	ErrInvalidPassword = errors.New("invalid meeting password")
+	ErrMeetingNotFound   = errors.New("meeting not found")
+	ErrNotAuthorized     = errors.New("not authorized to access this meeting")
+	ErrInvalidPassword   = errors.New("invalid meeting password")
+	ErrPasswordRequired  = errors.New("private meetings need a password")
+	ErrMeetingEnded      = errors.New("meeting has ended")
+	ErrMeetingNotStarted = errors.New("meeting has not opened yet")
+	ErrInvalidSchedule   = errors.New("invalid meeting schedule")
 )
 
+// Config controls scheduling rules of the MeetingService
+type Config struct {
+	// EarlyJoinWindow is how long before the scheduled start participants may join, the host can always join
+	EarlyJoinWindow time.Duration
+	// OverrunGrace is how long a scheduled meeting may run past its planned end before it is ended
+	OverrunGrace time.Duration
+	// DefaultDuration applies to scheduled meetings created without a duration
+	DefaultDuration time.Duration
+	// AppBaseURL is the frontend URL used to build join links
+	AppBaseURL string
+	// MaxParticipants caps the users connected to a meeting unless the host set a lower limit
+	MaxParticipants int
+}
+
+// Signaling reaches the users of a meeting connected to its signaling websocket
+type Signaling interface {
+	// Notify pushes an event to the given users
+	Notify(meetingID string, userIDs []string, event string, data any)
+	// MoveToBreakout moves a user's media into a breakout room, an empty breakoutID moves them back
+	MoveToBreakout(meetingID string, breakoutID string, userID string)
+	// CloseBreakouts moves everyone in breakout rooms back to the meeting
+	CloseBreakouts(meetingID string)
+	// ConnectedUsers lists the users whose media is connected to the meeting
+	ConnectedUsers(meetingID string) []string
+	// LowerHands takes users out of the speaking queue, everyone when userIDs is nil
+	LowerHands(meetingID string, userIDs []string) []string
+	// Disconnect closes the media connections to the meeting of everyone but the users in keep
+	Disconnect(meetingID string, keep []string)
+}
+
+// Schedule plans a meeting for later, the zero value creates an instant meeting
+type Schedule struct {
+	StartAt  time.Time
+	Duration time.Duration
+	// Timezone is the IANA name the start was picked in, calendars show the meeting in it
+	Timezone string
+}
+
 const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
 type MeetingService struct {
 	meetingRepo *repository.MeetingRepository
 	userRepo    *repository.UserRepository
+	mailer      mail.Mailer
+	signaling   Signaling
+	cfg         Config
+
+	// throttle meeting code enumeration and password guessing
+	joinIPLimiter   *ratelimit.Limiter
+	joinCodeLimiter *ratelimit.Limiter
+	joinUserLimiter *ratelimit.Limiter
+	// every guest join creates a guest and a lobby entry, so successful joins count too
+	guestJoinLimiter *ratelimit.Limiter
 }
 
-func NewMeetingService(meetingRepo *repository.MeetingRepository, userRepo *repository.UserRepository) *MeetingService {
+func NewMeetingService(meetingRepo *repository.MeetingRepository, userRepo *repository.UserRepository, limitStore ratelimit.Store, mailer mail.Mailer, signaling Signaling, cfg Config) *MeetingService {
 	return &MeetingService{
 		meetingRepo: meetingRepo,
 		userRepo:    userRepo,
+		mailer:      mailer,
+		signaling:   signaling,
+		cfg:         cfg,
+		joinIPLimiter: ratelimit.NewLimiter(limitStore, "join-ip", ratelimit.Policy{
+			FreeAttempts: 20,
+			BaseDelay:    time.Second,
+			MaxDelay:     15 * time.Minute,
+			ResetAfter:   time.Hour,
+		}),
+		// generous, every wrong guess on a code also delays legitimate joiners
+		joinCodeLimiter: ratelimit.NewLimiter(limitStore, "join-code", ratelimit.Policy{
+			FreeAttempts: 20,
+			BaseDelay:    time.Second,
+			MaxDelay:     time.Minute,
+			ResetAfter:   time.Hour,
+		}),
+		joinUserLimiter: ratelimit.NewLimiter(limitStore, "join-user", ratelimit.Policy{
+			FreeAttempts: 5,
+			BaseDelay:    2 * time.Second,
+			MaxDelay:     10 * time.Minute,
+			ResetAfter:   time.Hour,
+		}),
+		guestJoinLimiter: ratelimit.NewLimiter(limitStore, "guest-join", ratelimit.Policy{
+			FreeAttempts: 5,
+			BaseDelay:    30 * time.Second,
+			MaxDelay:     30 * time.Minute,
+			ResetAfter:   time.Hour,
+		}),
 	}
 }
 
-func (s *MeetingService) CreateMeeting(ctx context.Context, title string, hostID string, isPrivate bool, password string) (*models.Meeting, error) {
+func (s *MeetingService) CreateMeeting(ctx context.Context, title string, hostID string, isPrivate bool, password string, inviteOnly bool, waitingRoom bool, schedule Schedule) (*models.Meeting, error) {
 	// generate unique meeting code
 	meetingCode := generateMeetingCode(10)
 
@@ -40,11 +127,27 @@ func (s *MeetingService) CreateMeeting(ctx context.Context, title string, hostID
 		HostID:      hostID,
 		MeetingCode: meetingCode,
 		IsPrivate:   isPrivate,
-		Password:    password,
+		InviteOnly:  inviteOnly,
+		WaitingRoom: waitingRoom,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
+	if err := s.applySchedule(meeting, schedule); err != nil {
+		return nil, err
+	}
+
+	if isPrivate {
+		if password == "" {
+			return nil, ErrPasswordRequired
+		}
+		hash, err := hashMeetingPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		meeting.PasswordHash = hash
+	}
+
 	if err := s.meetingRepo.Create(ctx, meeting); err != nil {
 		return nil, err
 	}
@@ -53,7 +156,7 @@ func (s *MeetingService) CreateMeeting(ctx context.Context, title string, hostID
 	participant := &models.MeetingParticipant{
 		MeetingID: meeting.ID,
 		UserID:    hostID,
-		Role:      "host",
+		Role:      models.MeetingParticipantHost,
 	}
 
 	if err := s.meetingRepo.AddParticipant(ctx, participant); err != nil {
@@ -63,98 +166,415 @@ func (s *MeetingService) CreateMeeting(ctx context.Context, title string, hostID
 	return meeting, nil
 }
 
-func (s *MeetingService) JoinMeeting(ctx context.Context, meetingCode string, userID string, password string) (*models.Meeting, error) {
+// JoinMeeting adds the user to the meeting. In meetings with a waiting room the participant
+// returned waits until a host admits them, the hosts are notified.
+func (s *MeetingService) JoinMeeting(ctx context.Context, clientIP string, meetingCode string, userID string, password string) (*models.Meeting, *models.MeetingParticipant, error) {
+	limits := map[*ratelimit.Limiter]string{
+		s.joinIPLimiter:   clientIP,
+		s.joinCodeLimiter: meetingCode,
+		s.joinUserLimiter: meetingCode + ":" + userID,
+	}
+	if err := ratelimit.AllowAll(ctx, limits); err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
 	meeting, err := s.meetingRepo.GetByCode(ctx, meetingCode)
 	if err != nil {
-		return nil, ErrMeetingNotFound
+		meeting, err = s.seriesOccurrence(ctx, meetingCode, now)
+	}
+	if err != nil {
+		if errors.Is(err, ErrMeetingNotFound) {
+			ratelimit.FailAll(ctx, map[*ratelimit.Limiter]string{s.joinIPLimiter: clientIP})
+		}
+		return nil, nil, err
+	}
+
+	if meeting.IsPrivate && !checkMeetingPassword(meeting.PasswordHash, password) {
+		ratelimit.FailAll(ctx, limits)
+		return nil, nil, ErrInvalidPassword
 	}
 
-	if meeting.IsPrivate && meeting.Password != password {
-		return nil, ErrInvalidPassword
+	if err := s.checkJoinWindow(meeting, userID, now); err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.checkInvitation(ctx, meeting, userID); err != nil {
+		return nil, nil, err
+	}
+
+	// the first join of a series occurrence stores it, keeping its participants and chat apart
+	if meeting.ID == "" {
+		if meeting, err = s.storeOccurrence(ctx, meeting); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// check if user is already a participant
 	participants, err := s.meetingRepo.GetParticipants(ctx, meeting.ID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	var isParticipant bool
+	var participant *models.MeetingParticipant
 	for _, p := range participants {
 		if p.UserID == userID {
-			isParticipant = true
-			// update joined_at if they're rejoining
-			if p.LeftAt.After(time.Time{}) {
-				p.JoinedAt = time.Now()
-				p.LeftAt = time.Time{}
-				if err := s.meetingRepo.UpdateParticipant(ctx, p); err != nil {
-					return nil, err
-				}
+			participant = p
+			if p.Status == models.ParticipantDenied {
+				return nil, nil, ErrAdmissionDenied
 			}
 			break
 		}
 	}
 
+	if err := s.checkAdmittance(meeting, participant, userID); err != nil {
+		return nil, nil, err
+	}
+
 	// if not participant, add then
-	if !isParticipant {
-		participant := models.MeetingParticipant{
+	if participant == nil {
+		participant = &models.MeetingParticipant{
 			MeetingID: meeting.ID,
 			UserID:    userID,
 			Role:      models.MeetingParticipantNormal,
+			Status:    models.ParticipantAdmitted,
 			JoinedAt:  time.Now(),
 		}
-		if err := s.meetingRepo.AddParticipant(ctx, &participant); err != nil {
+		if meeting.WaitingRoom && meeting.HostID != userID {
+			participant.Status = models.ParticipantWaiting
+		}
+		if err := s.meetingRepo.AddParticipant(ctx, participant); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if participant.Status == models.ParticipantWaiting {
+		if err := s.notifyLobbyJoined(ctx, meeting, participant); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return meeting, participant, nil
+}
+
+func (s *MeetingService) EndMeeting(ctx context.Context, meetingID string, userID string) error {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermEndMeeting)
+	if err != nil {
+		return err
+	}
+	if meeting.Personal {
+		return s.resetPersonalMeeting(ctx, meeting)
+	}
+
+	if err := s.meetingRepo.EndMeeting(ctx, meeting.ID); err != nil {
+		return err
+	}
+	return s.meetingRepo.EndAttendance(ctx, meeting.ID, "", time.Now())
+}
+
+// ChangePassword sets a new password and makes the meeting private, only the host can do this.
+// Participants who already joined stay in the meeting.
+func (s *MeetingService) ChangePassword(ctx context.Context, meetingID string, userID string, password string) (*models.Meeting, error) {
+	if password == "" {
+		return nil, ErrPasswordRequired
+	}
+
+	meeting, err := s.authorize(ctx, meetingID, userID, PermChangeSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	// every occurrence of a series is joined with the password of the series, so changing it
+	// changes every occurrence. Only the series host may do that, not whoever hosts this one.
+	if meeting.SeriesID != "" {
+		series, err := s.meetingRepo.GetSeriesByID(ctx, meeting.SeriesID)
+		if err != nil {
+			return nil, ErrSeriesNotFound
+		}
+		if series.HostID != userID {
+			return nil, ErrNotAuthorized
+		}
+		if _, err := s.setSeriesPassword(ctx, series, password); err != nil {
 			return nil, err
 		}
+		return s.meetingRepo.GetByID(ctx, meeting.ID)
+	}
+
+	hash, err := hashMeetingPassword(password)
+	if err != nil {
+		return nil, err
 	}
 
+	meeting.IsPrivate = true
+	meeting.PasswordHash = hash
+	meeting.UpdatedAt = time.Now()
+	if err := s.meetingRepo.Update(ctx, meeting); err != nil {
+		return nil, err
+	}
 	return meeting, nil
 }
 
-func (s *MeetingService) EndMeeting(ctx context.Context, meetingID string, userID string) error {
-	meeting, err := s.meetingRepo.GetByID(ctx, meetingID)
+// RegenerateCode gives the meeting a new code, so the old one can no longer be used to join
+func (s *MeetingService) RegenerateCode(ctx context.Context, meetingID string, userID string) (*models.Meeting, error) {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermChangeSettings)
 	if err != nil {
-		return ErrMeetingNotFound
+		return nil, err
 	}
 
-	// Only host can end meeting
-	if meeting.HostID != userID {
-		return ErrNotAuthorized
+	// occurrences join with the code of their series, see ChangePassword
+	if meeting.SeriesID != "" {
+		series, err := s.meetingRepo.GetSeriesByID(ctx, meeting.SeriesID)
+		if err != nil {
+			return nil, ErrSeriesNotFound
+		}
+		if series.HostID != userID {
+			return nil, ErrNotAuthorized
+		}
+		if _, err := s.replaceSeriesCode(ctx, series); err != nil {
+			return nil, err
+		}
+		return s.meetingRepo.GetByID(ctx, meeting.ID)
 	}
 
-	return s.meetingRepo.EndMeeting(ctx, meetingID)
+	meeting.MeetingCode = generateMeetingCode(10)
+	meeting.UpdatedAt = time.Now()
+	if err := s.meetingRepo.Update(ctx, meeting); err != nil {
+		return nil, err
+	}
+	return meeting, nil
 }
 
 func (s *MeetingService) GetMeeting(ctx context.Context, meetingID string) (*models.Meeting, error) {
-	return s.meetingRepo.GetByID(ctx, meetingID)
+	meeting, err := s.meetingRepo.GetByID(ctx, meetingID)
+	if err != nil {
+		return nil, ErrMeetingNotFound
+	}
+	return meeting, nil
 }
 
 func (s *MeetingService) GetMeetingByCode(ctx context.Context, code string) (*models.Meeting, error) {
 	return s.meetingRepo.GetByCode(ctx, code)
 }
 
-func (s *MeetingService) GetActiveUserMeetings(ctx context.Context, userID string) ([]*models.Meeting, error) {
-	return s.meetingRepo.GetActiveForUser(ctx, userID)
+// ListUserMeetings returns the meetings the user hosts or joined with the total count for paging.
+// status is upcoming, live or past, active for both upcoming and live, or all.
+func (s *MeetingService) ListUserMeetings(ctx context.Context, userID string, status string, from, to time.Time, page int, pageSize int) ([]*models.Meeting, int, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	filter := repository.MeetingFilter{
+		From:   from,
+		To:     to,
+		Limit:  pageSize,
+		Offset: (page - 1) * pageSize,
+	}
+	switch status {
+	case "active":
+		filter.ActiveOnly = true
+	case "all":
+	default:
+		filter.Status = models.MeetingStatus(status)
+	}
+
+	return s.meetingRepo.ListForUser(ctx, userID, filter)
+}
+
+// CalendarFeed returns the events of a user's calendar feed: the scheduled meetings they host or
+// joined that haven't ended, and their active series. Occurrences are left out, the series covers them.
+func (s *MeetingService) CalendarFeed(ctx context.Context, userID string) ([]*models.Meeting, []*models.MeetingSeries, error) {
+	meetings, _, err := s.meetingRepo.ListForUser(ctx, userID, repository.MeetingFilter{ActiveOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	meetings = slices.DeleteFunc(meetings, func(m *models.Meeting) bool {
+		return !m.IsScheduled() || m.SeriesID != ""
+	})
+
+	series, err := s.meetingRepo.ListSeriesForUser(ctx, userID, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return meetings, series, nil
+}
+
+// StartScheduler periodically ends scheduled meetings that overran their window, disconnecting
+// everyone still in them, and closes breakout rooms whose timer ran out until ctx is done.
+// Attendance sessions left open by a previous run of the server are closed first, no connection
+// outlives it.
+func (s *MeetingService) StartScheduler(ctx context.Context, interval time.Duration) {
+	if closed, err := s.meetingRepo.EndStaleAttendance(ctx, time.Now()); err != nil {
+		slog.Error("failed to end stale attendance sessions", "error", err)
+	} else if closed > 0 {
+		slog.Info("ended stale attendance sessions", "count", closed)
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.closeDueBreakouts(ctx)
+
+				ended, err := s.meetingRepo.EndOverrunMeetings(ctx, s.cfg.OverrunGrace)
+				if err != nil {
+					slog.Error("failed to end overrun meetings", "error", err)
+					continue
+				}
+				for _, id := range ended {
+					slog.Info("ended meeting that overran its schedule", "meeting_id", id)
+					s.signaling.Disconnect(id, nil)
+					if err := s.meetingRepo.EndAttendance(ctx, id, "", time.Now()); err != nil {
+						slog.Error("failed to end attendance sessions", "meeting_id", id, "error", err)
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 }
 
 func (s *MeetingService) GetMeetingParticipants(ctx context.Context, meetingID string) ([]*models.MeetingParticipant, error) {
 	return s.meetingRepo.GetParticipants(ctx, meetingID)
 }
 
+// SaveChatMessage stores a message of a user or guest, guests have no users row to point at
 func (s *MeetingService) SaveChatMessage(ctx context.Context, meetingID string, userID string, message string) error {
+	guest, err := s.chatGuest(ctx, meetingID, userID)
+	if err != nil {
+		return err
+	}
+
 	chat := &models.MeetingChat{
 		MeetingID: meetingID,
 		UserID:    userID,
 		Message:   message,
 		SentAt:    time.Now(),
 	}
+	if guest {
+		chat.UserID, chat.GuestID = "", userID
+	}
 	return s.meetingRepo.SaveChat(ctx, chat)
 }
 
-func (s *MeetingService) GetChatHistory(ctx context.Context, meetingID string) ([]*models.MeetingChat, error) {
+func (s *MeetingService) GetChatHistory(ctx context.Context, meetingID string, userID string) ([]*models.MeetingChat, error) {
+	if _, err := s.chatGuest(ctx, meetingID, userID); err != nil {
+		return nil, err
+	}
 	return s.meetingRepo.GetChatHistory(ctx, meetingID)
 }
 
+// chatGuest reports whether memberID is a guest. Guests and users alike only take part in the chat
+// while admitted, not from the lobby or once denied or removed; the host always does.
+func (s *MeetingService) chatGuest(ctx context.Context, meetingID string, memberID string) (bool, error) {
+	guest, err := s.meetingRepo.GetGuest(ctx, memberID)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err := s.participantMeeting(ctx, meetingID, memberID)
+		return false, err
+	}
+	if err != nil {
+		return false, err
+	}
+	if guest.MeetingID != meetingID {
+		return false, ErrNotAuthorized
+	}
+
+	participant, err := s.meetingRepo.GetParticipant(ctx, meetingID, memberID)
+	if err != nil || participant.Status != models.ParticipantAdmitted {
+		return false, ErrNotAuthorized
+	}
+	return true, nil
+}
+
+// GetChatTranscript returns the meeting and its full chat history, provided the user hosted it
+// or was admitted to it
+func (s *MeetingService) GetChatTranscript(ctx context.Context, meetingID string, userID string) (*models.Meeting, []*models.MeetingChat, error) {
+	meeting, err := s.participantMeeting(ctx, meetingID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chats, err := s.GetChatHistory(ctx, meetingID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return meeting, chats, nil
+}
+
+func (s *MeetingService) SearchChatMessages(ctx context.Context, userID string, query string, page int, pageSize int) ([]*models.ChatSearchResult, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	return s.meetingRepo.SearchChats(ctx, userID, query, pageSize, (page-1)*pageSize)
+}
+
 func generateMeetingCode(length int) string {
 	return gonanoid.MustGenerate(charset, length)
 }
+
+func (s *MeetingService) applySchedule(meeting *models.Meeting, schedule Schedule) error {
+	if schedule.StartAt.IsZero() {
+		return nil
+	}
+
+	// a little slack for clients scheduling "now"
+	if schedule.StartAt.Before(time.Now().Add(-time.Minute)) {
+		return fmt.Errorf("%w: start is in the past", ErrInvalidSchedule)
+	}
+
+	duration := schedule.Duration
+	if duration == 0 {
+		duration = s.cfg.DefaultDuration
+	}
+	if duration < time.Minute {
+		return fmt.Errorf("%w: duration must be at least a minute", ErrInvalidSchedule)
+	}
+
+	timezone := schedule.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidSchedule, timezone)
+	}
+
+	meeting.ScheduledAt = schedule.StartAt.UTC()
+	meeting.DurationMinutes = int(duration / time.Minute)
+	meeting.Timezone = timezone
+	return nil
+}
+
+// checkJoinWindow rejects ended meetings, and scheduled meetings that don't accept participants yet
+func (s *MeetingService) checkJoinWindow(meeting *models.Meeting, userID string, now time.Time) error {
+	if meeting.Status(now) == models.MeetingStatusPast {
+		return ErrMeetingEnded
+	}
+	if !meeting.IsScheduled() || meeting.HostID == userID {
+		return nil
+	}
+
+	opensAt := meeting.ScheduledAt.Add(-s.cfg.EarlyJoinWindow)
+	if now.Before(opensAt) {
+		return fmt.Errorf("%w, it opens at %s", ErrMeetingNotStarted, opensAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func hashMeetingPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// checkMeetingPassword compares in constant time, an unset hash never matches
+func checkMeetingPassword(hash, password string) bool {
+	if hash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
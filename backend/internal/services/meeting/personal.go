@@ -0,0 +1,116 @@
+package meeting
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+var (
+	ErrInvalidVanityCode = errors.New("meeting codes are 4 to 20 letters, digits or dashes and can't start or end with a dash")
+	ErrCodeTaken         = errors.New("meeting code is already taken")
+	ErrNotPersonal       = errors.New("only personal meetings can have a custom code")
+)
+
+// codes are matched case-insensitively, they are validated once upper cased
+var vanityCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9-]{2,18}[A-Z0-9]$`)
+
+// GetPersonalMeeting returns the user's personal meeting, ErrMeetingNotFound until it is created
+// with CreatePersonalMeeting
+func (s *MeetingService) GetPersonalMeeting(ctx context.Context, userID string) (*models.Meeting, error) {
+	meeting, err := s.meetingRepo.GetPersonalMeeting(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMeetingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return meeting, nil
+}
+
+// CreatePersonalMeeting returns the user's personal meeting, creating it the first time. It is a permanent
+// room that keeps its code and is never ended, ending it only resets it for the next session.
+func (s *MeetingService) CreatePersonalMeeting(ctx context.Context, userID string) (*models.Meeting, error) {
+	if meeting, err := s.GetPersonalMeeting(ctx, userID); !errors.Is(err, ErrMeetingNotFound) {
+		return meeting, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	meeting := &models.Meeting{
+		ID:          uuid.NewString(),
+		Title:       user.DisplayName + "'s room",
+		HostID:      userID,
+		MeetingCode: generateMeetingCode(10),
+		Personal:    true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	host := &models.MeetingParticipant{
+		UserID: userID,
+		Role:   models.MeetingParticipantHost,
+	}
+	if _, err := s.meetingRepo.CreatePersonalMeeting(ctx, meeting, host); err != nil {
+		return nil, err
+	}
+	// a concurrent request may have created it first
+	return s.meetingRepo.GetPersonalMeeting(ctx, userID)
+}
+
+// SetVanityCode gives the user's personal meeting a code of their choice. Codes are case-insensitive
+// and stored upper case, the old code can no longer be used to join.
+func (s *MeetingService) SetVanityCode(ctx context.Context, meetingID string, userID string, code string) (*models.Meeting, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !vanityCodePattern.MatchString(code) {
+		return nil, ErrInvalidVanityCode
+	}
+
+	meeting, err := s.authorize(ctx, meetingID, userID, PermChangeSettings)
+	if err != nil {
+		return nil, err
+	}
+	if !meeting.Personal {
+		return nil, ErrNotPersonal
+	}
+	if code == meeting.MeetingCode {
+		return meeting, nil
+	}
+
+	changed, err := s.meetingRepo.SetMeetingCode(ctx, meeting.ID, code)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return nil, ErrCodeTaken
+	}
+	return s.meetingRepo.GetByID(ctx, meeting.ID)
+}
+
+// resetPersonalMeeting ends the session of a personal meeting instead of the meeting, so its code
+// keeps working. Everyone but the host leaves and the next session starts unlocked, without breakout
+// rooms, open polls or raised hands.
+func (s *MeetingService) resetPersonalMeeting(ctx context.Context, meeting *models.Meeting) error {
+	now := time.Now()
+	if err := s.meetingRepo.ResetMeeting(ctx, meeting.ID, now); err != nil {
+		return err
+	}
+	if err := s.meetingRepo.EndAttendance(ctx, meeting.ID, "", now); err != nil {
+		return err
+	}
+
+	s.signaling.CloseBreakouts(meeting.ID)
+	s.signaling.LowerHands(meeting.ID, nil)
+	s.signaling.Disconnect(meeting.ID, []string{meeting.HostID})
+	return nil
+}
@@ -0,0 +1,264 @@
+package meeting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+var (
+	ErrPollNotFound = errors.New("poll not found")
+	ErrInvalidPoll  = errors.New("invalid poll")
+	ErrInvalidVote  = errors.New("invalid vote")
+	ErrPollNotOpen  = errors.New("the poll is not open for voting")
+	ErrPollStatus   = errors.New("the poll can't change to this status")
+)
+
+const (
+	MinPollOptions = 2
+	MaxPollOptions = 10
+)
+
+// Events pushed over signaling while polls run
+const (
+	// EventPollOpened asks everyone to vote, with the PollResults so far
+	EventPollOpened = "poll-opened"
+	// EventPollResults updates the results after a vote, with PollResults
+	EventPollResults = "poll-results"
+	// EventPollClosed ends the voting, with the final PollResults
+	EventPollClosed = "poll-closed"
+)
+
+// PollResults is a poll with its vote counts, the voters are only named when the poll isn't anonymous
+type PollResults struct {
+	ID             string              `json:"id"`
+	Question       string              `json:"question"`
+	MultipleChoice bool                `json:"multipleChoice"`
+	Anonymous      bool                `json:"anonymous"`
+	Status         models.PollStatus   `json:"status"`
+	Voters         int                 `json:"voters"`
+	Options        []PollOptionResults `json:"options"`
+}
+
+type PollOptionResults struct {
+	ID     string      `json:"id"`
+	Label  string      `json:"label"`
+	Votes  int         `json:"votes"`
+	Voters []PollVoter `json:"voters,omitempty"`
+}
+
+type PollVoter struct {
+	UserID      string `json:"userId"`
+	DisplayName string `json:"displayName"`
+}
+
+// Tally counts the votes of a poll loaded with its options and votes
+func Tally(poll *models.MeetingPoll) PollResults {
+	results := PollResults{
+		ID:             poll.ID,
+		Question:       poll.Question,
+		MultipleChoice: poll.MultipleChoice,
+		Anonymous:      poll.Anonymous,
+		Status:         poll.Status,
+		Options:        make([]PollOptionResults, len(poll.Options)),
+	}
+
+	index := map[string]int{}
+	for i, option := range poll.Options {
+		index[option.ID] = i
+		results.Options[i] = PollOptionResults{ID: option.ID, Label: option.Label}
+	}
+
+	voters := map[string]bool{}
+	for _, vote := range poll.Votes {
+		i, ok := index[vote.OptionID]
+		if !ok {
+			continue
+		}
+		voters[vote.BallotID] = true
+		results.Options[i].Votes++
+		if !poll.Anonymous {
+			voter := PollVoter{UserID: vote.UserID, DisplayName: models.DeletedUserDisplayName}
+			if vote.User != nil {
+				voter.DisplayName = vote.User.DisplayName
+			}
+			results.Options[i].Voters = append(results.Options[i].Voters, voter)
+		}
+	}
+	results.Voters = len(voters)
+	return results
+}
+
+// CreatePoll drafts a poll, it is put to the participants once opened
+func (s *MeetingService) CreatePoll(ctx context.Context, meetingID string, userID string, question string, options []string, multipleChoice bool, anonymous bool) (*models.MeetingPoll, error) {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermManagePolls)
+	if err != nil {
+		return nil, err
+	}
+	if meeting.Status(time.Now()) == models.MeetingStatusPast {
+		return nil, ErrMeetingEnded
+	}
+	if len(options) < MinPollOptions || len(options) > MaxPollOptions {
+		return nil, fmt.Errorf("%w: a poll needs %d to %d options", ErrInvalidPoll, MinPollOptions, MaxPollOptions)
+	}
+
+	poll := &models.MeetingPoll{
+		ID:             uuid.NewString(),
+		MeetingID:      meeting.ID,
+		CreatedBy:      userID,
+		Question:       question,
+		MultipleChoice: multipleChoice,
+		Anonymous:      anonymous,
+		Status:         models.PollDraft,
+		CreatedAt:      time.Now(),
+	}
+	for i, label := range options {
+		poll.Options = append(poll.Options, &models.MeetingPollOption{
+			ID:       uuid.NewString(),
+			PollID:   poll.ID,
+			Label:    label,
+			Position: i + 1,
+		})
+	}
+
+	if err := s.meetingRepo.CreatePoll(ctx, poll); err != nil {
+		return nil, err
+	}
+	return poll, nil
+}
+
+// ListPolls returns the polls of a meeting to its participants, also after the meeting ended.
+// Drafts are only listed for hosts and co-hosts.
+func (s *MeetingService) ListPolls(ctx context.Context, meetingID string, userID string) ([]*models.MeetingPoll, error) {
+	meeting, err := s.meetingRepo.GetByID(ctx, meetingID)
+	if err != nil {
+		return nil, ErrMeetingNotFound
+	}
+	role := s.roleOf(ctx, meeting, userID)
+	if role == "" {
+		return nil, ErrNotAuthorized
+	}
+
+	polls, err := s.meetingRepo.ListPolls(ctx, meeting.ID)
+	if err != nil {
+		return nil, err
+	}
+	if Can(role, PermManagePolls) {
+		return polls, nil
+	}
+
+	visible := make([]*models.MeetingPoll, 0, len(polls))
+	for _, poll := range polls {
+		if poll.Status != models.PollDraft {
+			visible = append(visible, poll)
+		}
+	}
+	return visible, nil
+}
+
+// OpenPoll starts the voting on a drafted poll
+func (s *MeetingService) OpenPoll(ctx context.Context, meetingID string, userID string, pollID string) (*models.MeetingPoll, error) {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermManagePolls)
+	if err != nil {
+		return nil, err
+	}
+	if meeting.Status(time.Now()) == models.MeetingStatusPast {
+		return nil, ErrMeetingEnded
+	}
+	return s.setPollStatus(ctx, meeting.ID, pollID, models.PollDraft, models.PollOpen, EventPollOpened)
+}
+
+// ClosePoll ends the voting on an open poll, its results stay available
+func (s *MeetingService) ClosePoll(ctx context.Context, meetingID string, userID string, pollID string) (*models.MeetingPoll, error) {
+	meeting, err := s.authorize(ctx, meetingID, userID, PermManagePolls)
+	if err != nil {
+		return nil, err
+	}
+	return s.setPollStatus(ctx, meeting.ID, pollID, models.PollOpen, models.PollClosed, EventPollClosed)
+}
+
+// Vote records the options a participant picked on an open poll, replacing an earlier vote.
+// Single choice polls take exactly one option.
+func (s *MeetingService) Vote(ctx context.Context, meetingID string, userID string, pollID string, optionIDs []string) (*models.MeetingPoll, error) {
+	meeting, err := s.participantMeeting(ctx, meetingID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	poll, err := s.meetingRepo.GetPoll(ctx, pollID, meeting.ID)
+	if err != nil {
+		return nil, ErrPollNotFound
+	}
+	if poll.Status != models.PollOpen {
+		return nil, ErrPollNotOpen
+	}
+
+	options := map[string]bool{}
+	for _, option := range poll.Options {
+		options[option.ID] = true
+	}
+	picked := map[string]bool{}
+	ballotID := uuid.NewString()
+	votes := make([]*models.MeetingPollVote, 0, len(optionIDs))
+	for _, optionID := range optionIDs {
+		if !options[optionID] {
+			return nil, fmt.Errorf("%w: %s is not an option of this poll", ErrInvalidVote, optionID)
+		}
+		if picked[optionID] {
+			continue
+		}
+		picked[optionID] = true
+		votes = append(votes, &models.MeetingPollVote{
+			PollID:   poll.ID,
+			BallotID: ballotID,
+			UserID:   userID,
+			OptionID: optionID,
+			VotedAt:  time.Now(),
+		})
+	}
+	if len(votes) == 0 || (!poll.MultipleChoice && len(votes) > 1) {
+		return nil, fmt.Errorf("%w: pick one option, or more on multiple choice polls", ErrInvalidVote)
+	}
+
+	open, err := s.meetingRepo.ReplaceVotes(ctx, poll.ID, userID, votes)
+	if err != nil {
+		return nil, err
+	}
+	if !open {
+		return nil, ErrPollNotOpen
+	}
+
+	return s.publishPoll(ctx, meeting.ID, poll.ID, EventPollResults)
+}
+
+func (s *MeetingService) setPollStatus(ctx context.Context, meetingID string, pollID string, from, to models.PollStatus, event string) (*models.MeetingPoll, error) {
+	changed, err := s.meetingRepo.SetPollStatus(ctx, pollID, meetingID, from, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		if _, err := s.meetingRepo.GetPoll(ctx, pollID, meetingID); err != nil {
+			return nil, ErrPollNotFound
+		}
+		return nil, fmt.Errorf("%w: only %s polls can become %s", ErrPollStatus, from, to)
+	}
+
+	return s.publishPoll(ctx, meetingID, pollID, event)
+}
+
+// publishPoll sends the current results of a poll to everyone in the meeting
+func (s *MeetingService) publishPoll(ctx context.Context, meetingID string, pollID string, event string) (*models.MeetingPoll, error) {
+	poll, err := s.meetingRepo.GetPoll(ctx, pollID, meetingID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.notifyAdmitted(ctx, meetingID, event, Tally(poll)); err != nil {
+		return nil, err
+	}
+	return poll, nil
+}
@@ -0,0 +1,175 @@
+package meeting
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+var (
+	ErrQuestionNotFound = errors.New("question not found")
+	ErrQuestionClosed   = errors.New("the question was answered or dismissed")
+	ErrInvalidQuestion  = errors.New("invalid question status")
+)
+
+// Events pushed over signaling while the Q&A board changes
+const (
+	// EventQuestionAsked adds a question to the board, with a QuestionEntry
+	EventQuestionAsked = "question-asked"
+	// EventQuestionUpdated carries the new upvotes or status of a question, with a QuestionEntry
+	EventQuestionUpdated = "question-updated"
+)
+
+// QuestionEntry is a question as everyone in the meeting sees it, anonymous questions have no author
+type QuestionEntry struct {
+	ID        string                `json:"id"`
+	Text      string                `json:"text"`
+	Anonymous bool                  `json:"anonymous"`
+	Author    string                `json:"author,omitempty"`
+	Status    models.QuestionStatus `json:"status"`
+	Upvotes   int                   `json:"upvotes"`
+	CreatedAt time.Time             `json:"createdAt"`
+}
+
+// NewQuestionEntry hides the author of anonymous questions
+func NewQuestionEntry(question *models.MeetingQuestion) QuestionEntry {
+	entry := QuestionEntry{
+		ID:        question.ID,
+		Text:      question.Text,
+		Anonymous: question.Anonymous,
+		Status:    question.Status,
+		Upvotes:   question.Upvotes,
+		CreatedAt: question.CreatedAt,
+	}
+	if !question.Anonymous {
+		entry.Author = models.DeletedUserDisplayName
+		if question.User != nil {
+			entry.Author = question.User.DisplayName
+		}
+	}
+	return entry
+}
+
+// AskQuestion puts a participant's question on the Q&A board of a meeting
+func (s *MeetingService) AskQuestion(ctx context.Context, meetingID string, userID string, text string, anonymous bool) (*models.MeetingQuestion, error) {
+	meeting, err := s.participantMeeting(ctx, meetingID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if meeting.Status(time.Now()) == models.MeetingStatusPast {
+		return nil, ErrMeetingEnded
+	}
+
+	question := &models.MeetingQuestion{
+		ID:        uuid.NewString(),
+		MeetingID: meeting.ID,
+		UserID:    userID,
+		Text:      text,
+		Anonymous: anonymous,
+		Status:    models.QuestionOpen,
+		CreatedAt: time.Now(),
+	}
+	if err := s.meetingRepo.CreateQuestion(ctx, question); err != nil {
+		return nil, err
+	}
+
+	return s.publishQuestion(ctx, meeting.ID, question.ID, EventQuestionAsked)
+}
+
+// ListQuestions returns the Q&A board of a meeting, most upvoted first, and the questions the user
+// upvoted. Dismissed questions are only listed for hosts and co-hosts.
+func (s *MeetingService) ListQuestions(ctx context.Context, meetingID string, userID string) ([]*models.MeetingQuestion, []string, error) {
+	meeting, err := s.meetingRepo.GetByID(ctx, meetingID)
+	if err != nil {
+		return nil, nil, ErrMeetingNotFound
+	}
+	role := s.roleOf(ctx, meeting, userID)
+	if role == "" {
+		return nil, nil, ErrNotAuthorized
+	}
+
+	questions, err := s.meetingRepo.ListQuestions(ctx, meeting.ID, Can(role, PermModerateQuestions))
+	if err != nil {
+		return nil, nil, err
+	}
+	upvoted, err := s.meetingRepo.ListUpvotedQuestions(ctx, meeting.ID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return questions, upvoted, nil
+}
+
+// UpvoteQuestion adds or takes back the user's upvote on an open question
+func (s *MeetingService) UpvoteQuestion(ctx context.Context, meetingID string, userID string, questionID string, upvote bool) (*models.MeetingQuestion, error) {
+	meeting, err := s.participantMeeting(ctx, meetingID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	question, err := s.meetingRepo.GetQuestion(ctx, questionID, meeting.ID)
+	if err != nil {
+		return nil, ErrQuestionNotFound
+	}
+	if question.Status != models.QuestionOpen {
+		return nil, ErrQuestionClosed
+	}
+
+	var changed bool
+	if upvote {
+		changed, err = s.meetingRepo.UpvoteQuestion(ctx, question.ID, userID)
+	} else {
+		changed, err = s.meetingRepo.RemoveUpvote(ctx, question.ID, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return question, nil
+	}
+
+	return s.publishQuestion(ctx, meeting.ID, question.ID, EventQuestionUpdated)
+}
+
+// SetQuestionStatus marks a question answered or dismissed, or opens it again (hosts and co-hosts only)
+func (s *MeetingService) SetQuestionStatus(ctx context.Context, meetingID string, userID string, questionID string, status models.QuestionStatus) error {
+	var resolvedAt time.Time
+	switch status {
+	case models.QuestionAnswered, models.QuestionDismissed:
+		resolvedAt = time.Now()
+	case models.QuestionOpen:
+	default:
+		return ErrInvalidQuestion
+	}
+
+	meeting, err := s.authorize(ctx, meetingID, userID, PermModerateQuestions)
+	if err != nil {
+		return err
+	}
+
+	updated, err := s.meetingRepo.SetQuestionStatus(ctx, questionID, meeting.ID, status, resolvedAt)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrQuestionNotFound
+	}
+
+	_, err = s.publishQuestion(ctx, meeting.ID, questionID, EventQuestionUpdated)
+	return err
+}
+
+// publishQuestion sends the current state of a question to everyone in the meeting
+func (s *MeetingService) publishQuestion(ctx context.Context, meetingID string, questionID string, event string) (*models.MeetingQuestion, error) {
+	question, err := s.meetingRepo.GetQuestion(ctx, questionID, meetingID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.notifyAdmitted(ctx, meetingID, event, NewQuestionEntry(question)); err != nil {
+		return nil, err
+	}
+	return question, nil
+}
@@ -0,0 +1,230 @@
+package meeting
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+var (
+	ErrParticipantNotFound = errors.New("participant not found")
+	ErrInvalidRole         = errors.New("invalid role change")
+)
+
+// EventRoleChanged tells everyone in the meeting about a new role, with a RoleChange
+const EventRoleChanged = "role-changed"
+
+// RoleChange is the new role of a participant
+type RoleChange struct {
+	UserID string                 `json:"userId"`
+	Role   models.ParticipantRole `json:"role"`
+}
+
+// Permission is an action on a meeting that only some roles may take
+type Permission string
+
+const (
+	PermEndMeeting        Permission = "end-meeting"
+	PermChangeSettings    Permission = "change-settings" // password, code, invite only, waiting room
+	PermManageInvitations Permission = "manage-invitations"
+	PermManageLobby       Permission = "manage-lobby"
+	PermLockMeeting       Permission = "lock-meeting"
+	PermManageBreakouts   Permission = "manage-breakouts"
+	PermManagePolls       Permission = "manage-polls"
+	PermManageHands       Permission = "manage-hands" // lower hands and call on the next speaker
+	PermModerateQuestions Permission = "moderate-questions"
+	PermManageRoles       Permission = "manage-roles" // promote and demote co-hosts
+	PermTransferHost      Permission = "transfer-host"
+	PermViewAttendance    Permission = "view-attendance"
+)
+
+// rolePermissions is the permission matrix, roles missing from it may only take part
+var rolePermissions = map[models.ParticipantRole][]Permission{
+	models.MeetingParticipantHost: {
+		PermEndMeeting,
+		PermChangeSettings,
+		PermManageInvitations,
+		PermManageLobby,
+		PermLockMeeting,
+		PermManageBreakouts,
+		PermManagePolls,
+		PermManageHands,
+		PermModerateQuestions,
+		PermManageRoles,
+		PermTransferHost,
+		PermViewAttendance,
+	},
+	models.MeetingParticipantCoHost: {
+		PermManageLobby,
+		PermLockMeeting,
+		PermManageBreakouts,
+		PermManagePolls,
+		PermManageHands,
+		PermModerateQuestions,
+	},
+}
+
+// Can reports whether the role grants the permission
+func Can(role models.ParticipantRole, perm Permission) bool {
+	return slices.Contains(rolePermissions[role], perm)
+}
+
+// SetParticipantRole promotes a participant to co-host or demotes them back, only the host can do this.
+// The host role changes hands with TransferHost.
+func (s *MeetingService) SetParticipantRole(ctx context.Context, meetingID string, hostID string, userID string, role models.ParticipantRole) error {
+	if role != models.MeetingParticipantCoHost && role != models.MeetingParticipantNormal {
+		return ErrInvalidRole
+	}
+
+	meeting, err := s.authorize(ctx, meetingID, hostID, PermManageRoles)
+	if err != nil {
+		return err
+	}
+	if userID == meeting.HostID {
+		return ErrInvalidRole
+	}
+
+	changed, err := s.meetingRepo.SetParticipantRole(ctx, meeting.ID, userID, role)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return ErrParticipantNotFound
+	}
+
+	return s.notifyAdmitted(ctx, meeting.ID, EventRoleChanged, RoleChange{UserID: userID, Role: role})
+}
+
+// TransferHost hands the meeting to another admitted participant, the previous host becomes a co-host
+func (s *MeetingService) TransferHost(ctx context.Context, meetingID string, hostID string, userID string) (*models.Meeting, error) {
+	meeting, err := s.authorize(ctx, meetingID, hostID, PermTransferHost)
+	if err != nil {
+		return nil, err
+	}
+	// a personal meeting belongs to its host for good
+	if userID == meeting.HostID || meeting.Personal {
+		return nil, ErrInvalidRole
+	}
+
+	participant, err := s.meetingRepo.GetParticipant(ctx, meeting.ID, userID)
+	if err != nil || participant.Status != models.ParticipantAdmitted {
+		return nil, ErrParticipantNotFound
+	}
+
+	transferred, err := s.meetingRepo.TransferHost(ctx, meeting.ID, hostID, userID)
+	if err != nil {
+		return nil, err
+	}
+	// the meeting changed hands since it was authorized
+	if !transferred {
+		return nil, ErrNotAuthorized
+	}
+
+	if err := s.notifyAdmitted(ctx, meeting.ID, EventRoleChanged, RoleChange{UserID: userID, Role: models.MeetingParticipantHost}); err != nil {
+		return nil, err
+	}
+	if err := s.notifyAdmitted(ctx, meeting.ID, EventRoleChanged, RoleChange{UserID: hostID, Role: models.MeetingParticipantCoHost}); err != nil {
+		return nil, err
+	}
+	return s.meetingRepo.GetByID(ctx, meeting.ID)
+}
+
+// HasPermission reports whether the user's role in the meeting grants the permission
+func (s *MeetingService) HasPermission(ctx context.Context, meetingID string, userID string, perm Permission) (bool, error) {
+	_, err := s.authorize(ctx, meetingID, userID, perm)
+	if errors.Is(err, ErrNotAuthorized) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// authorize returns the meeting if the user's role in it grants the permission
+func (s *MeetingService) authorize(ctx context.Context, meetingID string, userID string, perm Permission) (*models.Meeting, error) {
+	meeting, err := s.meetingRepo.GetByID(ctx, meetingID)
+	if err != nil {
+		return nil, ErrMeetingNotFound
+	}
+	if !Can(s.roleOf(ctx, meeting, userID), perm) {
+		return nil, ErrNotAuthorized
+	}
+	return meeting, nil
+}
+
+// participantMeeting returns the meeting if the user hosts it or was admitted to it. Only a missing
+// participant counts as not authorized, failures looking it up are returned as they are.
+func (s *MeetingService) participantMeeting(ctx context.Context, meetingID string, userID string) (*models.Meeting, error) {
+	meeting, err := s.meetingRepo.GetByID(ctx, meetingID)
+	if err != nil {
+		return nil, ErrMeetingNotFound
+	}
+	if meeting.HostID != "" && meeting.HostID == userID {
+		return meeting, nil
+	}
+
+	participant, err := s.meetingRepo.GetParticipant(ctx, meeting.ID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotAuthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+	// a stale host row after the meeting was handed over grants nothing, like in roleOf
+	if participant.Status != models.ParticipantAdmitted || participant.Role == models.MeetingParticipantHost {
+		return nil, ErrNotAuthorized
+	}
+	return meeting, nil
+}
+
+// roleOf returns the user's role in the meeting. meetings.host_id decides who the host is,
+// other roles only count once the participant is admitted.
+func (s *MeetingService) roleOf(ctx context.Context, meeting *models.Meeting, userID string) models.ParticipantRole {
+	if meeting.HostID != "" && meeting.HostID == userID {
+		return models.MeetingParticipantHost
+	}
+
+	participant, err := s.meetingRepo.GetParticipant(ctx, meeting.ID, userID)
+	if err != nil || participant.Status != models.ParticipantAdmitted || participant.Role == models.MeetingParticipantHost {
+		return ""
+	}
+	return participant.Role
+}
+
+// holders returns the users of a meeting whose role grants the permission
+func (s *MeetingService) holders(ctx context.Context, meeting *models.Meeting, perm Permission) ([]string, error) {
+	participants, err := s.meetingRepo.GetParticipants(ctx, meeting.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var userIDs []string
+	if meeting.HostID != "" && Can(models.MeetingParticipantHost, perm) {
+		userIDs = append(userIDs, meeting.HostID)
+	}
+	for _, p := range participants {
+		if p.UserID == "" || p.UserID == meeting.HostID || p.Status != models.ParticipantAdmitted {
+			continue
+		}
+		if p.Role != models.MeetingParticipantHost && Can(p.Role, perm) {
+			userIDs = append(userIDs, p.UserID)
+		}
+	}
+	return userIDs, nil
+}
+
+// notifyAdmitted sends an event to everyone admitted to the meeting
+func (s *MeetingService) notifyAdmitted(ctx context.Context, meetingID string, event string, data any) error {
+	participants, err := s.meetingRepo.ListParticipantsByStatus(ctx, meetingID, models.ParticipantAdmitted)
+	if err != nil {
+		return err
+	}
+
+	userIDs := make([]string, 0, len(participants))
+	for _, p := range participants {
+		userIDs = append(userIDs, p.MemberID())
+	}
+	s.signaling.Notify(meetingID, userIDs, event, data)
+	return nil
+}
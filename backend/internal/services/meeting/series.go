@@ -0,0 +1,456 @@
+package meeting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/teambition/rrule-go"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+var (
+	ErrSeriesNotFound     = errors.New("meeting series not found")
+	ErrInvalidRecurrence  = errors.New("invalid recurrence rule")
+	ErrOccurrenceNotFound = errors.New("the series has no occurrence at that time")
+	ErrInvalidRange       = errors.New("invalid occurrence range")
+)
+
+const (
+	// maxOccurrences caps a single expansion of a series
+	maxOccurrences = 500
+	// maxOccurrenceRange is the longest window occurrences can be listed for at once
+	maxOccurrenceRange = 366 * 24 * time.Hour
+)
+
+// seriesFrequencies are the supported RRULE frequencies, anything more frequent than daily
+// would produce overlapping occurrences
+var seriesFrequencies = []rrule.Frequency{rrule.DAILY, rrule.WEEKLY, rrule.MONTHLY}
+
+// Recurrence repeats a scheduled meeting
+type Recurrence struct {
+	// RRule is an RFC 5545 recurrence rule without DTSTART, the schedule start takes its place
+	RRule string
+	// ExDates are starts of occurrences that won't take place
+	ExDates []time.Time
+}
+
+// Occurrence is a single date of a meeting series
+type Occurrence struct {
+	StartAt time.Time
+	EndAt   time.Time
+	// Meeting holds the participants and chat of the occurrence, it is nil until someone joins
+	Meeting *models.Meeting
+}
+
+// Status tells whether the occurrence is still to come, can be joined, or is over
+func (o Occurrence) Status(now time.Time) models.MeetingStatus {
+	switch {
+	case o.Meeting != nil:
+		return o.Meeting.Status(now)
+	case now.Before(o.StartAt):
+		return models.MeetingStatusUpcoming
+	case now.Before(o.EndAt):
+		return models.MeetingStatusLive
+	default:
+		return models.MeetingStatusPast
+	}
+}
+
+// CreateSeries plans a recurring meeting. Every occurrence is joined with the same code and password.
+func (s *MeetingService) CreateSeries(ctx context.Context, title string, hostID string, isPrivate bool, password string, schedule Schedule, recurrence Recurrence) (*models.MeetingSeries, error) {
+	if schedule.StartAt.IsZero() {
+		return nil, fmt.Errorf("%w: a series needs a start", ErrInvalidSchedule)
+	}
+
+	// reuse the validation of single meetings for the first start, duration and timezone
+	first := &models.Meeting{}
+	if err := s.applySchedule(first, schedule); err != nil {
+		return nil, err
+	}
+
+	code, err := s.newSeriesCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	series := &models.MeetingSeries{
+		Title:       title,
+		HostID:      hostID,
+		MeetingCode: code,
+		IsPrivate:   isPrivate,
+		// occurrences are compared with exceptions at second precision
+		StartsAt:        first.ScheduledAt.Truncate(time.Second),
+		DurationMinutes: first.DurationMinutes,
+		Timezone:        first.Timezone,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	rule, err := normalizeRRule(recurrence.RRule, series.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	series.RRule = rule
+
+	series.ExDates = make([]time.Time, 0, len(recurrence.ExDates))
+	for _, exdate := range recurrence.ExDates {
+		series.ExDates = append(series.ExDates, exdate.UTC().Truncate(time.Second))
+	}
+
+	set, err := recurrenceSet(series)
+	if err != nil {
+		return nil, err
+	}
+	if set.After(series.StartsAt, true).IsZero() {
+		return nil, fmt.Errorf("%w: the rule has no occurrences", ErrInvalidRecurrence)
+	}
+
+	if isPrivate {
+		if password == "" {
+			return nil, ErrPasswordRequired
+		}
+		hash, err := hashMeetingPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		series.PasswordHash = hash
+	}
+
+	if err := s.meetingRepo.CreateSeries(ctx, series); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// GetSeries returns a series to its host and to users who joined one of its occurrences
+func (s *MeetingService) GetSeries(ctx context.Context, seriesID string, userID string) (*models.MeetingSeries, error) {
+	series, err := s.meetingRepo.GetSeriesByID(ctx, seriesID)
+	if err != nil {
+		return nil, ErrSeriesNotFound
+	}
+	if series.HostID == userID {
+		return series, nil
+	}
+
+	joined, err := s.meetingRepo.HasJoinedSeries(ctx, seriesID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !joined {
+		return nil, ErrNotAuthorized
+	}
+	return series, nil
+}
+
+// ListUserSeries returns the series the user hosts or joined an occurrence of
+func (s *MeetingService) ListUserSeries(ctx context.Context, userID string, activeOnly bool) ([]*models.MeetingSeries, error) {
+	return s.meetingRepo.ListSeriesForUser(ctx, userID, activeOnly)
+}
+
+// ListOccurrences expands the series between from and to. Occurrences someone joined
+// come with their meeting, so their participants and chat can be looked up.
+func (s *MeetingService) ListOccurrences(ctx context.Context, seriesID string, userID string, from, to time.Time) (*models.MeetingSeries, []Occurrence, error) {
+	if !to.After(from) || to.Sub(from) > maxOccurrenceRange {
+		return nil, nil, fmt.Errorf("%w: the range must be positive and at most a year", ErrInvalidRange)
+	}
+
+	series, err := s.GetSeries(ctx, seriesID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	occurrences, err := s.expandSeries(series, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stored, err := s.meetingRepo.ListOccurrences(ctx, series.ID, from.Add(-series.Duration()), to)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range occurrences {
+		for _, meeting := range stored {
+			if meeting.ScheduledAt.Equal(occurrences[i].StartAt) {
+				occurrences[i].Meeting = meeting
+			}
+		}
+	}
+	return series, occurrences, nil
+}
+
+// NextOccurrence is the first occurrence of the series that hasn't finished yet, the zero time
+// if the series is over
+func (s *MeetingService) NextOccurrence(series *models.MeetingSeries, now time.Time) time.Time {
+	if !series.EndedAt.IsZero() {
+		return time.Time{}
+	}
+	set, err := recurrenceSet(series)
+	if err != nil {
+		return time.Time{}
+	}
+	return set.After(now.Add(-series.Duration()), false)
+}
+
+// CancelOccurrence adds an exception to the series, so the occurrence starting at startAt
+// won't take place. A meeting already running for it is ended.
+func (s *MeetingService) CancelOccurrence(ctx context.Context, seriesID string, userID string, startAt time.Time) (*models.MeetingSeries, error) {
+	series, err := s.getHostedSeries(ctx, seriesID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := recurrenceSet(series)
+	if err != nil {
+		return nil, err
+	}
+	startAt = startAt.UTC().Truncate(time.Second)
+	if !slices.ContainsFunc(set.Between(startAt, startAt, true), startAt.Equal) {
+		return nil, ErrOccurrenceNotFound
+	}
+
+	series.ExDates = append(series.ExDates, startAt)
+	if err := s.meetingRepo.UpdateSeries(ctx, series); err != nil {
+		return nil, err
+	}
+
+	if occurrence, err := s.meetingRepo.GetOccurrence(ctx, series.ID, startAt); err == nil {
+		if err := s.meetingRepo.EndMeeting(ctx, occurrence.ID); err != nil {
+			return nil, err
+		}
+	}
+	return series, nil
+}
+
+// EndSeries stops a series, no further occurrences can be joined
+func (s *MeetingService) EndSeries(ctx context.Context, seriesID string, userID string) error {
+	series, err := s.getHostedSeries(ctx, seriesID, userID)
+	if err != nil {
+		return err
+	}
+	return s.meetingRepo.EndSeries(ctx, series.ID)
+}
+
+// ChangeSeriesPassword sets a new password for every upcoming occurrence and makes the series private
+func (s *MeetingService) ChangeSeriesPassword(ctx context.Context, seriesID string, userID string, password string) (*models.MeetingSeries, error) {
+	if password == "" {
+		return nil, ErrPasswordRequired
+	}
+
+	series, err := s.getHostedSeries(ctx, seriesID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.setSeriesPassword(ctx, series, password)
+}
+
+func (s *MeetingService) setSeriesPassword(ctx context.Context, series *models.MeetingSeries, password string) (*models.MeetingSeries, error) {
+	hash, err := hashMeetingPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	series.IsPrivate = true
+	series.PasswordHash = hash
+	if err := s.meetingRepo.UpdateSeries(ctx, series); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// RegenerateSeriesCode gives the series a new code, the old one no longer joins any occurrence
+func (s *MeetingService) RegenerateSeriesCode(ctx context.Context, seriesID string, userID string) (*models.MeetingSeries, error) {
+	series, err := s.getHostedSeries(ctx, seriesID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.replaceSeriesCode(ctx, series)
+}
+
+func (s *MeetingService) replaceSeriesCode(ctx context.Context, series *models.MeetingSeries) (*models.MeetingSeries, error) {
+	code, err := s.newSeriesCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	series.MeetingCode = code
+	if err := s.meetingRepo.UpdateSeries(ctx, series); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// newSeriesCode generates a code no meeting or series uses. Occurrences share the code of their
+// series, so the unique index on meetings can't catch a series taking the code of a meeting.
+func (s *MeetingService) newSeriesCode(ctx context.Context) (string, error) {
+	for range 5 {
+		code := generateMeetingCode(10)
+		inUse, err := s.meetingRepo.CodeInUse(ctx, code)
+		if err != nil {
+			return "", err
+		}
+		if !inUse {
+			return code, nil
+		}
+	}
+	return "", ErrCodeTaken
+}
+
+func (s *MeetingService) getHostedSeries(ctx context.Context, seriesID string, userID string) (*models.MeetingSeries, error) {
+	series, err := s.meetingRepo.GetSeriesByID(ctx, seriesID)
+	if err != nil {
+		return nil, ErrSeriesNotFound
+	}
+	if series.HostID != userID {
+		return nil, ErrNotAuthorized
+	}
+	return series, nil
+}
+
+// seriesOccurrence resolves a series code to the occurrence that can be joined now. It is only
+// stored once the join is allowed, see storeOccurrence. When the series is over an ended
+// meeting is returned, so joining fails the same way as for single meetings.
+func (s *MeetingService) seriesOccurrence(ctx context.Context, code string, now time.Time) (*models.Meeting, error) {
+	series, err := s.meetingRepo.GetSeriesByCode(ctx, code)
+	if err != nil {
+		return nil, ErrMeetingNotFound
+	}
+
+	occurrence := &models.Meeting{
+		Title:           series.Title,
+		HostID:          series.HostID,
+		MeetingCode:     series.MeetingCode,
+		PasswordHash:    series.PasswordHash,
+		IsPrivate:       series.IsPrivate,
+		DurationMinutes: series.DurationMinutes,
+		Timezone:        series.Timezone,
+		SeriesID:        series.ID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		Host:            series.Host,
+	}
+	if !series.EndedAt.IsZero() {
+		occurrence.EndedAt = series.EndedAt
+		return occurrence, nil
+	}
+
+	set, err := recurrenceSet(series)
+	if err != nil {
+		return nil, err
+	}
+	// an occurrence stays open for as long as the scheduler lets it overrun
+	startAt := set.After(now.Add(-series.Duration()-s.cfg.OverrunGrace), false)
+	if startAt.IsZero() {
+		occurrence.EndedAt = now
+		return occurrence, nil
+	}
+
+	if stored, err := s.meetingRepo.GetOccurrence(ctx, series.ID, startAt); err == nil {
+		return stored, nil
+	}
+	occurrence.ScheduledAt = startAt.UTC()
+	return occurrence, nil
+}
+
+// storeOccurrence saves the meeting of an occurrence when it is joined for the first time
+func (s *MeetingService) storeOccurrence(ctx context.Context, occurrence *models.Meeting) (*models.Meeting, error) {
+	occurrence.ID = uuid.NewString()
+	created, err := s.meetingRepo.CreateOccurrence(ctx, occurrence)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		// someone else joined first
+		return s.meetingRepo.GetOccurrence(ctx, occurrence.SeriesID, occurrence.ScheduledAt)
+	}
+
+	if occurrence.HostID != "" {
+		participant := &models.MeetingParticipant{
+			MeetingID: occurrence.ID,
+			UserID:    occurrence.HostID,
+			Role:      models.MeetingParticipantHost,
+		}
+		if err := s.meetingRepo.AddParticipant(ctx, participant); err != nil {
+			return nil, err
+		}
+	}
+	return occurrence, nil
+}
+
+func (s *MeetingService) expandSeries(series *models.MeetingSeries, from, to time.Time) ([]Occurrence, error) {
+	set, err := recurrenceSet(series)
+	if err != nil {
+		return nil, err
+	}
+
+	var occurrences []Occurrence
+	next := set.Iterator()
+	for startAt, ok := next(); ok && startAt.Before(to) && len(occurrences) < maxOccurrences; startAt, ok = next() {
+		endAt := startAt.Add(series.Duration())
+		if !endAt.After(from) {
+			continue
+		}
+		occurrences = append(occurrences, Occurrence{StartAt: startAt.UTC(), EndAt: endAt.UTC()})
+	}
+	return occurrences, nil
+}
+
+// normalizeRRule validates a recurrence rule and returns it in canonical form
+func normalizeRRule(rule string, timezone string) (string, error) {
+	opt, err := parseRRule(rule, timezone)
+	if err != nil {
+		return "", err
+	}
+	return opt.RRuleString(), nil
+}
+
+func parseRRule(rule string, timezone string) (*rrule.ROption, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidSchedule, timezone)
+	}
+
+	rule = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+	opt, err := rrule.StrToROptionInLocation(rule, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRecurrence, err)
+	}
+
+	switch {
+	case !slices.Contains(seriesFrequencies, opt.Freq):
+		return nil, fmt.Errorf("%w: FREQ must be DAILY, WEEKLY or MONTHLY", ErrInvalidRecurrence)
+	case !opt.Dtstart.IsZero():
+		return nil, fmt.Errorf("%w: DTSTART is taken from the start of the series", ErrInvalidRecurrence)
+	case len(opt.Byhour) > 0 || len(opt.Byminute) > 0 || len(opt.Bysecond) > 0:
+		return nil, fmt.Errorf("%w: occurrences start at the time of day of the series start", ErrInvalidRecurrence)
+	case opt.Count < 0 || opt.Interval < 0:
+		return nil, fmt.Errorf("%w: COUNT and INTERVAL must be positive", ErrInvalidRecurrence)
+	}
+	return opt, nil
+}
+
+// recurrenceSet builds the occurrences of a series. The rule is expanded in the series timezone
+// so occurrences keep their wall clock time across daylight saving changes.
+func recurrenceSet(series *models.MeetingSeries) (*rrule.Set, error) {
+	opt, err := parseRRule(series.RRule, series.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, _ := time.LoadLocation(series.Timezone)
+	opt.Dtstart = series.StartsAt.In(loc)
+	rule, err := rrule.NewRRule(*opt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRecurrence, err)
+	}
+
+	set := &rrule.Set{}
+	set.RRule(rule)
+	set.SetExDates(series.ExDates)
+	return set, nil
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+// PostgresStore keeps counters in the rate_limits table so every instance sees the same failures
+type PostgresStore struct {
+	db *bun.DB
+}
+
+func NewPostgresStore(db *bun.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Get(ctx context.Context, key string) (Attempts, error) {
+	limit := new(models.RateLimit)
+	err := s.db.NewSelect().Model(limit).Where("key = ?", key).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Attempts{}, nil
+	}
+	if err != nil {
+		return Attempts{}, err
+	}
+
+	return Attempts{Failures: limit.Failures, LastFailure: limit.LastFailure}, nil
+}
+
+func (s *PostgresStore) RecordFailure(ctx context.Context, key string, resetAfter time.Duration) (Attempts, error) {
+	now := time.Now()
+	limit := &models.RateLimit{
+		Key:         key,
+		Failures:    1,
+		LastFailure: now,
+	}
+
+	err := s.db.NewInsert().
+		Model(limit).
+		On("CONFLICT (key) DO UPDATE").
+		Set("failures = CASE WHEN rl.last_failure < ? THEN 1 ELSE rl.failures + 1 END", now.Add(-resetAfter)).
+		Set("last_failure = EXCLUDED.last_failure").
+		Returning("failures, last_failure").
+		Scan(ctx)
+	if err != nil {
+		return Attempts{}, err
+	}
+
+	return Attempts{Failures: limit.Failures, LastFailure: limit.LastFailure}, nil
+}
+
+func (s *PostgresStore) Reset(ctx context.Context, key string) error {
+	_, err := s.db.NewDelete().Model((*models.RateLimit)(nil)).Where("key = ?", key).Exec(ctx)
+	return err
+}
+
+func (s *PostgresStore) Cleanup(ctx context.Context, before time.Time) error {
+	_, err := s.db.NewDelete().Model((*models.RateLimit)(nil)).Where("last_failure < ?", before).Exec(ctx)
+	return err
+}
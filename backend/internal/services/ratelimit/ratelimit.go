@@ -0,0 +1,176 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var ErrLimited = errors.New("too many attempts")
+
+// LimitedError is returned when an attempt is refused, RetryAfter says when to try again
+type LimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LimitedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrLimited, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LimitedError) Is(target error) bool {
+	return target == ErrLimited
+}
+
+// Attempts is what a Store remembers about a key
+type Attempts struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// Store keeps failure counters. MemoryStore is enough for a single instance,
+// PostgresStore shares counters between instances.
+type Store interface {
+	Get(ctx context.Context, key string) (Attempts, error)
+	// RecordFailure increments the counter, starting over if the last failure is older than resetAfter
+	RecordFailure(ctx context.Context, key string, resetAfter time.Duration) (Attempts, error)
+	Reset(ctx context.Context, key string) error
+	// Cleanup removes keys whose last failure is before the given time
+	Cleanup(ctx context.Context, before time.Time) error
+}
+
+// Policy describes progressive backoff: the first FreeAttempts failures cost nothing,
+// after that every failure doubles the wait, starting at BaseDelay and capped at MaxDelay.
+// A policy with BaseDelay equal to MaxDelay is a plain lockout.
+type Policy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	// ResetAfter forgets failures after this long without a new one
+	ResetAfter time.Duration
+}
+
+func (p Policy) delay(failures int) time.Duration {
+	over := failures - p.FreeAttempts
+	if over <= 0 {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < over && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// Limiter applies a Policy to the keys of one namespace, e.g. login attempts per IP address
+type Limiter struct {
+	store     Store
+	policy    Policy
+	namespace string
+}
+
+func NewLimiter(store Store, namespace string, policy Policy) *Limiter {
+	return &Limiter{
+		store:     store,
+		policy:    policy,
+		namespace: namespace,
+	}
+}
+
+// key hashes id, ids like email addresses can be longer than the rate_limits key column
+func (l *Limiter) key(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return l.namespace + ":" + hex.EncodeToString(sum[:])
+}
+
+func (l *Limiter) wait(attempts Attempts, now time.Time) time.Duration {
+	if attempts.Failures == 0 || now.Sub(attempts.LastFailure) > l.policy.ResetAfter {
+		return 0
+	}
+	wait := attempts.LastFailure.Add(l.policy.delay(attempts.Failures)).Sub(now)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// Allow returns a *LimitedError if id has to wait before its next attempt
+func (l *Limiter) Allow(ctx context.Context, id string) error {
+	attempts, err := l.store.Get(ctx, l.key(id))
+	if err != nil {
+		return err
+	}
+
+	if wait := l.wait(attempts, time.Now()); wait > 0 {
+		return &LimitedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// Fail records a failed attempt
+func (l *Limiter) Fail(ctx context.Context, id string) error {
+	_, err := l.store.RecordFailure(ctx, l.key(id), l.policy.ResetAfter)
+	return err
+}
+
+// Reset forgets the failures of id, e.g. after a successful login
+func (l *Limiter) Reset(ctx context.Context, id string) error {
+	return l.store.Reset(ctx, l.key(id))
+}
+
+// AllowAll checks several limiters and returns the longest wait among them
+func AllowAll(ctx context.Context, checks map[*Limiter]string) error {
+	var longest *LimitedError
+	for limiter, id := range checks {
+		err := limiter.Allow(ctx, id)
+		var limited *LimitedError
+		switch {
+		case err == nil:
+		case errors.As(err, &limited):
+			if longest == nil || limited.RetryAfter > longest.RetryAfter {
+				longest = limited
+			}
+		default:
+			return err
+		}
+	}
+
+	if longest != nil {
+		return longest
+	}
+	return nil
+}
+
+// FailAll records a failure on several limiters, errors are logged since the attempt already failed
+func FailAll(ctx context.Context, checks map[*Limiter]string) {
+	for limiter, id := range checks {
+		if err := limiter.Fail(ctx, id); err != nil {
+			slog.Error("failed to record failed attempt", "key", limiter.key(id), "error", err)
+		}
+	}
+}
+
+// StartCleanup periodically removes keys that have not failed for maxAge, until ctx is done
+func StartCleanup(ctx context.Context, store Store, interval, maxAge time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := store.Cleanup(ctx, time.Now().Add(-maxAge)); err != nil {
+					slog.Error("failed to clean up rate limits", "error", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,72 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/meetia/backend/internal/models"
+)
+
+var ErrCalendarFeedNotFound = errors.New("calendar feed not found")
+
+// CalendarFeedPathPrefix is where the API serves calendar feeds, followed by the secret token and .ics
+const CalendarFeedPathPrefix = "/api/calendar/"
+
+// EnableCalendarFeed issues a secret calendar feed URL for the user, replacing the previous one.
+// The token is only returned here, the database keeps a hash.
+func (s *UserService) EnableCalendarFeed(ctx context.Context, userID string) (string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "", ErrUserNotFound
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	user.CalendarTokenHash = hashCalendarToken(token)
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return "", err
+	}
+	return CalendarFeedPathPrefix + token + ".ics", nil
+}
+
+// DisableCalendarFeed turns the feed off, its URL stops working
+func (s *UserService) DisableCalendarFeed(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if user.CalendarTokenHash == "" {
+		return nil
+	}
+
+	user.CalendarTokenHash = ""
+	user.UpdatedAt = time.Now()
+	return s.userRepo.Update(ctx, user)
+}
+
+// GetCalendarFeedOwner returns the user a calendar feed token belongs to
+func (s *UserService) GetCalendarFeedOwner(ctx context.Context, token string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrCalendarFeedNotFound
+	}
+	user, err := s.userRepo.GetByCalendarTokenHash(ctx, hashCalendarToken(token))
+	if err != nil {
+		return nil, ErrCalendarFeedNotFound
+	}
+	return user, nil
+}
+
+func hashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
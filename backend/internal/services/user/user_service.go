@@ -0,0 +1,221 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/meetia/backend/internal/models"
+	"github.com/meetia/backend/internal/repository"
+	"github.com/meetia/backend/internal/services/auth"
+	"github.com/meetia/backend/internal/services/storage"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrAvatarTooLarge     = errors.New("avatar image is too large")
+	ErrUnsupportedAvatar  = errors.New("avatar must be a PNG, JPEG, GIF or WebP image")
+	ErrAvatarNotFound     = errors.New("avatar not found")
+	ErrInvalidDisplayName = errors.New("display name cannot be empty")
+)
+
+// AvatarPathPrefix is where the API serves avatars, followed by the blob name
+const AvatarPathPrefix = "/api/users/avatars/"
+
+const avatarKeyPrefix = "avatars/"
+
+// avatarTypes are the accepted image types and the file extension they are stored with
+var avatarTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+type UserService struct {
+	userRepo       *repository.UserRepository
+	authService    *auth.AuthService
+	blobs          storage.BlobStore
+	maxAvatarBytes int64
+}
+
+func NewUserService(userRepo *repository.UserRepository, authService *auth.AuthService, blobs storage.BlobStore, maxAvatarBytes int64) *UserService {
+	return &UserService{
+		userRepo:       userRepo,
+		authService:    authService,
+		blobs:          blobs,
+		maxAvatarBytes: maxAvatarBytes,
+	}
+}
+
+// MaxAvatarBytes is the largest avatar image accepted by SetAvatar
+func (s *UserService) MaxAvatarBytes() int64 {
+	return s.maxAvatarBytes
+}
+
+func (s *UserService) GetProfile(ctx context.Context, userID string) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// ProfileUpdate holds the fields of a partial profile update, nil fields are left unchanged
+type ProfileUpdate struct {
+	DisplayName *string
+	Email       *string
+	// Confirmation is required when changing the email address
+	Confirmation auth.Confirmation
+}
+
+func (s *UserService) UpdateProfile(ctx context.Context, userID string, update ProfileUpdate) (*models.User, error) {
+	if update.DisplayName != nil && strings.TrimSpace(*update.DisplayName) == "" {
+		return nil, ErrInvalidDisplayName
+	}
+
+	if update.Email != nil {
+		if _, err := s.authService.ChangeEmail(ctx, userID, update.Confirmation, *update.Email); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if update.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*update.DisplayName)
+		user.UpdatedAt = time.Now()
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// SetAvatar stores a new avatar image and replaces the previous one
+func (s *UserService) SetAvatar(ctx context.Context, userID string, r io.Reader) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.maxAvatarBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxAvatarBytes {
+		return nil, ErrAvatarTooLarge
+	}
+
+	// the declared content type of an upload can't be trusted
+	ext, ok := avatarTypes[http.DetectContentType(data)]
+	if !ok {
+		return nil, ErrUnsupportedAvatar
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	// a new name per upload lets clients cache avatars forever
+	name := user.ID + "-" + hex.EncodeToString(suffix) + ext
+	if err := s.blobs.Put(ctx, avatarKeyPrefix+name, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	previous := user.AvatarURL
+	user.AvatarURL = AvatarPathPrefix + name
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		s.deleteAvatarBlob(ctx, user.AvatarURL)
+		return nil, err
+	}
+
+	s.deleteAvatarBlob(ctx, previous)
+	return user, nil
+}
+
+func (s *UserService) RemoveAvatar(ctx context.Context, userID string) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.AvatarURL == "" {
+		return user, nil
+	}
+
+	previous := user.AvatarURL
+	user.AvatarURL = ""
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	s.deleteAvatarBlob(ctx, previous)
+	return user, nil
+}
+
+// GetAvatar opens the avatar blob with the given name and returns its content type
+func (s *UserService) GetAvatar(ctx context.Context, name string) (io.ReadCloser, string, error) {
+	contentType := ""
+	for t, ext := range avatarTypes {
+		if path.Ext(name) == ext {
+			contentType = t
+		}
+	}
+	if contentType == "" || strings.Contains(name, "/") {
+		return nil, "", ErrAvatarNotFound
+	}
+
+	blob, err := s.blobs.Get(ctx, avatarKeyPrefix+name)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			return nil, "", ErrAvatarNotFound
+		}
+		return nil, "", err
+	}
+	return blob, contentType, nil
+}
+
+// DeleteAccount removes the user once the deletion is confirmed. Meetings, participations and
+// chat messages stay for the other participants with the author removed, see the foreign keys.
+// The personal meeting is ended, its code can't be joined anymore. Refresh and personal access
+// tokens go with the account, its access tokens are refused once it is gone, see VerifyToken.
+func (s *UserService) DeleteAccount(ctx context.Context, userID string, confirmation auth.Confirmation) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.authService.Confirm(ctx, user, confirmation); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.Delete(ctx, user.ID); err != nil {
+		return err
+	}
+
+	s.deleteAvatarBlob(ctx, user.AvatarURL)
+	return nil
+}
+
+func (s *UserService) deleteAvatarBlob(ctx context.Context, avatarURL string) {
+	name, ok := strings.CutPrefix(avatarURL, AvatarPathPrefix)
+	if !ok || name == "" {
+		return
+	}
+	if err := s.blobs.Delete(ctx, avatarKeyPrefix+name); err != nil {
+		slog.Error("failed to delete avatar", "key", avatarKeyPrefix+name, "error", err)
+	}
+}
@@ -0,0 +1,197 @@
+package webrtc
+
+import (
+	"log"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// MoveToBreakout moves a user's media into a breakout room of a meeting room, creating the
+// breakout room on first use. An empty breakoutID moves them back to the meeting room. The
+// websocket stays connected, the peers that gain or lose tracks are sent new offers.
+// Users without a peer connection are skipped, they are placed when they connect.
+func (s *SFUService) MoveToBreakout(roomID string, breakoutID string, userID string) {
+	s.roomsMutex.Lock()
+	defer s.roomsMutex.Unlock()
+
+	room, exists := s.rooms[roomID]
+	if !exists {
+		return
+	}
+	peer := room.findPeer(userID)
+	if peer == nil {
+		return
+	}
+
+	target := room
+	if breakoutID != "" {
+		target = room.breakout(breakoutID)
+	}
+	s.movePeer(peer, target)
+}
+
+// CloseBreakouts moves everyone in the breakout rooms of a meeting room back into it
+func (s *SFUService) CloseBreakouts(roomID string) {
+	s.roomsMutex.Lock()
+	defer s.roomsMutex.Unlock()
+
+	room, exists := s.rooms[roomID]
+	if !exists {
+		return
+	}
+
+	for _, breakout := range room.Breakouts {
+		for _, peer := range breakout.Peers {
+			s.movePeer(peer, room)
+		}
+	}
+	room.Breakouts = make(map[string]*Room)
+}
+
+// movePeer swaps the media of a peer from its room to the target room, the caller holds roomsMutex
+func (s *SFUService) movePeer(peer *Peer, target *Room) {
+	source := peer.Room
+	if source == target {
+		return
+	}
+	renegotiate := map[*Peer]bool{}
+
+	// the others in the source room stop receiving the peer's media, and the peer theirs
+	delete(source.Peers, peer.ID)
+	for trackID, track := range peer.Tracks {
+		delete(source.Tracks, trackID)
+		for _, other := range source.Peers {
+			if removeTrack(other, track) {
+				renegotiate[other] = true
+			}
+		}
+	}
+	for _, track := range source.Tracks {
+		if removeTrack(peer, track) {
+			renegotiate[peer] = true
+		}
+	}
+
+	peer.Room = target
+	target.Peers[peer.ID] = peer
+	for trackID, track := range peer.Tracks {
+		target.Tracks[trackID] = track
+		for _, other := range target.Peers {
+			if other == peer {
+				continue
+			}
+			if _, err := other.Connection.AddTrack(track); err != nil {
+				log.Printf("Failed to add track %s to peer %s: %v\n", trackID, other.ID, err)
+				continue
+			}
+			renegotiate[other] = true
+		}
+	}
+	for trackID, track := range target.Tracks {
+		if track.StreamID() == peer.ID {
+			continue
+		}
+		if _, err := peer.Connection.AddTrack(track); err != nil {
+			log.Printf("Failed to add track %s to peer %s: %v\n", trackID, peer.ID, err)
+			continue
+		}
+		renegotiate[peer] = true
+	}
+
+	for p := range renegotiate {
+		sendOffer(p)
+	}
+}
+
+// breakout returns the breakout room with the id, creating it if needed
+func (r *Room) breakout(id string) *Room {
+	if breakout, exists := r.Breakouts[id]; exists {
+		return breakout
+	}
+
+	breakout := &Room{
+		ID:        id,
+		Peers:     make(map[string]*Peer),
+		Tracks:    make(map[string]*webrtc.TrackLocalStaticRTP),
+		Lobby:     make(map[string]chan *SignalMessage),
+		Breakouts: make(map[string]*Room),
+		Parent:    r,
+		CreatedAt: time.Now(),
+		closeChan: r.closeChan,
+	}
+	r.Breakouts[id] = breakout
+	return breakout
+}
+
+// root returns the meeting room of a breakout room, or the room itself
+func (r *Room) root() *Room {
+	if r.Parent != nil {
+		return r.Parent
+	}
+	return r
+}
+
+// userIDs lists the users connected to the room and its breakout rooms
+func (r *Room) userIDs() []string {
+	userIDs := make([]string, 0, len(r.Peers))
+	for userID := range r.Peers {
+		userIDs = append(userIDs, userID)
+	}
+	for _, breakout := range r.Breakouts {
+		for userID := range breakout.Peers {
+			if _, ok := r.Peers[userID]; !ok {
+				userIDs = append(userIDs, userID)
+			}
+		}
+	}
+	return userIDs
+}
+
+// findPeer looks for a user's peer in the room and its breakout rooms
+func (r *Room) findPeer(userID string) *Peer {
+	if peer, ok := r.Peers[userID]; ok {
+		return peer
+	}
+	for _, breakout := range r.Breakouts {
+		if peer, ok := breakout.Peers[userID]; ok {
+			return peer
+		}
+	}
+	return nil
+}
+
+// removeTrack stops sending a track to a peer, it reports whether the peer was receiving it
+func removeTrack(peer *Peer, track *webrtc.TrackLocalStaticRTP) bool {
+	for _, sender := range peer.Connection.GetSenders() {
+		if sender.Track() != track {
+			continue
+		}
+		if err := peer.Connection.RemoveTrack(sender); err != nil {
+			log.Printf("Failed to remove track %s from peer %s: %v\n", track.ID(), peer.ID, err)
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// sendOffer renegotiates the peer connection after its tracks changed
+func sendOffer(peer *Peer) {
+	offer, err := peer.Connection.CreateOffer(nil)
+	if err != nil {
+		log.Printf("Failed to create offer for peer %s: %v\n", peer.ID, err)
+		return
+	}
+	if err := peer.Connection.SetLocalDescription(offer); err != nil {
+		log.Printf("Failed to set local description for peer %s: %v\n", peer.ID, err)
+		return
+	}
+
+	peer.send(&SignalMessage{
+		Type:      "offer",
+		SDP:       offer.SDP,
+		UserID:    "server",
+		MeetingID: peer.Room.root().ID,
+	})
+}
@@ -0,0 +1,152 @@
+package webrtc
+
+import (
+	"log"
+	"slices"
+	"time"
+)
+
+// Events the SFU pushes about the speaking queue and reactions
+const (
+	// EventHands carries the whole queue, as []HandRaise, after every change and on connect
+	EventHands = "hands"
+	// EventHandCalled tells everyone who a host called on, with their HandRaise
+	EventHandCalled = "hand-called"
+	// EventReaction is an emoji someone sent to their room, with a Reaction
+	EventReaction = "reaction"
+)
+
+// RaiseHand puts a user at the end of the speaking queue of a meeting room, it reports false
+// when their hand was up already
+func (s *SFUService) RaiseHand(roomID string, userID string) bool {
+	s.roomsMutex.Lock()
+	defer s.roomsMutex.Unlock()
+
+	room, exists := s.rooms[roomID]
+	if !exists {
+		return false
+	}
+	if slices.ContainsFunc(room.Hands, func(h HandRaise) bool { return h.UserID == userID }) {
+		return false
+	}
+
+	room.Hands = append(room.Hands, HandRaise{UserID: userID, RaisedAt: time.Now()})
+	room.broadcastHands()
+	return true
+}
+
+// LowerHands takes users out of the speaking queue, everyone when userIDs is nil.
+// It returns the users whose hand was up.
+func (s *SFUService) LowerHands(roomID string, userIDs []string) []string {
+	s.roomsMutex.Lock()
+	defer s.roomsMutex.Unlock()
+
+	room, exists := s.rooms[roomID]
+	if !exists {
+		return nil
+	}
+
+	var lowered []string
+	room.Hands = slices.DeleteFunc(room.Hands, func(h HandRaise) bool {
+		if userIDs != nil && !slices.Contains(userIDs, h.UserID) {
+			return false
+		}
+		lowered = append(lowered, h.UserID)
+		return true
+	})
+	if len(lowered) > 0 {
+		room.broadcastHands()
+	}
+	return lowered
+}
+
+// NextHand calls on the first user in the speaking queue and takes them out of it
+func (s *SFUService) NextHand(roomID string) (HandRaise, bool) {
+	s.roomsMutex.Lock()
+	defer s.roomsMutex.Unlock()
+
+	room, exists := s.rooms[roomID]
+	if !exists || len(room.Hands) == 0 {
+		return HandRaise{}, false
+	}
+
+	next := room.Hands[0]
+	room.Hands = slices.Delete(room.Hands, 0, 1)
+	room.broadcast(&SignalMessage{Type: EventHandCalled, UserID: next.UserID, MeetingID: room.ID, Data: next})
+	room.broadcastHands()
+	return next, true
+}
+
+// Hands returns the speaking queue of a meeting room, first in line first
+func (s *SFUService) Hands(roomID string) []HandRaise {
+	s.roomsMutex.Lock()
+	defer s.roomsMutex.Unlock()
+
+	room, exists := s.rooms[roomID]
+	if !exists {
+		return []HandRaise{}
+	}
+	return append([]HandRaise{}, room.Hands...)
+}
+
+// React sends an emoji to everyone in the room the user is in, a breakout room while they are in one.
+// Reactions aren't kept.
+func (s *SFUService) React(roomID string, userID string, emoji string) {
+	s.roomsMutex.Lock()
+	defer s.roomsMutex.Unlock()
+
+	room, exists := s.rooms[roomID]
+	if !exists {
+		return
+	}
+	peer := room.findPeer(userID)
+	if peer == nil {
+		return
+	}
+
+	msg := &SignalMessage{
+		Type:      EventReaction,
+		UserID:    userID,
+		MeetingID: room.ID,
+		Data:      Reaction{UserID: userID, Emoji: emoji},
+	}
+	for _, other := range peer.Room.Peers {
+		other.send(msg)
+	}
+}
+
+// broadcastHands sends the speaking queue to everyone in the room, the caller holds roomsMutex
+func (r *Room) broadcastHands() {
+	r.broadcast(&SignalMessage{
+		Type:      EventHands,
+		UserID:    "server",
+		MeetingID: r.ID,
+		Data:      append([]HandRaise{}, r.Hands...),
+	})
+}
+
+// broadcast sends a message to the peers of a meeting room and its breakout rooms, the caller holds roomsMutex
+func (r *Room) broadcast(msg *SignalMessage) {
+	for _, peer := range r.Peers {
+		peer.send(msg)
+	}
+	for _, breakout := range r.Breakouts {
+		for _, peer := range breakout.Peers {
+			peer.send(msg)
+		}
+	}
+}
+
+// send queues a message for the peer's websocket without blocking on a stalled client
+func (p *Peer) send(msg *SignalMessage) {
+	sendSignal(p.SignalChannel, p.ID, msg)
+}
+
+// sendSignal queues a message on a signal channel, dropping it when the channel is full
+func sendSignal(events chan *SignalMessage, to string, msg *SignalMessage) {
+	select {
+	case events <- msg:
+	default:
+		log.Printf("Dropped %s message for %s, signal channel full\n", msg.Type, to)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io"
 	"log"
+	"slices"
 	"sync"
 	"time"
 
@@ -11,10 +12,25 @@ import (
 	"github.com/pion/webrtc/v3"
 )
 
+// ErrRoomFull is returned when a room has as many users connected as it allows
+var ErrRoomFull = errors.New("meeting full")
+
 type SFUService struct {
 	rooms      map[string]*Room
 	roomsMutex sync.Mutex
 	config     webrtc.Configuration
+	listener   PeerListener
+}
+
+// PeerListener hears when users connect their media to a meeting room and when the connection goes away
+type PeerListener interface {
+	PeerJoined(roomID string, userID string)
+	PeerLeft(roomID string, userID string)
+}
+
+// SetPeerListener registers the listener of the peer lifecycle, call it before serving connections
+func (s *SFUService) SetPeerListener(listener PeerListener) {
+	s.listener = listener
 }
 
 func NewSFUService() *SFUService {
@@ -59,6 +75,8 @@ func (s *SFUService) GetOrCreateRoom(roomID string) *Room {
 		ID:        roomID,
 		Peers:     make(map[string]*Peer),
 		Tracks:    make(map[string]*webrtc.TrackLocalStaticRTP),
+		Lobby:     make(map[string]chan *SignalMessage),
+		Breakouts: make(map[string]*Room),
 		CreatedAt: time.Now(),
 		closeChan: make(chan struct{}),
 	}
@@ -77,7 +95,70 @@ func (s *SFUService) RemoveRoom(roomID string) {
 	}
 }
 
-func (s *SFUService) CreatePeerConnection(roomID string, peerID string) (*Peer, error) {
+// WaitInLobby registers a user waiting for admission to a room. Events sent to them with Notify
+// arrive on the returned channel until leave is called.
+func (s *SFUService) WaitInLobby(roomID string, userID string) (<-chan *SignalMessage, func()) {
+	room := s.GetOrCreateRoom(roomID)
+	events := make(chan *SignalMessage, 10)
+
+	s.roomsMutex.Lock()
+	room.Lobby[userID] = events
+	s.roomsMutex.Unlock()
+
+	leave := func() {
+		s.roomsMutex.Lock()
+		defer s.roomsMutex.Unlock()
+		if room.Lobby[userID] == events {
+			delete(room.Lobby, userID)
+		}
+	}
+	return events, leave
+}
+
+// Notify sends an event to the users of a room connected to signaling, whether they have a
+// peer connection, are in one of its breakout rooms or wait in the lobby. Users that aren't
+// connected are skipped.
+func (s *SFUService) Notify(roomID string, userIDs []string, event string, data any) {
+	s.roomsMutex.Lock()
+	defer s.roomsMutex.Unlock()
+
+	room, exists := s.rooms[roomID]
+	if !exists {
+		return
+	}
+
+	for _, userID := range userIDs {
+		msg := &SignalMessage{
+			Type:      event,
+			UserID:    userID,
+			MeetingID: roomID,
+			Data:      data,
+		}
+
+		// a stalled client must not block the sender
+		if peer := room.findPeer(userID); peer != nil {
+			peer.send(msg)
+		} else if waiting, ok := room.Lobby[userID]; ok {
+			sendSignal(waiting, userID, msg)
+		}
+	}
+}
+
+// ConnectedUsers lists the users connected to a meeting room, breakout rooms included
+func (s *SFUService) ConnectedUsers(roomID string) []string {
+	s.roomsMutex.Lock()
+	defer s.roomsMutex.Unlock()
+
+	room, exists := s.rooms[roomID]
+	if !exists {
+		return []string{}
+	}
+	return room.userIDs()
+}
+
+// CreatePeerConnection connects a user's media to a meeting room. maxPeers caps the users connected
+// to the room and its breakout rooms, 0 for no cap. A user replacing their own connection always gets in.
+func (s *SFUService) CreatePeerConnection(roomID string, peerID string, maxPeers int) (*Peer, error) {
 	room := s.GetOrCreateRoom(roomID)
 
 	// create new peer connection
@@ -102,8 +183,18 @@ func (s *SFUService) CreatePeerConnection(roomID string, peerID string) (*Peer,
 	}
 	peer.DataChannel = dataChannel
 
-	// add peer to room
+	// add peer to room, replacing an earlier connection of the user
+	s.roomsMutex.Lock()
+	if maxPeers > 0 && room.findPeer(peerID) == nil && len(room.userIDs()) >= maxPeers {
+		s.roomsMutex.Unlock()
+		peerConnection.Close()
+		return nil, ErrRoomFull
+	}
 	room.Peers[peerID] = peer
+	s.roomsMutex.Unlock()
+	if s.listener != nil {
+		s.listener.PeerJoined(roomID, peerID)
+	}
 
 	// setup ICE connection state handler
 	peerConnection.OnICEConnectionStateChange(func(state webrtc.ICEConnectionState) {
@@ -114,10 +205,7 @@ func (s *SFUService) CreatePeerConnection(roomID string, peerID string) (*Peer,
 			time.AfterFunc(10*time.Second, func() {
 				if peer.Connection.ICEConnectionState() == webrtc.ICEConnectionStateDisconnected {
 					log.Printf("Peer %s permanent disconnect, cleaning up\n", peerID)
-					s.roomsMutex.Lock()
-					delete(room.Peers, peerID)
-					s.roomsMutex.Unlock()
-					peerConnection.Close()
+					s.ClosePeer(peer)
 				}
 			})
 			return
@@ -125,10 +213,7 @@ func (s *SFUService) CreatePeerConnection(roomID string, peerID string) (*Peer,
 
 		if state == webrtc.ICEConnectionStateFailed ||
 			state == webrtc.ICEConnectionStateClosed {
-			s.roomsMutex.Lock()
-			delete(room.Peers, peerID)
-			s.roomsMutex.Unlock()
-			peerConnection.Close()
+			s.ClosePeer(peer)
 		}
 	})
 
@@ -143,12 +228,14 @@ func (s *SFUService) CreatePeerConnection(roomID string, peerID string) (*Peer,
 			return
 		}
 
-		// add track to room
-		room.Tracks[remoteTrack.ID()] = trackLocal
+		// add track to the room the peer is in, which is a breakout room while they are in one
+		s.roomsMutex.Lock()
+		current := peer.Room
+		current.Tracks[remoteTrack.ID()] = trackLocal
 		peer.Tracks[remoteTrack.ID()] = trackLocal
 
 		// send tracks to existing peers in the room
-		for otherPeerID, otherPeer := range room.Peers {
+		for otherPeerID, otherPeer := range current.Peers {
 			if otherPeerID == peerID {
 				continue
 			}
@@ -172,15 +259,16 @@ func (s *SFUService) CreatePeerConnection(roomID string, peerID string) (*Peer,
 				continue
 			}
 
-			// send the offer to the other peer
-			otherPeer.SignalChannel <- &SignalMessage{
+			// send the offer to the other peer, never blocking while every room is locked
+			otherPeer.send(&SignalMessage{
 				Type:      "offer",
 				SDP:       offer.SDP,
 				UserID:    peerID,
 				MeetingID: roomID,
 				TrackID:   remoteTrack.ID(),
-			}
+			})
 		}
+		s.roomsMutex.Unlock()
 
 		// read packets from the track and forward them
 		go func() {
@@ -260,6 +348,51 @@ func (s *SFUService) CreatePeerConnection(roomID string, peerID string) (*Peer,
 	return peer, nil
 }
 
+// ClosePeer takes a peer out of its room and closes its connection. The listener hears the user
+// left unless a newer connection of theirs replaced the peer.
+func (s *SFUService) ClosePeer(peer *Peer) {
+	s.roomsMutex.Lock()
+	current := peer.Room
+	removed := current.Peers[peer.ID] == peer
+	if removed {
+		delete(current.Peers, peer.ID)
+	}
+	roomID := current.root().ID
+	s.roomsMutex.Unlock()
+
+	if err := peer.Connection.Close(); err != nil {
+		log.Printf("Failed to close peer %s: %v\n", peer.ID, err)
+	}
+	if removed && s.listener != nil {
+		s.listener.PeerLeft(roomID, peer.ID)
+	}
+}
+
+// Disconnect closes the peers of a room and its breakout rooms, except those of the users in keep
+func (s *SFUService) Disconnect(roomID string, keep []string) {
+	s.roomsMutex.Lock()
+	var peers []*Peer
+	if room, exists := s.rooms[roomID]; exists {
+		rooms := []*Room{room}
+		for _, breakout := range room.Breakouts {
+			rooms = append(rooms, breakout)
+		}
+		for _, r := range rooms {
+			for userID, peer := range r.Peers {
+				if !slices.Contains(keep, userID) && !slices.Contains(peers, peer) {
+					peers = append(peers, peer)
+				}
+			}
+		}
+	}
+	s.roomsMutex.Unlock()
+
+	// ClosePeer takes the lock itself
+	for _, peer := range peers {
+		s.ClosePeer(peer)
+	}
+}
+
 func (s *SFUService) HandleOffer(peer *Peer, offer webrtc.SessionDescription) (webrtc.SessionDescription, error) {
 	if peer.Connection.SignalingState() == webrtc.SignalingStateHaveRemoteOffer {
 		peer.Connection.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer})
@@ -15,6 +15,7 @@ type SignalMessage struct {
 	MeetingID string                   `json:"meetingId"`
 	TrackID   string                   `json:"trackId,omitempty"`
 	Target    string                   `json:"target,omitempty"` // target user id for p2p messages
+	Data      any                      `json:"data,omitempty"`   // payload of meeting events
 }
 
 // Room represents a meeting room with multiple peers
@@ -22,10 +23,26 @@ type Room struct {
 	ID        string
 	Peers     map[string]*Peer
 	Tracks    map[string]*webrtc.TrackLocalStaticRTP
+	Lobby     map[string]chan *SignalMessage // users waiting for admission, by user id
+	Breakouts map[string]*Room               // breakout rooms of a meeting room, by id
+	Parent    *Room                          // the meeting room of a breakout room
+	Hands     []HandRaise                    // speaking queue in the order hands went up, kept across reconnects
 	CreatedAt time.Time
 	closeChan chan struct{}
 }
 
+// HandRaise is a participant waiting to speak
+type HandRaise struct {
+	UserID   string    `json:"userId"`
+	RaisedAt time.Time `json:"raisedAt"`
+}
+
+// Reaction is a transient emoji a participant sent to their room
+type Reaction struct {
+	UserID string `json:"userId"`
+	Emoji  string `json:"emoji"`
+}
+
 type Peer struct {
 	ID            string
 	Connection    *webrtc.PeerConnection
@@ -3,12 +3,23 @@ package humagroup
 import (
 	"context"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/danielgtaylor/huma/v2"
+	"github.com/go-chi/jwtauth/v5"
 )
 
 // huma lacks route group so I created one
 
+// ScopeClaim holds the space separated scopes of a restricted token, such as a personal access
+// token. Tokens without the claim, like the ones from an interactive login, may call any operation.
+const ScopeClaim = "scope"
+
+// GuestClaim holds the meeting a guest token was issued for. Guest tokens may only call operations
+// that name the path parameter holding the meeting id, see HumaGroupOptions.GuestParam.
+const GuestClaim = "guest_meeting"
+
 type HumaGroup struct {
 	basePath    string
 	tags        []string
@@ -19,6 +30,14 @@ type HumaGroup struct {
 type HumaGroupOptions struct {
 	Summary     string
 	Description string
+	// MaxBodyBytes overrides huma's default request body limit of 1MB
+	MaxBodyBytes int64
+	// Scopes a restricted token needs to call the operation. Restricted tokens can't call
+	// operations without scopes.
+	Scopes []string
+	// GuestParam is the path parameter with the meeting id when guests of that meeting may call
+	// the operation. Guest tokens can't call operations without it.
+	GuestParam string
 }
 
 func NewHumaGroup(
@@ -47,18 +66,7 @@ func Post[I, O any](
 	options *HumaGroupOptions,
 	middlewares ...func(ctx huma.Context, next func(huma.Context)),
 ) {
-	operation := huma.Operation{
-		OperationID: operationName,
-		Method:      http.MethodPost,
-		Path:        g.basePath + path,
-		Tags:        g.tags,
-		Middlewares: append(g.middlewares, middlewares...),
-	}
-	if options != nil {
-		operation.Summary = options.Summary
-		operation.Description = options.Description
-	}
-	huma.Register(g.api, operation, handler)
+	huma.Register(g.api, g.operation(http.MethodPost, path, operationName, options, middlewares), handler)
 }
 
 func Get[I, O any](
@@ -69,18 +77,7 @@ func Get[I, O any](
 	options *HumaGroupOptions,
 	middlewares ...func(ctx huma.Context, next func(huma.Context)),
 ) {
-	operation := huma.Operation{
-		OperationID: operationName,
-		Method:      http.MethodGet,
-		Path:        g.basePath + path,
-		Tags:        g.tags,
-		Middlewares: append(g.middlewares, middlewares...),
-	}
-	if options != nil {
-		operation.Summary = options.Summary
-		operation.Description = options.Description
-	}
-	huma.Register(g.api, operation, handler)
+	huma.Register(g.api, g.operation(http.MethodGet, path, operationName, options, middlewares), handler)
 }
 
 func Patch[I, O any](
@@ -88,16 +85,10 @@ func Patch[I, O any](
 	path string,
 	handler func(context.Context, *I) (*O, error),
 	operationName string,
+	options *HumaGroupOptions,
 	middlewares ...func(ctx huma.Context, next func(huma.Context)),
 ) {
-	operation := huma.Operation{
-		OperationID: operationName,
-		Method:      http.MethodPatch,
-		Path:        g.basePath + path,
-		Tags:        g.tags,
-		Middlewares: append(g.middlewares, middlewares...),
-	}
-	huma.Register(g.api, operation, handler)
+	huma.Register(g.api, g.operation(http.MethodPatch, path, operationName, options, middlewares), handler)
 }
 
 func Put[I, O any](
@@ -105,16 +96,10 @@ func Put[I, O any](
 	path string,
 	handler func(context.Context, *I) (*O, error),
 	operationName string,
+	options *HumaGroupOptions,
 	middlewares ...func(ctx huma.Context, next func(huma.Context)),
 ) {
-	operation := huma.Operation{
-		OperationID: operationName,
-		Method:      http.MethodPut,
-		Path:        g.basePath + path,
-		Tags:        g.tags,
-		Middlewares: append(g.middlewares, middlewares...),
-	}
-	huma.Register(g.api, operation, handler)
+	huma.Register(g.api, g.operation(http.MethodPut, path, operationName, options, middlewares), handler)
 }
 
 func Delete[I, O any](
@@ -122,14 +107,87 @@ func Delete[I, O any](
 	path string,
 	handler func(context.Context, *I) (*O, error),
 	operationName string,
+	options *HumaGroupOptions,
 	middlewares ...func(ctx huma.Context, next func(huma.Context)),
 ) {
+	huma.Register(g.api, g.operation(http.MethodDelete, path, operationName, options, middlewares), handler)
+}
+
+func (g *HumaGroup) operation(
+	method string,
+	path string,
+	operationName string,
+	options *HumaGroupOptions,
+	middlewares huma.Middlewares,
+) huma.Operation {
+	var scopes []string
+	var guestParam string
 	operation := huma.Operation{
 		OperationID: operationName,
-		Method:      http.MethodDelete,
+		Method:      method,
 		Path:        g.basePath + path,
 		Tags:        g.tags,
-		Middlewares: append(g.middlewares, middlewares...),
 	}
-	huma.Register(g.api, operation, handler)
+	if options != nil {
+		operation.Summary = options.Summary
+		operation.Description = options.Description
+		operation.MaxBodyBytes = options.MaxBodyBytes
+		scopes = options.Scopes
+		guestParam = options.GuestParam
+	}
+	if len(scopes) > 0 {
+		operation.Description += "\n\nPersonal access tokens need the scopes: " + strings.Join(scopes, ", ")
+	}
+
+	// the guest and scope checks run last, after the auth middlewares put the token in the context
+	operation.Middlewares = slices.Concat(g.middlewares, middlewares, huma.Middlewares{restrictGuests(g.api, guestParam), requireScopes(g.api, scopes)})
+	return operation
+}
+
+// restrictGuests rejects guest tokens unless the operation takes guests and is about their meeting
+func restrictGuests(api huma.API, guestParam string) func(ctx huma.Context, next func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		_, claims, _ := jwtauth.FromContext(ctx.Context())
+		meetingID, guest := claims[GuestClaim].(string)
+		if !guest {
+			next(ctx)
+			return
+		}
+
+		if guestParam == "" {
+			huma.WriteErr(api, ctx, http.StatusForbidden, "this operation is not available to guests")
+			return
+		}
+		if ctx.Param(guestParam) != meetingID {
+			huma.WriteErr(api, ctx, http.StatusForbidden, "guest tokens are only good for the meeting they were issued for")
+			return
+		}
+		next(ctx)
+	}
+}
+
+// requireScopes rejects restricted tokens that were not granted every scope of the operation
+func requireScopes(api huma.API, scopes []string) func(ctx huma.Context, next func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		_, claims, _ := jwtauth.FromContext(ctx.Context())
+		granted, restricted := claims[ScopeClaim].(string)
+		if !restricted {
+			next(ctx)
+			return
+		}
+
+		if len(scopes) == 0 {
+			huma.WriteErr(api, ctx, http.StatusForbidden, "this operation is not available to personal access tokens")
+			return
+		}
+
+		have := strings.Fields(granted)
+		for _, scope := range scopes {
+			if !slices.Contains(have, scope) {
+				huma.WriteErr(api, ctx, http.StatusForbidden, "token is missing the "+scope+" scope")
+				return
+			}
+		}
+		next(ctx)
+	}
 }
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE meeting_chats
+    ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('english', message)) STORED;
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+
+CREATE INDEX idx_meeting_chats_search_vector ON meeting_chats USING GIN (search_vector);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_meeting_chats_search_vector;
+ALTER TABLE meeting_chats DROP COLUMN IF EXISTS search_vector;
+
+-- +goose StatementEnd
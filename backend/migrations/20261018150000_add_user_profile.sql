@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN avatar_url VARCHAR(255);
+
+-- +goose StatementEnd
+-- +goose StatementBegin
+
+-- deleting an account keeps the meetings, participations and messages it took part in, without the author
+ALTER TABLE meetings
+    ALTER COLUMN host_id DROP NOT NULL,
+    DROP CONSTRAINT meetings_host_id_fkey,
+    ADD CONSTRAINT meetings_host_id_fkey FOREIGN KEY (host_id) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE meeting_participants
+    ALTER COLUMN user_id DROP NOT NULL,
+    DROP CONSTRAINT meeting_participants_user_id_fkey,
+    ADD CONSTRAINT meeting_participants_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE meeting_chats
+    ALTER COLUMN user_id DROP NOT NULL,
+    DROP CONSTRAINT meeting_chats_user_id_fkey,
+    ADD CONSTRAINT meeting_chats_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- rows of deleted accounts can't satisfy NOT NULL again
+DELETE FROM meeting_chats WHERE user_id IS NULL;
+DELETE FROM meeting_participants WHERE user_id IS NULL;
+DELETE FROM meeting_chats WHERE meeting_id IN (SELECT id FROM meetings WHERE host_id IS NULL);
+DELETE FROM meeting_participants WHERE meeting_id IN (SELECT id FROM meetings WHERE host_id IS NULL);
+DELETE FROM meetings WHERE host_id IS NULL;
+
+ALTER TABLE meeting_chats
+    DROP CONSTRAINT meeting_chats_user_id_fkey,
+    ADD CONSTRAINT meeting_chats_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id),
+    ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE meeting_participants
+    DROP CONSTRAINT meeting_participants_user_id_fkey,
+    ADD CONSTRAINT meeting_participants_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id),
+    ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE meetings
+    DROP CONSTRAINT meetings_host_id_fkey,
+    ADD CONSTRAINT meetings_host_id_fkey FOREIGN KEY (host_id) REFERENCES users(id),
+    ALTER COLUMN host_id SET NOT NULL;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS avatar_url;
+
+-- +goose StatementEnd
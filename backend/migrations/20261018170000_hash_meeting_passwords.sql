@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+ALTER TABLE meetings RENAME COLUMN password TO password_hash;
+
+-- crypt with a bf salt produces the same bcrypt hashes as golang.org/x/crypto/bcrypt at cost 10
+UPDATE meetings
+SET password_hash = crypt(password_hash, gen_salt('bf', 10))
+WHERE password_hash IS NOT NULL AND password_hash <> '';
+
+UPDATE meetings SET password_hash = NULL WHERE password_hash = '';
+
+-- an empty password used to match an empty guess, those meetings were open to anyone with the code
+UPDATE meetings SET is_private = false WHERE is_private AND password_hash IS NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- hashed passwords can't be recovered, private meetings need a new password after rolling back
+ALTER TABLE meetings RENAME COLUMN password_hash TO password;
+
+-- +goose StatementEnd
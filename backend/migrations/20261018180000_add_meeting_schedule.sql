@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE meetings
+    ADD COLUMN duration_minutes INTEGER,
+    ADD COLUMN timezone VARCHAR(64);
+
+-- unset times used to be written as year 1 instead of NULL
+UPDATE meetings SET scheduled_at = NULL WHERE scheduled_at < '1970-01-01';
+UPDATE meetings SET ended_at = NULL WHERE ended_at < '1970-01-01';
+
+CREATE INDEX idx_meetings_scheduled_at ON meetings(scheduled_at) WHERE ended_at IS NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_meetings_scheduled_at;
+ALTER TABLE meetings
+    DROP COLUMN IF EXISTS timezone,
+    DROP COLUMN IF EXISTS duration_minutes;
+
+-- +goose StatementEnd
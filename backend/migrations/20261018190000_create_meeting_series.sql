@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE meeting_series (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    title VARCHAR(255) NOT NULL,
+    host_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    meeting_code VARCHAR(20) UNIQUE NOT NULL,
+    password_hash VARCHAR(255),
+    is_private BOOLEAN NOT NULL DEFAULT false,
+    rrule TEXT NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    duration_minutes INTEGER NOT NULL,
+    timezone VARCHAR(64) NOT NULL,
+    exdates TIMESTAMPTZ[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ended_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_meeting_series_host_id ON meeting_series(host_id);
+
+-- occurrences are meetings created when first joined, they share the code of their series
+ALTER TABLE meetings
+    ADD COLUMN series_id UUID REFERENCES meeting_series(id),
+    ADD CONSTRAINT meetings_series_occurrence_key UNIQUE (series_id, scheduled_at),
+    DROP CONSTRAINT meetings_meeting_code_key;
+
+CREATE UNIQUE INDEX meetings_meeting_code_key ON meetings(meeting_code) WHERE series_id IS NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM meeting_chats WHERE meeting_id IN (SELECT id FROM meetings WHERE series_id IS NOT NULL);
+DELETE FROM meeting_participants WHERE meeting_id IN (SELECT id FROM meetings WHERE series_id IS NOT NULL);
+DELETE FROM meetings WHERE series_id IS NOT NULL;
+
+DROP INDEX IF EXISTS meetings_meeting_code_key;
+ALTER TABLE meetings
+    DROP CONSTRAINT IF EXISTS meetings_series_occurrence_key,
+    DROP COLUMN IF EXISTS series_id,
+    ADD CONSTRAINT meetings_meeting_code_key UNIQUE (meeting_code);
+
+DROP TABLE IF EXISTS meeting_series;
+
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE meetings
+    ADD COLUMN invite_only BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE meeting_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    meeting_id UUID NOT NULL REFERENCES meetings(id),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    -- set when the invitee has an account, invitations to an address match whoever signs up with it
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    responded_at TIMESTAMPTZ,
+    UNIQUE(meeting_id, email)
+);
+
+CREATE INDEX idx_meeting_invitations_user_id ON meeting_invitations(user_id);
+CREATE INDEX idx_meeting_invitations_email ON meeting_invitations(email);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS meeting_invitations;
+ALTER TABLE meetings
+    DROP COLUMN IF EXISTS invite_only;
+
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE meetings
+    ADD COLUMN waiting_room BOOLEAN NOT NULL DEFAULT false;
+
+-- participants of a meeting with a waiting room wait until a host admits or denies them
+ALTER TABLE meeting_participants
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'admitted' CHECK (status IN ('waiting', 'admitted', 'denied'));
+
+CREATE INDEX idx_meeting_participants_waiting ON meeting_participants(meeting_id) WHERE status = 'waiting';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE meeting_participants
+    DROP COLUMN IF EXISTS status;
+ALTER TABLE meetings
+    DROP COLUMN IF EXISTS waiting_room;
+
+-- +goose StatementEnd
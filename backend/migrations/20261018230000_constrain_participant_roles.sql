@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- roles were written in different spellings, bring them in line with models.ParticipantRole
+UPDATE meeting_participants AS mp
+SET role = CASE
+        WHEN mp.user_id = m.host_id THEN 'Host'
+        WHEN lower(mp.role) IN ('co-host', 'cohost') THEN 'Co-Host'
+        ELSE 'Participant'
+    END
+FROM meetings AS m
+WHERE m.id = mp.meeting_id;
+
+ALTER TABLE meeting_participants
+    ALTER COLUMN role SET DEFAULT 'Participant',
+    ADD CONSTRAINT meeting_participants_role_check CHECK (role IN ('Host', 'Co-Host', 'Participant'));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE meeting_participants
+    DROP CONSTRAINT IF EXISTS meeting_participants_role_check,
+    ALTER COLUMN role SET DEFAULT 'participant';
+
+-- +goose StatementEnd
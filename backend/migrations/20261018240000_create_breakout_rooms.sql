@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+-- set while the breakout rooms of a meeting are open, close_at when a timer or countdown ends them
+ALTER TABLE meetings
+    ADD COLUMN breakouts_opened_at TIMESTAMPTZ,
+    ADD COLUMN breakouts_close_at TIMESTAMPTZ;
+
+CREATE TABLE breakout_rooms (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    meeting_id UUID NOT NULL REFERENCES meetings(id),
+    name VARCHAR(100) NOT NULL,
+    position INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_breakout_rooms_meeting_id ON breakout_rooms(meeting_id);
+
+-- a participant is in at most one breakout room of a meeting
+CREATE TABLE breakout_assignments (
+    meeting_id UUID NOT NULL REFERENCES meetings(id),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    room_id UUID NOT NULL REFERENCES breakout_rooms(id) ON DELETE CASCADE,
+    assigned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (meeting_id, user_id)
+);
+
+CREATE INDEX idx_breakout_assignments_room_id ON breakout_assignments(room_id);
+
+CREATE INDEX idx_meetings_breakouts_close_at ON meetings(breakouts_close_at) WHERE breakouts_close_at IS NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS breakout_assignments;
+DROP TABLE IF EXISTS breakout_rooms;
+ALTER TABLE meetings
+    DROP COLUMN IF EXISTS breakouts_close_at,
+    DROP COLUMN IF EXISTS breakouts_opened_at;
+
+-- +goose StatementEnd
@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE meeting_polls (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    meeting_id UUID NOT NULL REFERENCES meetings(id),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    question VARCHAR(500) NOT NULL,
+    multiple_choice BOOLEAN NOT NULL DEFAULT false,
+    -- votes of anonymous polls are still recorded per user to count each voter once, but never shown
+    anonymous BOOLEAN NOT NULL DEFAULT false,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'open', 'closed')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    opened_at TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_meeting_polls_meeting_id ON meeting_polls(meeting_id);
+
+CREATE TABLE meeting_poll_options (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    poll_id UUID NOT NULL REFERENCES meeting_polls(id) ON DELETE CASCADE,
+    label VARCHAR(200) NOT NULL,
+    position INTEGER NOT NULL
+);
+
+CREATE INDEX idx_meeting_poll_options_poll_id ON meeting_poll_options(poll_id);
+
+CREATE TABLE meeting_poll_votes (
+    poll_id UUID NOT NULL REFERENCES meeting_polls(id) ON DELETE CASCADE,
+    option_id UUID NOT NULL REFERENCES meeting_poll_options(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    voted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (poll_id, user_id, option_id)
+);
+
+CREATE INDEX idx_meeting_poll_votes_option_id ON meeting_poll_votes(option_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS meeting_poll_votes;
+DROP TABLE IF EXISTS meeting_poll_options;
+DROP TABLE IF EXISTS meeting_polls;
+
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE meeting_questions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    meeting_id UUID NOT NULL REFERENCES meetings(id),
+    -- kept for anonymous questions too so authors find their own, but never shown
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    text VARCHAR(1000) NOT NULL,
+    anonymous BOOLEAN NOT NULL DEFAULT false,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'answered', 'dismissed')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_meeting_questions_meeting_id ON meeting_questions(meeting_id);
+
+CREATE TABLE meeting_question_votes (
+    question_id UUID NOT NULL REFERENCES meeting_questions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (question_id, user_id)
+);
+
+CREATE INDEX idx_meeting_question_votes_user_id ON meeting_question_votes(user_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS meeting_question_votes;
+DROP TABLE IF EXISTS meeting_questions;
+
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- zero times were written for timestamps that were never set
+UPDATE meeting_participants SET joined_at = NULL WHERE joined_at = '0001-01-01 00:00:00+00';
+UPDATE meeting_participants SET left_at = NULL WHERE left_at = '0001-01-01 00:00:00+00';
+
+-- one row per media connection, left_at is set when the connection goes away
+CREATE TABLE attendance_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    meeting_id UUID NOT NULL REFERENCES meetings(id),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    left_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_attendance_sessions_meeting_id ON attendance_sessions(meeting_id, user_id);
+CREATE INDEX idx_attendance_sessions_open ON attendance_sessions(meeting_id, user_id) WHERE left_at IS NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS attendance_sessions;
+
+-- +goose StatementEnd
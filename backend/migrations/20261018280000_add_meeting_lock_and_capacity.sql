@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- a locked meeting takes no new joiners, max_participants caps who is connected at once,
+-- NULL falls back to the server default
+ALTER TABLE meetings
+    ADD COLUMN locked BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN max_participants INTEGER CHECK (max_participants > 0);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE meetings
+    DROP COLUMN IF EXISTS max_participants,
+    DROP COLUMN IF EXISTS locked;
+
+-- +goose StatementEnd
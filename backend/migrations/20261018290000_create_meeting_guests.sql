@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE meetings
+    ADD COLUMN allow_guests BOOLEAN NOT NULL DEFAULT false;
+
+-- people who joined a meeting with a display name instead of an account
+CREATE TABLE meeting_guests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    meeting_id UUID NOT NULL REFERENCES meetings(id) ON DELETE CASCADE,
+    display_name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_meeting_guests_meeting_id ON meeting_guests(meeting_id);
+
+-- guests take part like users, their rows point at meeting_guests instead of users
+ALTER TABLE meeting_participants
+    ADD COLUMN guest_id UUID UNIQUE REFERENCES meeting_guests(id) ON DELETE CASCADE,
+    ADD CONSTRAINT meeting_participants_user_or_guest CHECK (user_id IS NULL OR guest_id IS NULL);
+
+ALTER TABLE meeting_chats
+    ADD COLUMN guest_id UUID REFERENCES meeting_guests(id) ON DELETE SET NULL,
+    ADD CONSTRAINT meeting_chats_user_or_guest CHECK (user_id IS NULL OR guest_id IS NULL);
+
+ALTER TABLE attendance_sessions
+    ADD COLUMN guest_id UUID REFERENCES meeting_guests(id) ON DELETE CASCADE,
+    ADD CONSTRAINT attendance_sessions_user_or_guest CHECK (user_id IS NULL OR guest_id IS NULL);
+
+CREATE INDEX idx_attendance_sessions_guest_id ON attendance_sessions(guest_id) WHERE guest_id IS NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE attendance_sessions
+    DROP CONSTRAINT IF EXISTS attendance_sessions_user_or_guest,
+    DROP COLUMN IF EXISTS guest_id;
+ALTER TABLE meeting_chats
+    DROP CONSTRAINT IF EXISTS meeting_chats_user_or_guest,
+    DROP COLUMN IF EXISTS guest_id;
+
+DELETE FROM meeting_participants WHERE guest_id IS NOT NULL;
+ALTER TABLE meeting_participants
+    DROP CONSTRAINT IF EXISTS meeting_participants_user_or_guest,
+    DROP COLUMN IF EXISTS guest_id;
+
+DROP TABLE IF EXISTS meeting_guests;
+
+ALTER TABLE meetings
+    DROP COLUMN IF EXISTS allow_guests;
+
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- every user has at most one personal meeting, it is never ended and keeps its code
+ALTER TABLE meetings
+    ADD COLUMN personal BOOLEAN NOT NULL DEFAULT false;
+
+CREATE UNIQUE INDEX idx_meetings_personal_host_id ON meetings(host_id) WHERE personal;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_meetings_personal_host_id;
+ALTER TABLE meetings
+    DROP COLUMN IF EXISTS personal;
+
+-- +goose StatementEnd
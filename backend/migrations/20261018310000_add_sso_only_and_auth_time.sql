@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- accounts created through single sign-on have no password the user knows until they reset it
+ALTER TABLE users
+    ADD COLUMN sso_only BOOLEAN NOT NULL DEFAULT false;
+
+-- when the login a token family was rotated from happened, sso_only accounts confirm sensitive
+-- changes with a recent login instead of their password
+ALTER TABLE refresh_tokens
+    ADD COLUMN authenticated_at TIMESTAMPTZ;
+
+UPDATE refresh_tokens SET authenticated_at = created_at;
+
+ALTER TABLE refresh_tokens
+    ALTER COLUMN authenticated_at SET NOT NULL,
+    ALTER COLUMN authenticated_at SET DEFAULT NOW();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS authenticated_at;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS sso_only;
+
+-- +goose StatementEnd
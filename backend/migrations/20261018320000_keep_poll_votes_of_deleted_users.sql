@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+-- votes of deleted accounts keep counting, they only lose their voter. The ballot groups the
+-- options one voter picked, so they still count as a single voter once user_id is gone.
+ALTER TABLE meeting_poll_votes
+    DROP CONSTRAINT meeting_poll_votes_pkey,
+    DROP CONSTRAINT meeting_poll_votes_user_id_fkey,
+    ADD COLUMN id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ADD COLUMN ballot_id UUID,
+    ALTER COLUMN user_id DROP NOT NULL,
+    ADD CONSTRAINT meeting_poll_votes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+UPDATE meeting_poll_votes AS v
+SET ballot_id = b.id
+FROM (SELECT poll_id, user_id, gen_random_uuid() AS id FROM meeting_poll_votes GROUP BY poll_id, user_id) AS b
+WHERE v.poll_id = b.poll_id AND v.user_id = b.user_id;
+
+ALTER TABLE meeting_poll_votes
+    ALTER COLUMN ballot_id SET NOT NULL;
+
+CREATE UNIQUE INDEX idx_meeting_poll_votes_user_option ON meeting_poll_votes(poll_id, user_id, option_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM meeting_poll_votes WHERE user_id IS NULL;
+
+DROP INDEX IF EXISTS idx_meeting_poll_votes_user_option;
+
+ALTER TABLE meeting_poll_votes
+    DROP CONSTRAINT meeting_poll_votes_user_id_fkey,
+    DROP COLUMN ballot_id,
+    DROP COLUMN id,
+    ALTER COLUMN user_id SET NOT NULL,
+    ADD CONSTRAINT meeting_poll_votes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    ADD PRIMARY KEY (poll_id, user_id, option_id);
+
+-- +goose StatementEnd